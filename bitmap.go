@@ -1,8 +1,11 @@
 package iso8583
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 func addField(fields uint64, num uint8) uint64 {
@@ -28,3 +31,113 @@ func decodeHexString(value string) (uint64, error) {
 func isBitSet(fields uint64, num uint8) bool {
 	return fields&(1<<(64-num)) != 0
 }
+
+const (
+	// BitmapHex encodes a bitmap as uppercase hexadecimal characters, 2 per byte
+	BitmapHex = iota
+	// BitmapBinary encodes a bitmap as raw bytes
+	BitmapBinary
+)
+
+// BitmapSpec describes the layout of the bitmaps in front of a bitmapped
+// (sub)message.
+type BitmapSpec struct {
+	// Size is the length of one bitmap in bytes, from 1 to 8
+	Size int
+	// Encoding is BitmapHex or BitmapBinary
+	Encoding int
+	// Fixed means that there is only one bitmap, and its first bit is a
+	// regular field instead of the indicator of the next bitmap
+	Fixed bool
+}
+
+// DefaultBitmap is the layout used by Message and SubMessage, an 8 byte
+// hexadecimal bitmap, extended by a secondary bitmap if the first bit is set
+var DefaultBitmap = BitmapSpec{Size: 8, Encoding: BitmapHex}
+
+// parseBitmapSpec parses the bitmap struct tag, e.g. `bitmap:"4,binary,fixed"`,
+// empty tag means DefaultBitmap
+func parseBitmapSpec(tag string) (BitmapSpec, error) {
+	spec := DefaultBitmap
+	if tag == "" {
+		return spec, nil
+	}
+	for i, opt := range strings.Split(tag, ",") {
+		switch {
+		case i == 0:
+			size, err := strconv.Atoi(opt)
+			if err != nil {
+				return spec, errors.New("invalid bitmap size: " + opt)
+			}
+			spec.Size = size
+		case opt == "hex":
+			spec.Encoding = BitmapHex
+		case opt == "binary":
+			spec.Encoding = BitmapBinary
+		case opt == "fixed":
+			spec.Fixed = true
+		default:
+			return spec, errors.New("invalid bitmap option: " + opt)
+		}
+	}
+	return spec, spec.check()
+}
+
+func (s BitmapSpec) check() error {
+	if s.Size < 1 || s.Size > 8 {
+		return errors.New("invalid bitmap size: " + strconv.Itoa(s.Size))
+	}
+	if s.Encoding != BitmapHex && s.Encoding != BitmapBinary {
+		return errors.New("invalid bitmap encoding")
+	}
+	return nil
+}
+
+// bits is the number of fields a single bitmap can hold
+func (s BitmapSpec) bits() int {
+	return s.Size * 8
+}
+
+// width is the length of a single encoded bitmap
+func (s BitmapSpec) width() int {
+	if s.Encoding == BitmapBinary {
+		return s.Size
+	}
+	return s.Size * 2
+}
+
+// position returns which bitmap holds the field with the given index,
+// and the bit of the field in that bitmap
+func (s BitmapSpec) position(index int) (bitmap, bit int) {
+	return (index - 1) / s.bits(), (index-1)%s.bits() + 1
+}
+
+func (s BitmapSpec) set(bitmap uint64, bit int) uint64 {
+	return bitmap | 1<<uint(s.bits()-bit)
+}
+
+func (s BitmapSpec) isSet(bitmap uint64, bit int) bool {
+	return bitmap&(1<<uint(s.bits()-bit)) != 0
+}
+
+func (s BitmapSpec) encode(bitmap uint64) []byte {
+	if s.Encoding == BitmapBinary {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, bitmap)
+		return b[8-s.Size:]
+	}
+	return []byte(fmt.Sprintf("%0*X", s.width(), bitmap))
+}
+
+func (s BitmapSpec) decode(raw []byte) (uint64, error) {
+	if len(raw) < s.width() {
+		return 0, errors.New("bitmap too short")
+	}
+	raw = raw[:s.width()]
+	if s.Encoding == BitmapBinary {
+		b := make([]byte, 8)
+		copy(b[8-s.Size:], raw)
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return decodeHexString(string(raw))
+}
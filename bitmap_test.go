@@ -120,3 +120,89 @@ func TestDecodeHexString(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBitmapSpec(t *testing.T) {
+	var scenarios = []struct {
+		tag  string
+		spec BitmapSpec
+		err  bool
+	}{
+		{tag: "", spec: DefaultBitmap},
+		{tag: "8", spec: BitmapSpec{Size: 8, Encoding: BitmapHex}},
+		{tag: "4,binary", spec: BitmapSpec{Size: 4, Encoding: BitmapBinary}},
+		{tag: "8,hex,fixed", spec: BitmapSpec{Size: 8, Encoding: BitmapHex, Fixed: true}},
+		{tag: "9", err: true},
+		{tag: "x", err: true},
+		{tag: "4,packed", err: true},
+	}
+
+	for _, scenario := range scenarios {
+		spec, err := parseBitmapSpec(scenario.tag)
+		if scenario.err {
+			if err == nil {
+				t.Errorf("expecting error for tag %q", scenario.tag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		} else if spec != scenario.spec {
+			t.Errorf("spec should be %#v, instead of %#v", scenario.spec, spec)
+		}
+	}
+}
+
+func TestBitmapSpecEncodeDecode(t *testing.T) {
+	var scenarios = []struct {
+		spec    BitmapSpec
+		bits    []int
+		encoded []byte
+	}{
+		{
+			spec:    DefaultBitmap,
+			bits:    []int{2, 7, 24, 30, 39, 44, 45, 46, 58, 59, 60, 61},
+			encoded: []byte("42000104021C0078"),
+		},
+		{
+			spec:    BitmapSpec{Size: 4, Encoding: BitmapHex},
+			bits:    []int{1, 3, 32},
+			encoded: []byte("A0000001"),
+		},
+		{
+			spec:    BitmapSpec{Size: 4, Encoding: BitmapBinary},
+			bits:    []int{2, 9, 32},
+			encoded: []byte{0x40, 0x80, 0x00, 0x01},
+		},
+		{
+			spec:    BitmapSpec{Size: 8, Encoding: BitmapBinary},
+			bits:    []int{1, 64},
+			encoded: []byte{0x80, 0, 0, 0, 0, 0, 0, 0x01},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		var bitmap uint64
+		for _, bit := range scenario.bits {
+			bitmap = scenario.spec.set(bitmap, bit)
+		}
+		if result := scenario.spec.encode(bitmap); !reflect.DeepEqual(result, scenario.encoded) {
+			t.Errorf("bitmap should be %X, instead of %X", scenario.encoded, result)
+		}
+		decoded, err := scenario.spec.decode(scenario.encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != bitmap {
+			t.Errorf("bitmap should be %d, instead of %d", bitmap, decoded)
+		}
+		for _, bit := range scenario.bits {
+			if !scenario.spec.isSet(decoded, bit) {
+				t.Errorf("bit %d should be set", bit)
+			}
+		}
+	}
+
+	if _, err := DefaultBitmap.decode([]byte("4200")); err == nil {
+		t.Error("expecting error, bitmap too short")
+	}
+}
@@ -0,0 +1,462 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTimeout is returned if the response does not arrive in time
+	ErrTimeout = errors.New("response timeout")
+	// ErrClientClosed is returned by the requests of a closed client
+	ErrClientClosed = errors.New("client closed")
+)
+
+// Matcher returns the key matching a response to its request,
+// the same for the request and its response
+type Matcher func(m *Message) string
+
+// DefaultMatcher matches by the MTI pair, DE11 (STAN), DE37 (RRN) and
+// DE41 (terminal ID)
+var DefaultMatcher = MatchBy(11, 37, 41)
+
+// MatchBy returns a Matcher of the MTI pair (e.g. 0100 and 0110) and
+// the values of the fields with the numbers des
+func MatchBy(des ...int) Matcher {
+	return func(m *Message) string {
+		key := mtiClass(m.Mti)
+		for _, de := range des {
+			value, _ := m.fieldString(de)
+			key += "|" + value
+		}
+		return key
+	}
+}
+
+// mtiClass returns the common part of the MTI of a request and its response,
+// the version, the class and the even function, e.g. 010 for 0100, 0101 and 0110
+func mtiClass(mti string) string {
+	if len(mti) < 3 {
+		return mti
+	}
+	function := (mti[2]-'0')&^1 + '0'
+	return mti[:2] + string(function)
+}
+
+// ResponseMTI returns the MTI of the response to a request MTI,
+// e.g. 0110 for 0100 and 0101, 0430 for 0420
+func ResponseMTI(mti string) (string, error) {
+	if len(mti) != 4 || !numberRegex.MatchString(mti) {
+		return "", errors.New("invalid MTI: " + mti)
+	}
+	if (mti[2]-'0')%2 != 0 {
+		return "", errors.New("MTI is not a request: " + mti)
+	}
+	return mtiClass(mti)[:2] + string(mti[2]+1) + "0", nil
+}
+
+// fieldString returns the value of the field with the number de
+func (m *Message) fieldString(de int) (string, bool) {
+	s := structOf(m)
+	specs := s.ISO8583Fields()
+	for i := range specs {
+		if specs[i].Index != de {
+			continue
+		}
+		value := s.ISO8583Field(i)
+		if value == nil {
+			return "", false
+		}
+		// e.g. a DateTime set by SetTime takes the layout of the field
+		if _, err := specs[i].asField(value); err != nil {
+			return "", false
+		}
+		if str, ok := value.(fmt.Stringer); ok {
+			return str.String(), true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// setFieldString sets the field with the number de to value, the field
+// has to be of a character type, e.g. N or ANS
+func (m *Message) setFieldString(de int, value string) error {
+	s := structOf(m)
+	specs := s.ISO8583Fields()
+	for i := range specs {
+		if specs[i].Index != de {
+			continue
+		}
+		switch f := s.ISO8583NewField(i).(type) {
+		case *N:
+			f.Value = []byte(value)
+		case *AN:
+			f.Value = []byte(value)
+		case *ANP:
+			f.Value = []byte(value)
+		case *ANS:
+			f.Value = []byte(value)
+		default:
+			return errors.New("field is not of a character type: " + specs[i].Name)
+		}
+		return nil
+	}
+	return errors.New("field not found: " + strconv.Itoa(de))
+}
+
+// Client sends requests to a host on a persistent connection and matches
+// the responses to the requests, many requests can be in flight at once.
+// The connection is opened by the first request, and again by the next
+// request after it is lost.
+type Client struct {
+	// Addr is the TCP address of the host
+	Addr string
+	// Framer is the frame of the messages, nil means DefaultLengthHeader
+	Framer Framer
+	// MaxLength limits the length of the responses, 0 means DefaultMaxLength
+	MaxLength int
+	// Header is the header of the frame of the requests, e.g. a TPDU
+	Header []byte
+	// Match returns the key of the requests and responses, nil means DefaultMatcher
+	Match Matcher
+	// Timeout limits the wait for each response, 0 means the deadline of the context
+	Timeout time.Duration
+	// Dial opens the connection, nil means net.Dialer
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Unmatched receives the messages without pending request, e.g. late
+	// responses or requests of the host, in the reading goroutine
+	Unmatched func(m *Message)
+	// OnConnect is called after the connection is opened, before the other
+	// requests are sent on it, e.g. to sign on. The requests sent with ctx
+	// go out at once. If it fails, the connection is closed.
+	OnConnect func(ctx context.Context) error
+	// OnDisconnect is called after the connection is lost, e.g. on a read
+	// error, not after Close
+	OnDisconnect func(err error)
+	// Reversals forwards the reversal of the reversible requests timed out
+	// (see Reversible and ReversalOf), nil means no automatic reversal
+	Reversals *StoreAndForward
+	// TimedOut is called with a reversible request timed out and its stored
+	// reversal, or the error of the reversal. The outcome of the reversal is
+	// given by the Acknowledged and Expired hooks of Reversals.
+	TimedOut func(m, reversal *Message, err error)
+
+	// mu is not held across Dial and the hooks, e.g. the Dial of a Pool
+	// locks the pool
+	mu      sync.Mutex
+	conn    net.Conn
+	pending map[string]chan clientResult
+	closed  bool
+	// connecting is closed when the dial in progress returns, nil without
+	// dial, cancelDial cancels it on Close
+	connecting chan struct{}
+	cancelDial context.CancelFunc
+	// ready is closed when OnConnect of conn returns, nil without OnConnect
+	ready    chan struct{}
+	readyErr error
+
+	// writeMu serializes the writes of the requests, it is locked before mu
+	writeMu sync.Mutex
+	enc     *Encoder
+	encConn net.Conn
+}
+
+// onConnectKey is the context key of the connection of OnConnect
+type onConnectKey struct{}
+
+type clientResult struct {
+	m   *Message
+	err error
+}
+
+func (c *Client) framer() Framer {
+	if c.Framer == nil {
+		return DefaultLengthHeader
+	}
+	return c.Framer
+}
+
+func (c *Client) match(m *Message) string {
+	if c.Match == nil {
+		return DefaultMatcher(m)
+	}
+	return c.Match(m)
+}
+
+// Connect opens the connection if it is not open
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connect(ctx)
+	return err
+}
+
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	c.mu.Lock()
+	// wait for the dial of another request, and try again if it failed
+	for c.connecting != nil && !c.closed {
+		connecting := c.connecting
+		c.mu.Unlock()
+		select {
+		case <-connecting:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		c.mu.Lock()
+	}
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	if conn := c.conn; conn != nil {
+		ready := c.ready
+		c.mu.Unlock()
+		if ready == nil || ctx.Value(onConnectKey{}) == conn {
+			return conn, nil
+		}
+		// wait for OnConnect, e.g. the sign-on
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.conn != conn {
+			if c.ready == ready && c.readyErr != nil {
+				return nil, c.readyErr
+			}
+			return nil, errors.New("connection lost")
+		}
+		return conn, nil
+	}
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	connecting := make(chan struct{})
+	dialCtx, cancel := context.WithCancel(ctx)
+	c.connecting, c.cancelDial = connecting, cancel
+	c.mu.Unlock()
+
+	conn, err := dial(dialCtx, "tcp", c.Addr)
+	cancel()
+	c.mu.Lock()
+	c.connecting, c.cancelDial = nil, nil
+	close(connecting)
+	if c.closed {
+		c.mu.Unlock()
+		if err == nil {
+			conn.Close()
+		}
+		return nil, ErrClientClosed
+	}
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.conn = conn
+	c.pending = make(map[string]chan clientResult)
+	c.ready, c.readyErr = nil, nil
+	go c.readLoop(conn)
+	if c.OnConnect == nil {
+		c.mu.Unlock()
+		return conn, nil
+	}
+	ready := make(chan struct{})
+	c.ready = ready
+	c.mu.Unlock()
+
+	err = c.OnConnect(context.WithValue(ctx, onConnectKey{}, conn))
+	if err != nil {
+		c.mu.Lock()
+		if c.ready == ready {
+			c.readyErr = err
+		}
+		c.mu.Unlock()
+		c.drop(conn, err)
+	}
+	close(ready)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Send sends the request and waits for its response, until the timeout
+// of the client or the end of ctx
+func (c *Client) Send(ctx context.Context, m *Message) (*Message, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := c.match(m)
+	ch := make(chan clientResult, 1)
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return nil, errors.New("connection lost")
+	}
+	if _, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		return nil, errors.New("request already in flight: " + key)
+	}
+	c.pending[key] = ch
+	c.mu.Unlock()
+
+	if err := c.write(ctx, conn, m); err != nil {
+		c.remove(conn, key)
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res.m, res.err
+	case <-ctx.Done():
+		c.remove(conn, key)
+		if ctx.Err() == context.DeadlineExceeded {
+			c.reverse(m)
+			return nil, ErrTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) write(ctx context.Context, conn net.Conn, m *Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.encConn != conn {
+		c.enc = NewEncoder(conn)
+		c.enc.SetFramer(c.framer(), c.Header)
+		c.encConn = conn
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(m); err != nil {
+		// a partial write breaks the framing of the connection
+		if _, ok := err.(net.Error); ok {
+			c.drop(conn, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// reverse stores the reversal of the request timed out
+func (c *Client) reverse(m *Message) {
+	if c.Reversals == nil || !Reversible(m.Mti) {
+		return
+	}
+	reversal, err := ReversalOf(m)
+	if err == nil {
+		_, err = c.Reversals.Enqueue(reversal)
+	}
+	if c.TimedOut != nil {
+		c.TimedOut(m, reversal, err)
+	}
+}
+
+// remove drops the pending request of key, if it is still of conn
+func (c *Client) remove(conn net.Conn, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		delete(c.pending, key)
+	}
+}
+
+func (c *Client) readLoop(conn net.Conn) {
+	dec := NewDecoder(conn)
+	dec.SetFramer(c.framer())
+	dec.SetMaxLength(c.MaxLength)
+	for {
+		m := &Message{}
+		if err := dec.Decode(m); err != nil {
+			c.drop(conn, err)
+			return
+		}
+		key := c.match(m)
+		c.mu.Lock()
+		var ch chan clientResult
+		ok := false
+		if c.conn == conn {
+			if ch, ok = c.pending[key]; ok {
+				delete(c.pending, key)
+			}
+		}
+		c.mu.Unlock()
+		if ok {
+			ch <- clientResult{m: m}
+		} else if c.Unmatched != nil {
+			c.Unmatched(m)
+		}
+	}
+}
+
+// drop closes conn, and fails its pending requests with err
+func (c *Client) drop(conn net.Conn, err error) {
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return
+	}
+	conn.Close()
+	lost := !c.closed
+	if lost {
+		err = errors.New("connection lost: " + err.Error())
+	}
+	for key, ch := range c.pending {
+		ch <- clientResult{err: err}
+		delete(c.pending, key)
+	}
+	c.conn = nil
+	c.mu.Unlock()
+	if lost && c.OnDisconnect != nil {
+		c.OnDisconnect(err)
+	}
+}
+
+// reset closes the current connection, e.g. after failed echo tests,
+// the next request opens a new one
+func (c *Client) reset(err error) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn != nil {
+		c.drop(conn, err)
+	}
+}
+
+// InFlight returns the number of requests waiting for their response
+func (c *Client) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Close closes the connection, and fails the pending requests
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	conn := c.conn
+	if c.cancelDial != nil {
+		c.cancelDial()
+	}
+	c.mu.Unlock()
+	if conn != nil {
+		c.drop(conn, ErrClientClosed)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testHost answers the requests of every connection by handle, a nil
+// response is not sent. The requests of a connection are handled in
+// batches of batch, the responses of a batch in reverse order.
+func testHost(t *testing.T, batch int, handle func(req *Message) *Message) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				dec, enc := NewDecoder(conn), NewEncoder(conn)
+				for {
+					var reqs []*Message
+					for len(reqs) < batch {
+						req := &Message{}
+						if err := dec.Decode(req); err != nil {
+							return
+						}
+						reqs = append(reqs, req)
+					}
+					for i := len(reqs) - 1; i >= 0; i-- {
+						resp := handle(reqs[i])
+						if resp == nil {
+							continue
+						}
+						if err := enc.Encode(resp); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), func() {
+		l.Close()
+	}
+}
+
+func clientTestRequest(stan string) *Message {
+	m := &Message{
+		DE3:  NewNumeric("000000"),
+		DE4:  NewNumeric("1000"),
+		DE11: NewNumeric(stan),
+		DE41: NewANS("TERM0001"),
+	}
+	m.Mti = "0100"
+	return m
+}
+
+func echoResponse(req *Message) *Message {
+	resp := &Message{DE11: req.DE11, DE41: req.DE41, DE39: NewAlphanumeric("000")}
+	resp.Mti, _ = ResponseMTI(req.Mti)
+	if resp.Mti[0] == '0' {
+		resp.DE39 = NewAlphanumeric("00")
+	}
+	return resp
+}
+
+func TestClientMatchesResponses(t *testing.T) {
+	addr, stop := testHost(t, 3, echoResponse)
+	defer stop()
+	c := &Client{Addr: addr, Timeout: 2 * time.Second}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for _, stan := range []string{"000001", "000002", "000003"} {
+		wg.Add(1)
+		go func(stan string) {
+			defer wg.Done()
+			resp, err := c.Send(context.Background(), clientTestRequest(stan))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.Mti != "0110" || resp.DE11.String() != stan {
+				t.Errorf("response %s %s should match request %s", resp.Mti, resp.DE11, stan)
+			}
+		}(stan)
+	}
+	wg.Wait()
+	if c.InFlight() != 0 {
+		t.Errorf("no request should be in flight, got %d", c.InFlight())
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	addr, stop := testHost(t, 1, func(req *Message) *Message {
+		<-release
+		return echoResponse(req)
+	})
+	defer stop()
+
+	unmatched := make(chan *Message, 1)
+	c := &Client{Addr: addr, Timeout: 50 * time.Millisecond, Unmatched: func(m *Message) { unmatched <- m }}
+	defer c.Close()
+
+	if _, err := c.Send(context.Background(), clientTestRequest("000001")); err != ErrTimeout {
+		t.Errorf("error should be ErrTimeout, got %v", err)
+	}
+	if c.InFlight() != 0 {
+		t.Errorf("timed out request should not be in flight")
+	}
+
+	// the late response is unmatched
+	close(release)
+	select {
+	case m := <-unmatched:
+		if m.DE11.String() != "000001" {
+			t.Errorf("unexpected unmatched message %v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("late response should be unmatched")
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	addr, stop := testHost(t, 1, func(req *Message) *Message { return nil })
+	defer stop()
+	c := &Client{Addr: addr}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := c.Send(ctx, clientTestRequest("000001")); err != context.Canceled {
+		t.Errorf("error should be context.Canceled, got %v", err)
+	}
+}
+
+func TestClientDuplicateInFlight(t *testing.T) {
+	addr, stop := testHost(t, 1, func(req *Message) *Message { return nil })
+	defer stop()
+	c := &Client{Addr: addr, Timeout: 200 * time.Millisecond}
+	defer c.Close()
+
+	go c.Send(context.Background(), clientTestRequest("000001"))
+	time.Sleep(50 * time.Millisecond)
+	_, err := c.Send(context.Background(), clientTestRequest("000001"))
+	if err == nil || !strings.Contains(err.Error(), "already in flight") {
+		t.Errorf("duplicate request should fail, got %v", err)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	first := true
+	var mu sync.Mutex
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				dec, enc := NewDecoder(conn), NewEncoder(conn)
+				for {
+					req := &Message{}
+					if err := dec.Decode(req); err != nil {
+						return
+					}
+					mu.Lock()
+					drop := first
+					first = false
+					mu.Unlock()
+					// the host drops the first connection
+					if drop {
+						return
+					}
+					_ = enc.Encode(echoResponse(req))
+				}
+			}()
+		}
+	}()
+
+	c := &Client{Addr: l.Addr().String(), Timeout: 2 * time.Second}
+	defer c.Close()
+	if _, err := c.Send(context.Background(), clientTestRequest("000001")); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("error should be connection lost, got %v", err)
+	}
+	resp, err := c.Send(context.Background(), clientTestRequest("000002"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.DE11.String() != "000002" {
+		t.Errorf("unexpected response %v", resp)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	c := &Client{Addr: "127.0.0.1:1"}
+	c.Close()
+	if _, err := c.Send(context.Background(), clientTestRequest("000001")); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("error should be ErrClientClosed, got %v", err)
+	}
+}
+
+func TestClientCloseWhileDialing(t *testing.T) {
+	dialing := make(chan struct{}, 2)
+	c := &Client{Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialing <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	errs := make(chan error, 2)
+	for _, stan := range []string{"000001", "000002"} {
+		go func(stan string) {
+			_, err := c.Send(context.Background(), clientTestRequest(stan))
+			errs <- err
+		}(stan)
+	}
+	<-dialing
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close should not wait for the dial")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrClientClosed) {
+				t.Errorf("error should be ErrClientClosed, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the requests should fail after Close")
+		}
+	}
+	// the second request waited for the dial of the first one
+	if len(dialing) != 0 {
+		t.Error("the client should dial once")
+	}
+}
+
+func TestResponseMTI(t *testing.T) {
+	for req, resp := range map[string]string{"0100": "0110", "0101": "0110", "0420": "0430", "1804": "1810"} {
+		if mti, err := ResponseMTI(req); err != nil || mti != resp {
+			t.Errorf("response MTI of %s should be %s, got %s", req, resp, mti)
+		}
+	}
+	if _, err := ResponseMTI("0110"); err == nil {
+		t.Error("0110 is not a request")
+	}
+	if DefaultMatcher(clientTestRequest("000001")) != DefaultMatcher(echoResponse(clientTestRequest("000001"))) {
+		t.Error("request and response should have the same key")
+	}
+}
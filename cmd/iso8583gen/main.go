@@ -0,0 +1,280 @@
+// Command iso8583gen generates the reflection-free field access of bitmapped
+// structs (e.g. iso8583.Message, or a submessage of a private data field),
+// used by Encode, Decode and Validate instead of reflection.
+//
+// Usage, in the directory of the package:
+//
+//	//go:generate go run github.com/fluidpay/iso8583/cmd/iso8583gen -type DE48,Nested
+//
+// The generated code implements iso8583.Generated, and it has to be
+// regenerated whenever the fields or their tags change.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const iso8583Path = "github.com/fluidpay/iso8583"
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of bitmapped struct type names; required")
+	output := flag.String("output", "", "output file name; default <type>_iso8583.go")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	names := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = strings.ToLower(names[0]) + "_iso8583.go"
+	}
+
+	src, err := generate(dir, names, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "iso8583gen:", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, *output), src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "iso8583gen:", err)
+		os.Exit(1)
+	}
+}
+
+// fieldSpec is a field of the generated struct
+type fieldSpec struct {
+	name      string
+	index     int
+	length    int
+	format    string
+	validator string
+	tag       string
+	sub       bool
+	typ       string
+}
+
+// structSpec is a struct to generate
+type structSpec struct {
+	name      string
+	fields    []fieldSpec
+	secondary string
+}
+
+// generate returns the generated source of the types of the package in dir,
+// output is left out of the package, as it may hold stale generated code
+func generate(dir string, typeNames []string, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+	// the iso8583 package is either the generated one, or one of its imports
+	iso := pkg
+	if pkg.Scope().Lookup("FieldSpec") == nil {
+		iso = nil
+		for _, p := range pkg.Imports() {
+			if p.Path() == iso8583Path {
+				iso = p
+			}
+		}
+		if iso == nil {
+			return nil, errors.New("package does not import " + iso8583Path)
+		}
+	}
+	fieldObj := iso.Scope().Lookup("field")
+	if fieldObj == nil {
+		return nil, errors.New("field interface not found in " + iso8583Path)
+	}
+	fieldIface := fieldObj.Type().Underlying().(*types.Interface)
+
+	imports := map[string]string{}
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		imports[p.Path()] = p.Name()
+		return p.Name()
+	}
+	isoPrefix := ""
+	if iso != pkg {
+		isoPrefix = qualifier(iso) + "."
+	}
+
+	var structs []structSpec
+	for _, name := range typeNames {
+		s, err := loadStruct(pkg, name, fieldIface, qualifier)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, s)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by iso8583gen -type %s; DO NOT EDIT.\n\n", strings.Join(typeNames, ","))
+	fmt.Fprintf(&b, "package %s\n\n", pkg.Name())
+	if len(imports) > 0 {
+		b.WriteString("import (\n")
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(&b, "\t%s %q\n", imports[path], path)
+		}
+		b.WriteString(")\n\n")
+	}
+	for _, s := range structs {
+		writeStruct(&b, s, isoPrefix)
+	}
+	return format.Source(b.Bytes())
+}
+
+// loadPackage parses and type-checks the package in dir without output
+func loadPackage(dir, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bp.ImportPath, fset, files, nil)
+}
+
+func loadStruct(pkg *types.Package, name string, fieldIface *types.Interface, qualifier types.Qualifier) (structSpec, error) {
+	s := structSpec{name: name}
+	obj := pkg.Scope().Lookup(name)
+	if obj == nil {
+		return s, errors.New("type not found: " + name)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return s, errors.New("type is not a struct: " + name)
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		// skip unexported fields
+		if !v.Exported() {
+			continue
+		}
+		tag := reflect.StructTag(st.Tag(i))
+		if basic, ok := v.Type().(*types.Basic); ok && basic.Kind() == types.Uint64 {
+			if index, err := fieldIndex(v.Name()); err == nil && index == 1 {
+				s.secondary = v.Name()
+			}
+			continue
+		}
+		ptr, ok := v.Type().(*types.Pointer)
+		if !ok {
+			continue
+		}
+		f := fieldSpec{
+			name:      v.Name(),
+			format:    tag.Get("format"),
+			validator: tag.Get("validator"),
+			tag:       string(tag),
+			typ:       types.TypeString(ptr.Elem(), qualifier),
+		}
+		index, err := fieldIndex(v.Name())
+		if err != nil {
+			return s, errors.New("invalid field name: " + name + "." + v.Name())
+		}
+		f.index = index
+		if l, ok := tag.Lookup("length"); ok {
+			if f.length, err = strconv.Atoi(l); err != nil || f.length < 0 {
+				return s, errors.New("invalid length of field: " + name + "." + v.Name())
+			}
+		}
+		_, isStruct := ptr.Elem().Underlying().(*types.Struct)
+		f.sub = isStruct && !types.Implements(ptr, fieldIface)
+		s.fields = append(s.fields, f)
+	}
+	return s, nil
+}
+
+// fieldIndex returns the field number from the name of the struct field,
+// the same way as the iso8583 package
+func fieldIndex(name string) (int, error) {
+	return strconv.Atoi(strings.TrimLeft(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"))
+}
+
+// unexported lowers the leading capitals of name, e.g. DE48 to de48
+func unexported(name string) string {
+	i := 1
+	for i < len(name) && name[i] >= 'A' && name[i] <= 'Z' {
+		i++
+	}
+	return strings.ToLower(name[:i]) + name[i:]
+}
+
+func writeStruct(b *bytes.Buffer, s structSpec, iso string) {
+	specs := unexported(s.name) + "FieldSpecs"
+
+	fmt.Fprintf(b, "var %s = []%sFieldSpec{\n", specs, iso)
+	for _, f := range s.fields {
+		tag := "`" + f.tag + "`"
+		if strings.Contains(f.tag, "`") {
+			tag = strconv.Quote(f.tag)
+		}
+		fmt.Fprintf(b, "\t{Name: %q, Index: %d, Length: %d, Format: %q, Validator: %q, Tag: %s", f.name, f.index, f.length, f.format, f.validator, tag)
+		if f.sub {
+			b.WriteString(", SubMessage: true")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "// ISO8583Fields returns the specs of the fields of %s, the slice is read-only\n", s.name)
+	fmt.Fprintf(b, "func (m *%s) ISO8583Fields() []%sFieldSpec {\n\treturn %s\n}\n\n", s.name, iso, specs)
+
+	fmt.Fprintf(b, "// ISO8583Field returns the field i of ISO8583Fields, nil if it is empty\n")
+	fmt.Fprintf(b, "func (m *%s) ISO8583Field(i int) interface{} {\n\tswitch i {\n", s.name)
+	for i, f := range s.fields {
+		fmt.Fprintf(b, "\tcase %d:\n\t\tif m.%s != nil {\n\t\t\treturn m.%s\n\t\t}\n", i, f.name, f.name)
+	}
+	b.WriteString("\t}\n\treturn nil\n}\n\n")
+
+	fmt.Fprintf(b, "// ISO8583NewField sets the field i of ISO8583Fields to a new empty value\n")
+	fmt.Fprintf(b, "func (m *%s) ISO8583NewField(i int) interface{} {\n\tswitch i {\n", s.name)
+	for i, f := range s.fields {
+		fmt.Fprintf(b, "\tcase %d:\n\t\tm.%s = &%s{}\n\t\treturn m.%s\n", i, f.name, f.typ, f.name)
+	}
+	b.WriteString("\t}\n\treturn nil\n}\n\n")
+
+	fmt.Fprintf(b, "// ISO8583SetSecondaryBitmap sets the secondary bitmap of %s\n", s.name)
+	fmt.Fprintf(b, "func (m *%s) ISO8583SetSecondaryBitmap(bitmap uint64) {\n", s.name)
+	if s.secondary != "" {
+		fmt.Fprintf(b, "\tm.%s = bitmap\n", s.secondary)
+	}
+	b.WriteString("}\n\n")
+}
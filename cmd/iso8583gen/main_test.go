@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	src, err := generate("../..", []string{"Message", "SubMessage"}, "message_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile("../../message_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, current) {
+		t.Error("message_gen.go is out of date, run go generate")
+	}
+}
+
+func TestGenerateOtherPackage(t *testing.T) {
+	src, err := generate("testdata/private", []string{"DE48", "Nested"}, "de48_iso8583.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`iso8583 "github.com/fluidpay/iso8583"`,
+		"var de48FieldSpecs = []iso8583.FieldSpec{",
+		"{Name: \"SF3\", Index: 3, Length: 999, Format: \"LLLVAR\", Validator: \"\", Tag: `format:\"LLLVAR\" length:\"999\" bitmap:\"4,binary\"`, SubMessage: true},",
+		"m.SF5 = &iso8583.TLV{}",
+		"m.SF3 = &Nested{}",
+		"m.SF1 = bitmap",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("generated code should contain %s", expected)
+		}
+	}
+
+	if _, err := generate("testdata/private", []string{"Missing"}, "de48_iso8583.go"); err == nil {
+		t.Error("generating unknown type should fail")
+	}
+}
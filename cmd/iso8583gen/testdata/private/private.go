@@ -0,0 +1,14 @@
+package private
+
+import "github.com/fluidpay/iso8583"
+
+type DE48 struct {
+	SF1 uint64
+	SF2 *iso8583.N   `format:"" length:"4" validator:"N"`
+	SF3 *Nested      `format:"LLLVAR" length:"999" bitmap:"4,binary"`
+	SF5 *iso8583.TLV `format:"LLVAR" length:"99" tlv:"2,2"`
+}
+
+type Nested struct {
+	TG2 *iso8583.ANS `format:"LLVAR" length:"20" validator:"ANS"`
+}
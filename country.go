@@ -0,0 +1,319 @@
+package iso8583
+
+import (
+	"errors"
+	"strings"
+)
+
+// Country is an ISO 3166-1 country
+type Country struct {
+	Alpha2  string
+	Alpha3  string
+	Numeric string
+}
+
+// countries are the officially assigned ISO 3166-1 countries
+var countries = []Country{
+	{Alpha2: "AF", Alpha3: "AFG", Numeric: "004"},
+	{Alpha2: "AX", Alpha3: "ALA", Numeric: "248"},
+	{Alpha2: "AL", Alpha3: "ALB", Numeric: "008"},
+	{Alpha2: "DZ", Alpha3: "DZA", Numeric: "012"},
+	{Alpha2: "AS", Alpha3: "ASM", Numeric: "016"},
+	{Alpha2: "AD", Alpha3: "AND", Numeric: "020"},
+	{Alpha2: "AO", Alpha3: "AGO", Numeric: "024"},
+	{Alpha2: "AI", Alpha3: "AIA", Numeric: "660"},
+	{Alpha2: "AQ", Alpha3: "ATA", Numeric: "010"},
+	{Alpha2: "AG", Alpha3: "ATG", Numeric: "028"},
+	{Alpha2: "AR", Alpha3: "ARG", Numeric: "032"},
+	{Alpha2: "AM", Alpha3: "ARM", Numeric: "051"},
+	{Alpha2: "AW", Alpha3: "ABW", Numeric: "533"},
+	{Alpha2: "AU", Alpha3: "AUS", Numeric: "036"},
+	{Alpha2: "AT", Alpha3: "AUT", Numeric: "040"},
+	{Alpha2: "AZ", Alpha3: "AZE", Numeric: "031"},
+	{Alpha2: "BS", Alpha3: "BHS", Numeric: "044"},
+	{Alpha2: "BH", Alpha3: "BHR", Numeric: "048"},
+	{Alpha2: "BD", Alpha3: "BGD", Numeric: "050"},
+	{Alpha2: "BB", Alpha3: "BRB", Numeric: "052"},
+	{Alpha2: "BY", Alpha3: "BLR", Numeric: "112"},
+	{Alpha2: "BE", Alpha3: "BEL", Numeric: "056"},
+	{Alpha2: "BZ", Alpha3: "BLZ", Numeric: "084"},
+	{Alpha2: "BJ", Alpha3: "BEN", Numeric: "204"},
+	{Alpha2: "BM", Alpha3: "BMU", Numeric: "060"},
+	{Alpha2: "BT", Alpha3: "BTN", Numeric: "064"},
+	{Alpha2: "BO", Alpha3: "BOL", Numeric: "068"},
+	{Alpha2: "BQ", Alpha3: "BES", Numeric: "535"},
+	{Alpha2: "BA", Alpha3: "BIH", Numeric: "070"},
+	{Alpha2: "BW", Alpha3: "BWA", Numeric: "072"},
+	{Alpha2: "BV", Alpha3: "BVT", Numeric: "074"},
+	{Alpha2: "BR", Alpha3: "BRA", Numeric: "076"},
+	{Alpha2: "IO", Alpha3: "IOT", Numeric: "086"},
+	{Alpha2: "BN", Alpha3: "BRN", Numeric: "096"},
+	{Alpha2: "BG", Alpha3: "BGR", Numeric: "100"},
+	{Alpha2: "BF", Alpha3: "BFA", Numeric: "854"},
+	{Alpha2: "BI", Alpha3: "BDI", Numeric: "108"},
+	{Alpha2: "CV", Alpha3: "CPV", Numeric: "132"},
+	{Alpha2: "KH", Alpha3: "KHM", Numeric: "116"},
+	{Alpha2: "CM", Alpha3: "CMR", Numeric: "120"},
+	{Alpha2: "CA", Alpha3: "CAN", Numeric: "124"},
+	{Alpha2: "KY", Alpha3: "CYM", Numeric: "136"},
+	{Alpha2: "CF", Alpha3: "CAF", Numeric: "140"},
+	{Alpha2: "TD", Alpha3: "TCD", Numeric: "148"},
+	{Alpha2: "CL", Alpha3: "CHL", Numeric: "152"},
+	{Alpha2: "CN", Alpha3: "CHN", Numeric: "156"},
+	{Alpha2: "CX", Alpha3: "CXR", Numeric: "162"},
+	{Alpha2: "CC", Alpha3: "CCK", Numeric: "166"},
+	{Alpha2: "CO", Alpha3: "COL", Numeric: "170"},
+	{Alpha2: "KM", Alpha3: "COM", Numeric: "174"},
+	{Alpha2: "CG", Alpha3: "COG", Numeric: "178"},
+	{Alpha2: "CD", Alpha3: "COD", Numeric: "180"},
+	{Alpha2: "CK", Alpha3: "COK", Numeric: "184"},
+	{Alpha2: "CR", Alpha3: "CRI", Numeric: "188"},
+	{Alpha2: "CI", Alpha3: "CIV", Numeric: "384"},
+	{Alpha2: "HR", Alpha3: "HRV", Numeric: "191"},
+	{Alpha2: "CU", Alpha3: "CUB", Numeric: "192"},
+	{Alpha2: "CW", Alpha3: "CUW", Numeric: "531"},
+	{Alpha2: "CY", Alpha3: "CYP", Numeric: "196"},
+	{Alpha2: "CZ", Alpha3: "CZE", Numeric: "203"},
+	{Alpha2: "DK", Alpha3: "DNK", Numeric: "208"},
+	{Alpha2: "DJ", Alpha3: "DJI", Numeric: "262"},
+	{Alpha2: "DM", Alpha3: "DMA", Numeric: "212"},
+	{Alpha2: "DO", Alpha3: "DOM", Numeric: "214"},
+	{Alpha2: "EC", Alpha3: "ECU", Numeric: "218"},
+	{Alpha2: "EG", Alpha3: "EGY", Numeric: "818"},
+	{Alpha2: "SV", Alpha3: "SLV", Numeric: "222"},
+	{Alpha2: "GQ", Alpha3: "GNQ", Numeric: "226"},
+	{Alpha2: "ER", Alpha3: "ERI", Numeric: "232"},
+	{Alpha2: "EE", Alpha3: "EST", Numeric: "233"},
+	{Alpha2: "SZ", Alpha3: "SWZ", Numeric: "748"},
+	{Alpha2: "ET", Alpha3: "ETH", Numeric: "231"},
+	{Alpha2: "FK", Alpha3: "FLK", Numeric: "238"},
+	{Alpha2: "FO", Alpha3: "FRO", Numeric: "234"},
+	{Alpha2: "FJ", Alpha3: "FJI", Numeric: "242"},
+	{Alpha2: "FI", Alpha3: "FIN", Numeric: "246"},
+	{Alpha2: "FR", Alpha3: "FRA", Numeric: "250"},
+	{Alpha2: "GF", Alpha3: "GUF", Numeric: "254"},
+	{Alpha2: "PF", Alpha3: "PYF", Numeric: "258"},
+	{Alpha2: "TF", Alpha3: "ATF", Numeric: "260"},
+	{Alpha2: "GA", Alpha3: "GAB", Numeric: "266"},
+	{Alpha2: "GM", Alpha3: "GMB", Numeric: "270"},
+	{Alpha2: "GE", Alpha3: "GEO", Numeric: "268"},
+	{Alpha2: "DE", Alpha3: "DEU", Numeric: "276"},
+	{Alpha2: "GH", Alpha3: "GHA", Numeric: "288"},
+	{Alpha2: "GI", Alpha3: "GIB", Numeric: "292"},
+	{Alpha2: "GR", Alpha3: "GRC", Numeric: "300"},
+	{Alpha2: "GL", Alpha3: "GRL", Numeric: "304"},
+	{Alpha2: "GD", Alpha3: "GRD", Numeric: "308"},
+	{Alpha2: "GP", Alpha3: "GLP", Numeric: "312"},
+	{Alpha2: "GU", Alpha3: "GUM", Numeric: "316"},
+	{Alpha2: "GT", Alpha3: "GTM", Numeric: "320"},
+	{Alpha2: "GG", Alpha3: "GGY", Numeric: "831"},
+	{Alpha2: "GN", Alpha3: "GIN", Numeric: "324"},
+	{Alpha2: "GW", Alpha3: "GNB", Numeric: "624"},
+	{Alpha2: "GY", Alpha3: "GUY", Numeric: "328"},
+	{Alpha2: "HT", Alpha3: "HTI", Numeric: "332"},
+	{Alpha2: "HM", Alpha3: "HMD", Numeric: "334"},
+	{Alpha2: "VA", Alpha3: "VAT", Numeric: "336"},
+	{Alpha2: "HN", Alpha3: "HND", Numeric: "340"},
+	{Alpha2: "HK", Alpha3: "HKG", Numeric: "344"},
+	{Alpha2: "HU", Alpha3: "HUN", Numeric: "348"},
+	{Alpha2: "IS", Alpha3: "ISL", Numeric: "352"},
+	{Alpha2: "IN", Alpha3: "IND", Numeric: "356"},
+	{Alpha2: "ID", Alpha3: "IDN", Numeric: "360"},
+	{Alpha2: "IR", Alpha3: "IRN", Numeric: "364"},
+	{Alpha2: "IQ", Alpha3: "IRQ", Numeric: "368"},
+	{Alpha2: "IE", Alpha3: "IRL", Numeric: "372"},
+	{Alpha2: "IM", Alpha3: "IMN", Numeric: "833"},
+	{Alpha2: "IL", Alpha3: "ISR", Numeric: "376"},
+	{Alpha2: "IT", Alpha3: "ITA", Numeric: "380"},
+	{Alpha2: "JM", Alpha3: "JAM", Numeric: "388"},
+	{Alpha2: "JP", Alpha3: "JPN", Numeric: "392"},
+	{Alpha2: "JE", Alpha3: "JEY", Numeric: "832"},
+	{Alpha2: "JO", Alpha3: "JOR", Numeric: "400"},
+	{Alpha2: "KZ", Alpha3: "KAZ", Numeric: "398"},
+	{Alpha2: "KE", Alpha3: "KEN", Numeric: "404"},
+	{Alpha2: "KI", Alpha3: "KIR", Numeric: "296"},
+	{Alpha2: "KP", Alpha3: "PRK", Numeric: "408"},
+	{Alpha2: "KR", Alpha3: "KOR", Numeric: "410"},
+	{Alpha2: "KW", Alpha3: "KWT", Numeric: "414"},
+	{Alpha2: "KG", Alpha3: "KGZ", Numeric: "417"},
+	{Alpha2: "LA", Alpha3: "LAO", Numeric: "418"},
+	{Alpha2: "LV", Alpha3: "LVA", Numeric: "428"},
+	{Alpha2: "LB", Alpha3: "LBN", Numeric: "422"},
+	{Alpha2: "LS", Alpha3: "LSO", Numeric: "426"},
+	{Alpha2: "LR", Alpha3: "LBR", Numeric: "430"},
+	{Alpha2: "LY", Alpha3: "LBY", Numeric: "434"},
+	{Alpha2: "LI", Alpha3: "LIE", Numeric: "438"},
+	{Alpha2: "LT", Alpha3: "LTU", Numeric: "440"},
+	{Alpha2: "LU", Alpha3: "LUX", Numeric: "442"},
+	{Alpha2: "MO", Alpha3: "MAC", Numeric: "446"},
+	{Alpha2: "MG", Alpha3: "MDG", Numeric: "450"},
+	{Alpha2: "MW", Alpha3: "MWI", Numeric: "454"},
+	{Alpha2: "MY", Alpha3: "MYS", Numeric: "458"},
+	{Alpha2: "MV", Alpha3: "MDV", Numeric: "462"},
+	{Alpha2: "ML", Alpha3: "MLI", Numeric: "466"},
+	{Alpha2: "MT", Alpha3: "MLT", Numeric: "470"},
+	{Alpha2: "MH", Alpha3: "MHL", Numeric: "584"},
+	{Alpha2: "MQ", Alpha3: "MTQ", Numeric: "474"},
+	{Alpha2: "MR", Alpha3: "MRT", Numeric: "478"},
+	{Alpha2: "MU", Alpha3: "MUS", Numeric: "480"},
+	{Alpha2: "YT", Alpha3: "MYT", Numeric: "175"},
+	{Alpha2: "MX", Alpha3: "MEX", Numeric: "484"},
+	{Alpha2: "FM", Alpha3: "FSM", Numeric: "583"},
+	{Alpha2: "MD", Alpha3: "MDA", Numeric: "498"},
+	{Alpha2: "MC", Alpha3: "MCO", Numeric: "492"},
+	{Alpha2: "MN", Alpha3: "MNG", Numeric: "496"},
+	{Alpha2: "ME", Alpha3: "MNE", Numeric: "499"},
+	{Alpha2: "MS", Alpha3: "MSR", Numeric: "500"},
+	{Alpha2: "MA", Alpha3: "MAR", Numeric: "504"},
+	{Alpha2: "MZ", Alpha3: "MOZ", Numeric: "508"},
+	{Alpha2: "MM", Alpha3: "MMR", Numeric: "104"},
+	{Alpha2: "NA", Alpha3: "NAM", Numeric: "516"},
+	{Alpha2: "NR", Alpha3: "NRU", Numeric: "520"},
+	{Alpha2: "NP", Alpha3: "NPL", Numeric: "524"},
+	{Alpha2: "NL", Alpha3: "NLD", Numeric: "528"},
+	{Alpha2: "NC", Alpha3: "NCL", Numeric: "540"},
+	{Alpha2: "NZ", Alpha3: "NZL", Numeric: "554"},
+	{Alpha2: "NI", Alpha3: "NIC", Numeric: "558"},
+	{Alpha2: "NE", Alpha3: "NER", Numeric: "562"},
+	{Alpha2: "NG", Alpha3: "NGA", Numeric: "566"},
+	{Alpha2: "NU", Alpha3: "NIU", Numeric: "570"},
+	{Alpha2: "NF", Alpha3: "NFK", Numeric: "574"},
+	{Alpha2: "MK", Alpha3: "MKD", Numeric: "807"},
+	{Alpha2: "MP", Alpha3: "MNP", Numeric: "580"},
+	{Alpha2: "NO", Alpha3: "NOR", Numeric: "578"},
+	{Alpha2: "OM", Alpha3: "OMN", Numeric: "512"},
+	{Alpha2: "PK", Alpha3: "PAK", Numeric: "586"},
+	{Alpha2: "PW", Alpha3: "PLW", Numeric: "585"},
+	{Alpha2: "PS", Alpha3: "PSE", Numeric: "275"},
+	{Alpha2: "PA", Alpha3: "PAN", Numeric: "591"},
+	{Alpha2: "PG", Alpha3: "PNG", Numeric: "598"},
+	{Alpha2: "PY", Alpha3: "PRY", Numeric: "600"},
+	{Alpha2: "PE", Alpha3: "PER", Numeric: "604"},
+	{Alpha2: "PH", Alpha3: "PHL", Numeric: "608"},
+	{Alpha2: "PN", Alpha3: "PCN", Numeric: "612"},
+	{Alpha2: "PL", Alpha3: "POL", Numeric: "616"},
+	{Alpha2: "PT", Alpha3: "PRT", Numeric: "620"},
+	{Alpha2: "PR", Alpha3: "PRI", Numeric: "630"},
+	{Alpha2: "QA", Alpha3: "QAT", Numeric: "634"},
+	{Alpha2: "RE", Alpha3: "REU", Numeric: "638"},
+	{Alpha2: "RO", Alpha3: "ROU", Numeric: "642"},
+	{Alpha2: "RU", Alpha3: "RUS", Numeric: "643"},
+	{Alpha2: "RW", Alpha3: "RWA", Numeric: "646"},
+	{Alpha2: "BL", Alpha3: "BLM", Numeric: "652"},
+	{Alpha2: "SH", Alpha3: "SHN", Numeric: "654"},
+	{Alpha2: "KN", Alpha3: "KNA", Numeric: "659"},
+	{Alpha2: "LC", Alpha3: "LCA", Numeric: "662"},
+	{Alpha2: "MF", Alpha3: "MAF", Numeric: "663"},
+	{Alpha2: "PM", Alpha3: "SPM", Numeric: "666"},
+	{Alpha2: "VC", Alpha3: "VCT", Numeric: "670"},
+	{Alpha2: "WS", Alpha3: "WSM", Numeric: "882"},
+	{Alpha2: "SM", Alpha3: "SMR", Numeric: "674"},
+	{Alpha2: "ST", Alpha3: "STP", Numeric: "678"},
+	{Alpha2: "SA", Alpha3: "SAU", Numeric: "682"},
+	{Alpha2: "SN", Alpha3: "SEN", Numeric: "686"},
+	{Alpha2: "RS", Alpha3: "SRB", Numeric: "688"},
+	{Alpha2: "SC", Alpha3: "SYC", Numeric: "690"},
+	{Alpha2: "SL", Alpha3: "SLE", Numeric: "694"},
+	{Alpha2: "SG", Alpha3: "SGP", Numeric: "702"},
+	{Alpha2: "SX", Alpha3: "SXM", Numeric: "534"},
+	{Alpha2: "SK", Alpha3: "SVK", Numeric: "703"},
+	{Alpha2: "SI", Alpha3: "SVN", Numeric: "705"},
+	{Alpha2: "SB", Alpha3: "SLB", Numeric: "090"},
+	{Alpha2: "SO", Alpha3: "SOM", Numeric: "706"},
+	{Alpha2: "ZA", Alpha3: "ZAF", Numeric: "710"},
+	{Alpha2: "GS", Alpha3: "SGS", Numeric: "239"},
+	{Alpha2: "SS", Alpha3: "SSD", Numeric: "728"},
+	{Alpha2: "ES", Alpha3: "ESP", Numeric: "724"},
+	{Alpha2: "LK", Alpha3: "LKA", Numeric: "144"},
+	{Alpha2: "SD", Alpha3: "SDN", Numeric: "729"},
+	{Alpha2: "SR", Alpha3: "SUR", Numeric: "740"},
+	{Alpha2: "SJ", Alpha3: "SJM", Numeric: "744"},
+	{Alpha2: "SE", Alpha3: "SWE", Numeric: "752"},
+	{Alpha2: "CH", Alpha3: "CHE", Numeric: "756"},
+	{Alpha2: "SY", Alpha3: "SYR", Numeric: "760"},
+	{Alpha2: "TW", Alpha3: "TWN", Numeric: "158"},
+	{Alpha2: "TJ", Alpha3: "TJK", Numeric: "762"},
+	{Alpha2: "TZ", Alpha3: "TZA", Numeric: "834"},
+	{Alpha2: "TH", Alpha3: "THA", Numeric: "764"},
+	{Alpha2: "TL", Alpha3: "TLS", Numeric: "626"},
+	{Alpha2: "TG", Alpha3: "TGO", Numeric: "768"},
+	{Alpha2: "TK", Alpha3: "TKL", Numeric: "772"},
+	{Alpha2: "TO", Alpha3: "TON", Numeric: "776"},
+	{Alpha2: "TT", Alpha3: "TTO", Numeric: "780"},
+	{Alpha2: "TN", Alpha3: "TUN", Numeric: "788"},
+	{Alpha2: "TR", Alpha3: "TUR", Numeric: "792"},
+	{Alpha2: "TM", Alpha3: "TKM", Numeric: "795"},
+	{Alpha2: "TC", Alpha3: "TCA", Numeric: "796"},
+	{Alpha2: "TV", Alpha3: "TUV", Numeric: "798"},
+	{Alpha2: "UG", Alpha3: "UGA", Numeric: "800"},
+	{Alpha2: "UA", Alpha3: "UKR", Numeric: "804"},
+	{Alpha2: "AE", Alpha3: "ARE", Numeric: "784"},
+	{Alpha2: "GB", Alpha3: "GBR", Numeric: "826"},
+	{Alpha2: "US", Alpha3: "USA", Numeric: "840"},
+	{Alpha2: "UM", Alpha3: "UMI", Numeric: "581"},
+	{Alpha2: "UY", Alpha3: "URY", Numeric: "858"},
+	{Alpha2: "UZ", Alpha3: "UZB", Numeric: "860"},
+	{Alpha2: "VU", Alpha3: "VUT", Numeric: "548"},
+	{Alpha2: "VE", Alpha3: "VEN", Numeric: "862"},
+	{Alpha2: "VN", Alpha3: "VNM", Numeric: "704"},
+	{Alpha2: "VG", Alpha3: "VGB", Numeric: "092"},
+	{Alpha2: "VI", Alpha3: "VIR", Numeric: "850"},
+	{Alpha2: "WF", Alpha3: "WLF", Numeric: "876"},
+	{Alpha2: "EH", Alpha3: "ESH", Numeric: "732"},
+	{Alpha2: "YE", Alpha3: "YEM", Numeric: "887"},
+	{Alpha2: "ZM", Alpha3: "ZMB", Numeric: "894"},
+	{Alpha2: "ZW", Alpha3: "ZWE", Numeric: "716"},
+}
+
+var (
+	countriesByNumeric = make(map[string]Country, len(countries))
+	countriesByAlpha   = make(map[string]Country, 2*len(countries))
+)
+
+func init() {
+	for _, c := range countries {
+		countriesByNumeric[c.Numeric] = c
+		countriesByAlpha[c.Alpha2] = c
+		countriesByAlpha[c.Alpha3] = c
+	}
+}
+
+// CountryByNumeric returns the country of a numeric code, e.g. 840
+func CountryByNumeric(code string) (Country, bool) {
+	c, ok := countriesByNumeric[code]
+	return c, ok
+}
+
+// CountryByAlpha returns the country of an alpha-2 or alpha-3 code, e.g. US or USA
+func CountryByAlpha(code string) (Country, bool) {
+	c, ok := countriesByAlpha[strings.ToUpper(code)]
+	return c, ok
+}
+
+func validateCountry(value string) error {
+	if _, ok := countriesByNumeric[value]; !ok {
+		return errors.New("unknown country code: " + value)
+	}
+	return nil
+}
+
+// AcceptorCountry returns the country of the card acceptor, in positions 38
+// to 40 of the name and location of DE43, as alpha-3, numeric or right
+// aligned alpha-2 code
+func (m *Message) AcceptorCountry() (Country, error) {
+	if m.DE43 == nil || len(m.DE43.Value) == 0 {
+		return Country{}, errors.New("no card acceptor name and location in DE43")
+	}
+	if len(m.DE43.Value) < 40 {
+		return Country{}, errors.New("DE43 has no country in positions 38 to 40")
+	}
+	code := strings.TrimLeft(string(m.DE43.Value[37:40]), " ")
+	c, ok := CountryByAlpha(code)
+	if !ok {
+		c, ok = CountryByNumeric(code)
+	}
+	if !ok || len(code) < 2 {
+		return Country{}, errors.New("unknown country code in DE43: " + code)
+	}
+	return c, nil
+}
@@ -0,0 +1,66 @@
+package iso8583
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCountryLookup(t *testing.T) {
+	c, ok := CountryByNumeric("840")
+	if !ok || c.Alpha2 != "US" || c.Alpha3 != "USA" {
+		t.Errorf("840 should be US, got %+v", c)
+	}
+	for _, code := range []string{"HU", "hun", "Hun"} {
+		if c, ok := CountryByAlpha(code); !ok || c.Numeric != "348" {
+			t.Errorf("%s should be 348, got %+v", code, c)
+		}
+	}
+	if _, ok := CountryByNumeric("999"); ok {
+		t.Error("999 should be unknown")
+	}
+}
+
+func TestCountryValidator(t *testing.T) {
+	if err := validate("036", "COUNTRY"); err != nil {
+		t.Error(err)
+	}
+	if err := validate("000", "COUNTRY"); err == nil {
+		t.Error("000 should be an invalid country")
+	}
+}
+
+func TestAcceptorCountry(t *testing.T) {
+	var scenarios = []struct {
+		de43     string
+		expected string
+	}{
+		{de43: location("ACME STORE 123", "NEW YORK", "USA"), expected: "840"},
+		{de43: location("ACME STORE 123", "LONDON", "GB"), expected: "826"},
+		{de43: location("ACME STORE 123", "BUDAPEST", "348"), expected: "348"},
+	}
+	for _, scenario := range scenarios {
+		m := &Message{DE43: NewANS(scenario.de43)}
+		c, err := m.AcceptorCountry()
+		if err != nil {
+			t.Errorf("%s: %v", scenario.de43, err)
+		} else if c.Numeric != scenario.expected {
+			t.Errorf("%s should be in %s, got %+v", scenario.de43, scenario.expected, c)
+		}
+	}
+
+	for _, de43 := range []string{"", "ACME STORE", location("ACME STORE 123", "NEW YORK", "XXX"), location("ACME STORE 123", "NEW YORK", "")} {
+		m := &Message{}
+		if de43 != "" {
+			m.DE43 = NewANS(de43)
+		}
+		if _, err := m.AcceptorCountry(); err == nil {
+			t.Errorf("expecting error for %q", de43)
+		}
+	}
+}
+
+// location returns a name and location of 40 characters, with the name in
+// positions 1 to 22, the city in 24 to 36 and the country in 38 to 40
+func location(name, city, country string) string {
+	return fmt.Sprintf("%-22s %-13s %3s", name, city, country)
+}
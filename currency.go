@@ -0,0 +1,292 @@
+package iso8583
+
+import (
+	"errors"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency
+type Currency struct {
+	Alpha   string
+	Numeric string
+	// Exponent is the number of digits after the decimal separator
+	// in the minor unit, e.g. 2 for USD, 0 for JPY
+	Exponent int
+}
+
+// currencies are the active ISO 4217 currencies, without precious metals
+// and testing codes
+var currencies = []Currency{
+	{Alpha: "AED", Numeric: "784", Exponent: 2},
+	{Alpha: "AFN", Numeric: "971", Exponent: 2},
+	{Alpha: "ALL", Numeric: "008", Exponent: 2},
+	{Alpha: "AMD", Numeric: "051", Exponent: 2},
+	{Alpha: "ANG", Numeric: "532", Exponent: 2},
+	{Alpha: "AOA", Numeric: "973", Exponent: 2},
+	{Alpha: "ARS", Numeric: "032", Exponent: 2},
+	{Alpha: "AUD", Numeric: "036", Exponent: 2},
+	{Alpha: "AWG", Numeric: "533", Exponent: 2},
+	{Alpha: "AZN", Numeric: "944", Exponent: 2},
+	{Alpha: "BAM", Numeric: "977", Exponent: 2},
+	{Alpha: "BBD", Numeric: "052", Exponent: 2},
+	{Alpha: "BDT", Numeric: "050", Exponent: 2},
+	{Alpha: "BGN", Numeric: "975", Exponent: 2},
+	{Alpha: "BHD", Numeric: "048", Exponent: 3},
+	{Alpha: "BIF", Numeric: "108", Exponent: 0},
+	{Alpha: "BMD", Numeric: "060", Exponent: 2},
+	{Alpha: "BND", Numeric: "096", Exponent: 2},
+	{Alpha: "BOB", Numeric: "068", Exponent: 2},
+	{Alpha: "BOV", Numeric: "984", Exponent: 2},
+	{Alpha: "BRL", Numeric: "986", Exponent: 2},
+	{Alpha: "BSD", Numeric: "044", Exponent: 2},
+	{Alpha: "BTN", Numeric: "064", Exponent: 2},
+	{Alpha: "BWP", Numeric: "072", Exponent: 2},
+	{Alpha: "BYN", Numeric: "933", Exponent: 2},
+	{Alpha: "BZD", Numeric: "084", Exponent: 2},
+	{Alpha: "CAD", Numeric: "124", Exponent: 2},
+	{Alpha: "CDF", Numeric: "976", Exponent: 2},
+	{Alpha: "CHE", Numeric: "947", Exponent: 2},
+	{Alpha: "CHF", Numeric: "756", Exponent: 2},
+	{Alpha: "CHW", Numeric: "948", Exponent: 2},
+	{Alpha: "CLF", Numeric: "990", Exponent: 4},
+	{Alpha: "CLP", Numeric: "152", Exponent: 0},
+	{Alpha: "CNY", Numeric: "156", Exponent: 2},
+	{Alpha: "COP", Numeric: "170", Exponent: 2},
+	{Alpha: "COU", Numeric: "970", Exponent: 2},
+	{Alpha: "CRC", Numeric: "188", Exponent: 2},
+	{Alpha: "CUC", Numeric: "931", Exponent: 2},
+	{Alpha: "CUP", Numeric: "192", Exponent: 2},
+	{Alpha: "CVE", Numeric: "132", Exponent: 2},
+	{Alpha: "CZK", Numeric: "203", Exponent: 2},
+	{Alpha: "DJF", Numeric: "262", Exponent: 0},
+	{Alpha: "DKK", Numeric: "208", Exponent: 2},
+	{Alpha: "DOP", Numeric: "214", Exponent: 2},
+	{Alpha: "DZD", Numeric: "012", Exponent: 2},
+	{Alpha: "EGP", Numeric: "818", Exponent: 2},
+	{Alpha: "ERN", Numeric: "232", Exponent: 2},
+	{Alpha: "ETB", Numeric: "230", Exponent: 2},
+	{Alpha: "EUR", Numeric: "978", Exponent: 2},
+	{Alpha: "FJD", Numeric: "242", Exponent: 2},
+	{Alpha: "FKP", Numeric: "238", Exponent: 2},
+	{Alpha: "GBP", Numeric: "826", Exponent: 2},
+	{Alpha: "GEL", Numeric: "981", Exponent: 2},
+	{Alpha: "GHS", Numeric: "936", Exponent: 2},
+	{Alpha: "GIP", Numeric: "292", Exponent: 2},
+	{Alpha: "GMD", Numeric: "270", Exponent: 2},
+	{Alpha: "GNF", Numeric: "324", Exponent: 0},
+	{Alpha: "GTQ", Numeric: "320", Exponent: 2},
+	{Alpha: "GYD", Numeric: "328", Exponent: 2},
+	{Alpha: "HKD", Numeric: "344", Exponent: 2},
+	{Alpha: "HNL", Numeric: "340", Exponent: 2},
+	{Alpha: "HTG", Numeric: "332", Exponent: 2},
+	{Alpha: "HUF", Numeric: "348", Exponent: 2},
+	{Alpha: "IDR", Numeric: "360", Exponent: 2},
+	{Alpha: "ILS", Numeric: "376", Exponent: 2},
+	{Alpha: "INR", Numeric: "356", Exponent: 2},
+	{Alpha: "IQD", Numeric: "368", Exponent: 3},
+	{Alpha: "IRR", Numeric: "364", Exponent: 2},
+	{Alpha: "ISK", Numeric: "352", Exponent: 0},
+	{Alpha: "JMD", Numeric: "388", Exponent: 2},
+	{Alpha: "JOD", Numeric: "400", Exponent: 3},
+	{Alpha: "JPY", Numeric: "392", Exponent: 0},
+	{Alpha: "KES", Numeric: "404", Exponent: 2},
+	{Alpha: "KGS", Numeric: "417", Exponent: 2},
+	{Alpha: "KHR", Numeric: "116", Exponent: 2},
+	{Alpha: "KMF", Numeric: "174", Exponent: 0},
+	{Alpha: "KPW", Numeric: "408", Exponent: 2},
+	{Alpha: "KRW", Numeric: "410", Exponent: 0},
+	{Alpha: "KWD", Numeric: "414", Exponent: 3},
+	{Alpha: "KYD", Numeric: "136", Exponent: 2},
+	{Alpha: "KZT", Numeric: "398", Exponent: 2},
+	{Alpha: "LAK", Numeric: "418", Exponent: 2},
+	{Alpha: "LBP", Numeric: "422", Exponent: 2},
+	{Alpha: "LKR", Numeric: "144", Exponent: 2},
+	{Alpha: "LRD", Numeric: "430", Exponent: 2},
+	{Alpha: "LSL", Numeric: "426", Exponent: 2},
+	{Alpha: "LYD", Numeric: "434", Exponent: 3},
+	{Alpha: "MAD", Numeric: "504", Exponent: 2},
+	{Alpha: "MDL", Numeric: "498", Exponent: 2},
+	{Alpha: "MGA", Numeric: "969", Exponent: 2},
+	{Alpha: "MKD", Numeric: "807", Exponent: 2},
+	{Alpha: "MMK", Numeric: "104", Exponent: 2},
+	{Alpha: "MNT", Numeric: "496", Exponent: 2},
+	{Alpha: "MOP", Numeric: "446", Exponent: 2},
+	{Alpha: "MRU", Numeric: "929", Exponent: 2},
+	{Alpha: "MUR", Numeric: "480", Exponent: 2},
+	{Alpha: "MVR", Numeric: "462", Exponent: 2},
+	{Alpha: "MWK", Numeric: "454", Exponent: 2},
+	{Alpha: "MXN", Numeric: "484", Exponent: 2},
+	{Alpha: "MXV", Numeric: "979", Exponent: 2},
+	{Alpha: "MYR", Numeric: "458", Exponent: 2},
+	{Alpha: "MZN", Numeric: "943", Exponent: 2},
+	{Alpha: "NAD", Numeric: "516", Exponent: 2},
+	{Alpha: "NGN", Numeric: "566", Exponent: 2},
+	{Alpha: "NIO", Numeric: "558", Exponent: 2},
+	{Alpha: "NOK", Numeric: "578", Exponent: 2},
+	{Alpha: "NPR", Numeric: "524", Exponent: 2},
+	{Alpha: "NZD", Numeric: "554", Exponent: 2},
+	{Alpha: "OMR", Numeric: "512", Exponent: 3},
+	{Alpha: "PAB", Numeric: "590", Exponent: 2},
+	{Alpha: "PEN", Numeric: "604", Exponent: 2},
+	{Alpha: "PGK", Numeric: "598", Exponent: 2},
+	{Alpha: "PHP", Numeric: "608", Exponent: 2},
+	{Alpha: "PKR", Numeric: "586", Exponent: 2},
+	{Alpha: "PLN", Numeric: "985", Exponent: 2},
+	{Alpha: "PYG", Numeric: "600", Exponent: 0},
+	{Alpha: "QAR", Numeric: "634", Exponent: 2},
+	{Alpha: "RON", Numeric: "946", Exponent: 2},
+	{Alpha: "RSD", Numeric: "941", Exponent: 2},
+	{Alpha: "RUB", Numeric: "643", Exponent: 2},
+	{Alpha: "RWF", Numeric: "646", Exponent: 0},
+	{Alpha: "SAR", Numeric: "682", Exponent: 2},
+	{Alpha: "SBD", Numeric: "090", Exponent: 2},
+	{Alpha: "SCR", Numeric: "690", Exponent: 2},
+	{Alpha: "SDG", Numeric: "938", Exponent: 2},
+	{Alpha: "SEK", Numeric: "752", Exponent: 2},
+	{Alpha: "SGD", Numeric: "702", Exponent: 2},
+	{Alpha: "SHP", Numeric: "654", Exponent: 2},
+	{Alpha: "SLE", Numeric: "925", Exponent: 2},
+	{Alpha: "SLL", Numeric: "694", Exponent: 2},
+	{Alpha: "SOS", Numeric: "706", Exponent: 2},
+	{Alpha: "SRD", Numeric: "968", Exponent: 2},
+	{Alpha: "SSP", Numeric: "728", Exponent: 2},
+	{Alpha: "STN", Numeric: "930", Exponent: 2},
+	{Alpha: "SVC", Numeric: "222", Exponent: 2},
+	{Alpha: "SYP", Numeric: "760", Exponent: 2},
+	{Alpha: "SZL", Numeric: "748", Exponent: 2},
+	{Alpha: "THB", Numeric: "764", Exponent: 2},
+	{Alpha: "TJS", Numeric: "972", Exponent: 2},
+	{Alpha: "TMT", Numeric: "934", Exponent: 2},
+	{Alpha: "TND", Numeric: "788", Exponent: 3},
+	{Alpha: "TOP", Numeric: "776", Exponent: 2},
+	{Alpha: "TRY", Numeric: "949", Exponent: 2},
+	{Alpha: "TTD", Numeric: "780", Exponent: 2},
+	{Alpha: "TWD", Numeric: "901", Exponent: 2},
+	{Alpha: "TZS", Numeric: "834", Exponent: 2},
+	{Alpha: "UAH", Numeric: "980", Exponent: 2},
+	{Alpha: "UGX", Numeric: "800", Exponent: 0},
+	{Alpha: "USD", Numeric: "840", Exponent: 2},
+	{Alpha: "USN", Numeric: "997", Exponent: 2},
+	{Alpha: "UYI", Numeric: "940", Exponent: 0},
+	{Alpha: "UYU", Numeric: "858", Exponent: 2},
+	{Alpha: "UYW", Numeric: "927", Exponent: 4},
+	{Alpha: "UZS", Numeric: "860", Exponent: 2},
+	{Alpha: "VED", Numeric: "926", Exponent: 2},
+	{Alpha: "VES", Numeric: "928", Exponent: 2},
+	{Alpha: "VND", Numeric: "704", Exponent: 0},
+	{Alpha: "VUV", Numeric: "548", Exponent: 0},
+	{Alpha: "WST", Numeric: "882", Exponent: 2},
+	{Alpha: "XAF", Numeric: "950", Exponent: 0},
+	{Alpha: "XCD", Numeric: "951", Exponent: 2},
+	{Alpha: "XOF", Numeric: "952", Exponent: 0},
+	{Alpha: "XPF", Numeric: "953", Exponent: 0},
+	{Alpha: "YER", Numeric: "886", Exponent: 2},
+	{Alpha: "ZAR", Numeric: "710", Exponent: 2},
+	{Alpha: "ZMW", Numeric: "967", Exponent: 2},
+	{Alpha: "ZWL", Numeric: "932", Exponent: 2},
+}
+
+var (
+	currenciesByNumeric = make(map[string]Currency, len(currencies))
+	currenciesByAlpha   = make(map[string]Currency, len(currencies))
+)
+
+func init() {
+	for _, c := range currencies {
+		currenciesByNumeric[c.Numeric] = c
+		currenciesByAlpha[c.Alpha] = c
+	}
+}
+
+// CurrencyByNumeric returns the currency of a numeric code, e.g. 840
+func CurrencyByNumeric(code string) (Currency, bool) {
+	c, ok := currenciesByNumeric[code]
+	return c, ok
+}
+
+// CurrencyByAlpha returns the currency of an alphabetic code, e.g. USD
+func CurrencyByAlpha(code string) (Currency, bool) {
+	c, ok := currenciesByAlpha[strings.ToUpper(code)]
+	return c, ok
+}
+
+// CurrencyExponent returns the minor unit exponent of a numeric currency code
+func CurrencyExponent(code string) (int, bool) {
+	c, ok := currenciesByNumeric[code]
+	return c.Exponent, ok
+}
+
+func validateCurrency(value string) error {
+	if _, ok := currenciesByNumeric[value]; !ok {
+		return errors.New("unknown currency code: " + value)
+	}
+	return nil
+}
+
+// AdditionalAmount is an amount set of DE54
+type AdditionalAmount struct {
+	AccountType string
+	AmountType  string
+	Currency    string
+	// Sign is C for credit, D for debit
+	Sign   string
+	Amount string
+}
+
+// additionalAmountLength is the length of an amount set, account type n2,
+// amount type n2, currency code n3, sign x1 and amount n12
+const additionalAmountLength = 20
+
+// ParseAdditionalAmounts splits the value of DE54 to amount sets,
+// and validates their currency codes
+func ParseAdditionalAmounts(value string) ([]AdditionalAmount, error) {
+	if len(value)%additionalAmountLength != 0 {
+		return nil, errors.New("invalid additional amounts length")
+	}
+	amounts := make([]AdditionalAmount, 0, len(value)/additionalAmountLength)
+	for i := 0; i < len(value); i += additionalAmountLength {
+		set := value[i : i+additionalAmountLength]
+		a := AdditionalAmount{
+			AccountType: set[0:2],
+			AmountType:  set[2:4],
+			Currency:    set[4:7],
+			Sign:        set[7:8],
+			Amount:      set[8:20],
+		}
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, a)
+	}
+	return amounts, nil
+}
+
+// FormatAdditionalAmounts joins amount sets to the value of DE54
+func FormatAdditionalAmounts(amounts []AdditionalAmount) (string, error) {
+	res := ""
+	for _, a := range amounts {
+		if len(a.Amount) < 12 {
+			a.Amount = strings.Repeat("0", 12-len(a.Amount)) + a.Amount
+		}
+		if err := a.validate(); err != nil {
+			return "", err
+		}
+		res += a.AccountType + a.AmountType + a.Currency + a.Sign + a.Amount
+	}
+	return res, nil
+}
+
+func (a AdditionalAmount) validate() error {
+	for _, v := range []string{a.AccountType, a.AmountType, a.Currency, a.Amount} {
+		if !numberRegex.MatchString(v) {
+			return errors.New("invalid number value format: " + v)
+		}
+	}
+	if len(a.AccountType) != 2 || len(a.AmountType) != 2 || len(a.Amount) != 12 {
+		return errors.New("invalid additional amount length")
+	}
+	if a.Sign != "C" && a.Sign != "D" {
+		return errors.New("invalid additional amount sign: " + a.Sign)
+	}
+	return validateCurrency(a.Currency)
+}
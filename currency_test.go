@@ -0,0 +1,72 @@
+package iso8583
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCurrencyLookup(t *testing.T) {
+	c, ok := CurrencyByNumeric("840")
+	if !ok || c.Alpha != "USD" || c.Exponent != 2 {
+		t.Errorf("840 should be USD with exponent 2, got %+v", c)
+	}
+	c, ok = CurrencyByAlpha("jpy")
+	if !ok || c.Numeric != "392" || c.Exponent != 0 {
+		t.Errorf("JPY should be 392 with exponent 0, got %+v", c)
+	}
+	if e, ok := CurrencyExponent("048"); !ok || e != 3 {
+		t.Errorf("exponent of BHD should be 3, got %d", e)
+	}
+	if _, ok := CurrencyByNumeric("999"); ok {
+		t.Error("999 should be unknown")
+	}
+}
+
+func TestCurrencyValidator(t *testing.T) {
+	if err := validate("978", "CURRENCY"); err != nil {
+		t.Error(err)
+	}
+	if err := validate("000", "CURRENCY"); err == nil {
+		t.Error("000 should be an invalid currency")
+	}
+
+	m := &Message{Mti: "0100", DE49: NewNumeric("001")}
+	if _, err := m.Encode(); err == nil {
+		t.Error("encoding unknown DE49 currency should fail")
+	}
+}
+
+func TestAdditionalAmounts(t *testing.T) {
+	value := "1002840C000000001000" + "1001978D000000000500"
+	amounts, err := ParseAdditionalAmounts(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []AdditionalAmount{
+		{AccountType: "10", AmountType: "02", Currency: "840", Sign: "C", Amount: "000000001000"},
+		{AccountType: "10", AmountType: "01", Currency: "978", Sign: "D", Amount: "000000000500"},
+	}
+	if !reflect.DeepEqual(amounts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, amounts)
+	}
+
+	expected[1].Amount = "500"
+	formatted, err := FormatAdditionalAmounts(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != value {
+		t.Errorf("Expected %s, got %s", value, formatted)
+	}
+
+	for _, invalid := range []string{
+		"1002840C00000000100",
+		"1002999C000000001000",
+		"1002840X000000001000",
+		"10028400000000001000",
+	} {
+		if _, err := ParseAdditionalAmounts(invalid); err == nil {
+			t.Errorf("%s should be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// now is the reference time of the year inference, replaced in tests
+var now = time.Now
+
+// dateTimeParts are the components of a date/time value, -1 if the layout misses them
+type dateTimeParts struct {
+	year, month, day, hour, min, sec int
+}
+
+// checkLayout returns an error if layout is not a date/time layout
+func checkLayout(layout string) error {
+	if len(layout) == 0 || len(layout)%2 != 0 {
+		return errors.New("invalid date/time layout: " + layout)
+	}
+	for i := 0; i < len(layout); i += 2 {
+		switch layout[i : i+2] {
+		case "YY", "MM", "DD", "HH", "SS":
+		default:
+			return errors.New("invalid date/time layout: " + layout)
+		}
+	}
+	return nil
+}
+
+// splitDateTime splits the value by the layout, e.g. MMDDHHMMSS,
+// MM is the month, unless it follows HH
+func splitDateTime(value, layout string) (dateTimeParts, error) {
+	p := dateTimeParts{-1, -1, -1, -1, -1, -1}
+	if len(value) != len(layout) || len(layout)%2 != 0 {
+		return p, errors.New("invalid " + layout + " value format: " + value)
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return p, errors.New("invalid " + layout + " value format: " + value)
+		}
+	}
+	for i := 0; i < len(layout); i += 2 {
+		n := int(value[i]-'0')*10 + int(value[i+1]-'0')
+		switch layout[i : i+2] {
+		case "YY":
+			p.year = n
+		case "MM":
+			if p.hour >= 0 {
+				p.min = n
+			} else {
+				p.month = n
+			}
+		case "DD":
+			p.day = n
+		case "HH":
+			p.hour = n
+		case "SS":
+			p.sec = n
+		default:
+			return p, errors.New("invalid date/time layout: " + layout)
+		}
+	}
+	return p, nil
+}
+
+// daysIn returns the number of days of the month, year -1 means unknown
+// year, then February has 29 days
+func daysIn(month, year int) int {
+	switch month {
+	case 2:
+		if year < 0 || (year%4 == 0 && (year%100 != 0 || year%400 == 0)) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
+
+// validCalendar checks that the parts make a real date and time,
+// a 2 digit year is in the 2000s for the leap year check
+func (p dateTimeParts) validCalendar() bool {
+	year := p.year
+	if year >= 0 {
+		year += 2000
+	}
+	if p.month >= 0 && (p.month < 1 || p.month > 12) {
+		return false
+	}
+	if p.day >= 0 && (p.day < 1 || p.day > daysIn(p.month, year)) {
+		return false
+	}
+	return p.hour <= 23 && p.min <= 59 && p.sec <= 59
+}
+
+func validateCalendar(value, layout string) error {
+	p, err := splitDateTime(value, layout)
+	if err != nil {
+		return err
+	}
+	if !p.validCalendar() {
+		return errors.New("invalid " + layout + " date: " + value)
+	}
+	return nil
+}
+
+// ParseDateTime converts a date/time value of the layout (e.g. MMDDHHMMSS,
+// YYMMDD, YYMM) to time.Time in loc. A missing year is inferred as the one
+// giving the date closest to ref, e.g. 1231 is the last December when ref
+// is in January. A 2 digit year is in the century closest to ref.
+// A missing day is the first of the month.
+func ParseDateTime(value, layout string, loc *time.Location, ref time.Time) (time.Time, error) {
+	p, err := splitDateTime(value, layout)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !p.validCalendar() {
+		return time.Time{}, errors.New("invalid " + layout + " date: " + value)
+	}
+	if p.month < 0 {
+		return time.Time{}, errors.New("date/time layout without month: " + layout)
+	}
+	if p.day < 0 {
+		p.day = 1
+	}
+	for _, v := range []*int{&p.hour, &p.min, &p.sec} {
+		if *v < 0 {
+			*v = 0
+		}
+	}
+	ref = ref.In(loc)
+	date := func(year int) time.Time {
+		return time.Date(year, time.Month(p.month), p.day, p.hour, p.min, p.sec, 0, loc)
+	}
+
+	if p.year >= 0 {
+		year := ref.Year() - ref.Year()%100 + p.year
+		if year-ref.Year() > 50 {
+			year -= 100
+		} else if ref.Year()-year > 50 {
+			year += 100
+		}
+		return date(year), nil
+	}
+
+	var closest time.Time
+	for year := ref.Year() - 1; year <= ref.Year()+1; year++ {
+		// e.g. 0229 is valid in leap years only
+		if p.day > daysIn(p.month, year) {
+			continue
+		}
+		t := date(year)
+		if closest.IsZero() || absDuration(t.Sub(ref)) < absDuration(closest.Sub(ref)) {
+			closest = t
+		}
+	}
+	if closest.IsZero() {
+		return closest, errors.New("invalid " + layout + " date: " + value)
+	}
+	return closest, nil
+}
+
+// FormatDateTime converts t in loc to a date/time value of the layout
+func FormatDateTime(t time.Time, layout string, loc *time.Location) (string, error) {
+	t = t.In(loc)
+	res := ""
+	hour := false
+	for i := 0; i+2 <= len(layout); i += 2 {
+		var n int
+		switch layout[i : i+2] {
+		case "YY":
+			n = t.Year() % 100
+		case "MM":
+			if hour {
+				n = t.Minute()
+			} else {
+				n = int(t.Month())
+			}
+		case "DD":
+			n = t.Day()
+		case "HH":
+			n, hour = t.Hour(), true
+		case "SS":
+			n = t.Second()
+		default:
+			return "", errors.New("invalid date/time layout: " + layout)
+		}
+		res += fmt.Sprintf("%02d", n)
+	}
+	if len(res) != len(layout) {
+		return "", errors.New("invalid date/time layout: " + layout)
+	}
+	return res, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// fullLayout is the layout of the value of a DateTime set by SetTime
+// before its layout is known
+const fullLayout = "YYMMDDHHMMSS"
+
+// DateTime is a fixed length numeric date/time field, e.g. DE7. Its layout
+// is the validator of the field, e.g. MMDDHHMMSS, known once the field is
+// encoded or decoded. Until then it is given by NewDateTimeLayout, or by the
+// length of the value, 6, 10 or 12 digits, except for the 4 digits of YYMM
+// and MMDD. SetTime on a new field keeps the time as YYMMDDHHMMSS.
+type DateTime struct {
+	Value []byte
+
+	// layout is the layout of Value, empty if it is not known
+	layout string
+}
+
+func NewDateTime(value string) *DateTime {
+	return &DateTime{Value: []byte(value)}
+}
+
+// NewDateTimeLayout returns a field of the value in the layout, e.g. YYMM
+func NewDateTimeLayout(value, layout string) *DateTime {
+	return &DateTime{Value: []byte(value), layout: layout}
+}
+
+// layoutOf returns the layout of a value of unknown layout from its length
+func layoutOf(value []byte) (string, error) {
+	switch len(value) {
+	case 6:
+		return "YYMMDD", nil
+	case 10:
+		return "MMDDHHMMSS", nil
+	case 12:
+		return fullLayout, nil
+	}
+	return "", errors.New("unknown layout of date/time field " + string(value) + ", e.g. YYMM or MMDD")
+}
+
+// Time converts the field to time.Time in loc, inferring a missing year
+// from the current time
+func (dt *DateTime) Time(loc *time.Location) (time.Time, error) {
+	if len(dt.Value) == 0 {
+		return time.Time{}, errors.New("empty date/time field")
+	}
+	layout := dt.layout
+	if layout == "" {
+		var err error
+		if layout, err = layoutOf(dt.Value); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return ParseDateTime(string(dt.Value), layout, loc, now())
+}
+
+// SetTime sets the field to t in loc
+func (dt *DateTime) SetTime(t time.Time, loc *time.Location) error {
+	layout := dt.layout
+	if layout == "" {
+		layout = fullLayout
+	}
+	value, err := FormatDateTime(t, layout, loc)
+	if err != nil {
+		return err
+	}
+	dt.Value, dt.layout = []byte(value), layout
+	return nil
+}
+
+func (dt *DateTime) Encode(encoder, length int, format, validator string) ([]byte, error) {
+	val := dt.Value
+	if err := validateBytes(val, validator); err != nil {
+		return []byte{}, err
+	}
+	if format != "" {
+		return []byte{}, errors.New("DateTime has fixed length")
+	}
+	if len(val) != length {
+		return nil, errors.New("invalid value length")
+	}
+
+	switch encoder {
+	case BCDIC:
+		panic("implement me")
+	default: //ASCII encoding
+		return val, nil
+	}
+}
+
+func (dt *DateTime) Decode(raw []byte, encoder, length int, format, validator string) (int, error) {
+	var nextFieldOffset int
+	switch encoder {
+	case BCDIC:
+	case ASCII:
+		if format != "" {
+			return 0, errors.New("DateTime has fixed length")
+		}
+		if len(raw) < length {
+			return 0, errors.New("invalid value length")
+		}
+		dt.Value = raw[:length]
+		nextFieldOffset = length
+	}
+
+	err := validateBytes(dt.Value, validator)
+	return nextFieldOffset, err
+}
+
+func (dt *DateTime) isEmpty() bool {
+	return len(dt.Value) == 0
+}
+
+// configure reads the layout from the validator struct tag, and converts
+// the value of SetTime to it
+func (dt *DateTime) configure(tag reflect.StructTag) error {
+	layout := tag.Get("validator")
+	if layout == dt.layout {
+		return nil
+	}
+	if err := checkLayout(layout); err != nil {
+		return err
+	}
+	if dt.layout == fullLayout && layout != fullLayout && len(dt.Value) > 0 {
+		t, err := ParseDateTime(string(dt.Value), fullLayout, time.UTC, now())
+		if err != nil {
+			return err
+		}
+		value, _ := FormatDateTime(t, layout, time.UTC)
+		dt.Value = []byte(value)
+	}
+	dt.layout = layout
+	return nil
+}
+
+func (dt DateTime) String() string {
+	return string(dt.Value)
+}
+
+func (dt *DateTime) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%s"`, dt.Value)), nil
+}
+
+func (dt *DateTime) UnmarshalJSON(data []byte) error {
+	content := strings.Replace(string(data), `"`, "", -1)
+	dt.Value, dt.layout = []byte(content), ""
+	return nil
+}
@@ -0,0 +1,193 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCalendarValidators(t *testing.T) {
+	var scenarios = []struct {
+		value     string
+		validator string
+		valid     bool
+	}{
+		{value: "0229", validator: "MMDD", valid: true},
+		{value: "0230", validator: "MMDD", valid: false},
+		{value: "0431", validator: "MMDD", valid: false},
+		{value: "0531", validator: "MMDD", valid: true},
+		{value: "240229", validator: "YYMMDD", valid: true},
+		{value: "230229", validator: "YYMMDD", valid: false},
+		{value: "000229", validator: "YYMMDD", valid: true},
+		{value: "0631235959", validator: "MMDDHHMMSS", valid: false},
+		{value: "0630235959", validator: "MMDDHHMMSS", valid: true},
+		{value: "230931120000", validator: "YYMMDDHHMMSS", valid: false},
+		{value: "2513", validator: "YYMM", valid: false},
+		{value: "2512", validator: "YYMM", valid: true},
+	}
+	for _, scenario := range scenarios {
+		err := validate(scenario.value, scenario.validator)
+		if scenario.valid && err != nil {
+			t.Errorf("%s should be a valid %s: %v", scenario.value, scenario.validator, err)
+		} else if !scenario.valid && err == nil {
+			t.Errorf("%s should be an invalid %s", scenario.value, scenario.validator)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	ref := time.Date(2021, time.January, 2, 10, 0, 0, 0, time.UTC)
+	var scenarios = []struct {
+		value    string
+		layout   string
+		ref      time.Time
+		expected time.Time
+	}{
+		// year boundaries
+		{value: "1231235959", layout: "MMDDHHMMSS", ref: ref, expected: time.Date(2020, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{value: "0101", layout: "MMDD", ref: time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC), expected: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "0615", layout: "MMDD", ref: ref, expected: time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)},
+		// 0229 is in the closest leap year
+		{value: "0229", layout: "MMDD", ref: time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC), expected: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{value: "210102100000", layout: "YYMMDDHHMMSS", ref: ref, expected: ref},
+		{value: "991231", layout: "YYMMDD", ref: ref, expected: time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "2512", layout: "YYMM", ref: ref, expected: time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, scenario := range scenarios {
+		result, err := ParseDateTime(scenario.value, scenario.layout, time.UTC, scenario.ref)
+		if err != nil {
+			t.Errorf("%s: %v", scenario.value, err)
+		} else if !result.Equal(scenario.expected) {
+			t.Errorf("%s should be %v, instead of %v", scenario.value, scenario.expected, result)
+		}
+	}
+
+	if _, err := ParseDateTime("0431", "MMDD", time.UTC, ref); err == nil {
+		t.Error("expecting error, April has 30 days")
+	}
+	if _, err := ParseDateTime("0229", "MMDD", time.UTC, time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("expecting error, there is no leap year around 2022")
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	tm := time.Date(2020, time.December, 31, 23, 59, 58, 0, time.UTC)
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	var scenarios = []struct {
+		layout   string
+		loc      *time.Location
+		expected string
+	}{
+		{layout: "MMDDHHMMSS", loc: time.UTC, expected: "1231235958"},
+		{layout: "YYMMDDHHMMSS", loc: loc, expected: "210101015958"},
+		{layout: "YYMM", loc: time.UTC, expected: "2012"},
+		{layout: "MMDD", loc: loc, expected: "0101"},
+	}
+	for _, scenario := range scenarios {
+		result, err := FormatDateTime(tm, scenario.layout, scenario.loc)
+		if err != nil {
+			t.Error(err)
+		}
+		equals(t, result, scenario.expected, scenario.layout)
+	}
+	if _, err := FormatDateTime(tm, "ANS", time.UTC); err == nil {
+		t.Error("expecting error, invalid layout")
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2021, time.January, 1, 0, 5, 0, 0, time.UTC) }
+
+	m := &Message{}
+	if err := m.Decode([]byte("0100" + "0210000000000000" + "1231235900" + "201231185900")); err != nil {
+		t.Fatal(err)
+	}
+	tm, err := m.DE7.Time(time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tm.Equal(time.Date(2020, time.December, 31, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("DE7 should be in 2020, instead of %v", tm)
+	}
+	est := time.FixedZone("EST", -5*60*60)
+	local, err := m.DE12.Time(est)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !local.Equal(tm) {
+		t.Errorf("DE12 should be the same instant as DE7, instead of %v", local)
+	}
+
+	// the layout of new fields is the validator, once they are encoded
+	m.DE17, m.DE15 = &DateTime{}, &DateTime{}
+	if err := m.DE17.SetTime(tm, est); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DE15.SetTime(tm, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	tm15, err := m.DE15.Time(time.UTC)
+	if err != nil || !tm15.Equal(tm) {
+		t.Errorf("DE15 should be %v before encoding, instead of %v %v", tm, tm15, err)
+	}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "0100"+"0212800000000000"+"1231235900"+"201231185900"+"201231"+"1231", "")
+	equals(t, m.DE17.String(), "1231", "DE17")
+	equals(t, m.DE15.String(), "201231", "DE15")
+	tm15, err = m.DE15.Time(time.UTC)
+	if err != nil || !tm15.Equal(time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("DE15 should be the date of DE7, instead of %v %v", tm15, err)
+	}
+
+	// e.g. DE7 of a request in the original data elements of its reversal
+	request := &Message{DE7: &DateTime{}}
+	if err := request.DE7.SetTime(tm, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	transmission, _ := request.fieldString(7)
+	equals(t, transmission, "1231235900", "DE7")
+
+	if _, err := (&DateTime{}).Time(time.UTC); err == nil {
+		t.Error("expecting error, empty field")
+	}
+	if _, err := NewDateTime("1231").Time(time.UTC); err == nil {
+		t.Error("expecting error, unknown layout")
+	}
+}
+
+func TestDateTimeLayoutBeforeEncoding(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2021, time.January, 1, 0, 5, 0, 0, time.UTC) }
+
+	// the layout of a new field is given by the length of its value
+	expected := time.Date(2020, time.December, 31, 23, 59, 0, 0, time.UTC)
+	for _, value := range []string{"1231235900", "201231235900"} {
+		tm, err := NewDateTime(value).Time(time.UTC)
+		if err != nil || !tm.Equal(expected) {
+			t.Errorf("%s should be %v, instead of %v %v", value, expected, tm, err)
+		}
+	}
+	tm, err := NewDateTime("201231").Time(time.UTC)
+	if err != nil || !tm.Equal(time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("201231 should be the 31st of December 2020, instead of %v %v", tm, err)
+	}
+
+	// YYMM and MMDD have the same length
+	tm, err = NewDateTimeLayout("2512", "YYMM").Time(time.UTC)
+	if err != nil || !tm.Equal(time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("2512 should be December 2025, instead of %v %v", tm, err)
+	}
+
+	// e.g. a message restored from JSON
+	m := &Message{}
+	if err := json.Unmarshal([]byte(`{"DE7":"1231235900"}`), m); err != nil {
+		t.Fatal(err)
+	}
+	if tm, err := m.DE7.Time(time.UTC); err != nil || !tm.Equal(expected) {
+		t.Errorf("DE7 should be %v, instead of %v %v", expected, tm, err)
+	}
+}
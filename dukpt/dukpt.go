@@ -0,0 +1,336 @@
+// Package dukpt derives the keys of Derived Unique Key Per Transaction,
+// the TDES DUKPT of ANSI X9.24-1 and the AES DUKPT of ANSI X9.24-3, from a
+// base derivation key (BDK) or an initial key and a key serial number (KSN).
+// The keys are derived in software, in the clear, e.g. for a test host.
+package dukpt
+
+import (
+	"crypto/aes"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// TDESKSNLength is the length of the KSN of TDES DUKPT, 59 bits of initial
+// key serial number followed by a counter of 21 bits
+const TDESKSNLength = 10
+
+// Variant is the variant of a TDES DUKPT working key
+type Variant int
+
+const (
+	// PINVariant is the key of the PIN blocks
+	PINVariant Variant = iota
+	// MACRequestVariant is the MAC key of the requests, both ways
+	// before X9.24-1 2009
+	MACRequestVariant
+	// MACResponseVariant is the MAC key of the responses
+	MACResponseVariant
+	// DataRequestVariant is the data encryption key of the requests
+	DataRequestVariant
+	// DataResponseVariant is the data encryption key of the responses
+	DataResponseVariant
+)
+
+// variantMasks are XORed with both halves of the current key
+var variantMasks = map[Variant][8]byte{
+	PINVariant:          {0, 0, 0, 0, 0, 0, 0, 0xFF},
+	MACRequestVariant:   {0, 0, 0, 0, 0, 0, 0xFF, 0},
+	MACResponseVariant:  {0, 0, 0, 0, 0xFF, 0, 0, 0},
+	DataRequestVariant:  {0, 0, 0, 0, 0, 0xFF, 0, 0},
+	DataResponseVariant: {0, 0, 0, 0xFF, 0, 0, 0, 0},
+}
+
+// keyMask derives the left half of the keys, and the right half of the IPEK
+var keyMask = []byte{0xC0, 0xC0, 0xC0, 0xC0, 0, 0, 0, 0, 0xC0, 0xC0, 0xC0, 0xC0, 0, 0, 0, 0}
+
+// counterBits is the number of bits of the counter of a TDES KSN
+const counterBits = 21
+
+func checkTDES(key, ksn []byte) error {
+	if len(key) != 16 {
+		return errors.New("invalid TDES DUKPT key length: " + strconv.Itoa(len(key)))
+	}
+	if len(ksn) != TDESKSNLength {
+		return errors.New("invalid TDES DUKPT KSN length: " + strconv.Itoa(len(ksn)))
+	}
+	return nil
+}
+
+// tdesEncrypt enciphers a block with a double length key
+func tdesEncrypt(key, block []byte) ([]byte, error) {
+	k := make([]byte, 0, 24)
+	k = append(append(k, key...), key[:8]...)
+	c, err := des.NewTripleDESCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(block))
+	for i := 0; i < len(block); i += 8 {
+		c.Encrypt(res[i:i+8], block[i:i+8])
+	}
+	return res, nil
+}
+
+func xor(a, b []byte) []byte {
+	res := make([]byte, len(a))
+	for i := range a {
+		res[i] = a[i] ^ b[i]
+	}
+	return res
+}
+
+// IPEK returns the initial PIN encryption key of TDES DUKPT, from the BDK
+// of 16 bytes and a KSN of the device, its counter is ignored
+func IPEK(bdk, ksn []byte) ([]byte, error) {
+	if err := checkTDES(bdk, ksn); err != nil {
+		return nil, err
+	}
+	serial := append([]byte(nil), ksn[:8]...)
+	serial[7] &= 0xE0
+	left, err := tdesEncrypt(bdk, serial)
+	if err != nil {
+		return nil, err
+	}
+	right, err := tdesEncrypt(xor(bdk, keyMask), serial)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// generateKey is the non-reversible key generation process, the new key
+// of the current key and the 8 rightmost bytes of the KSN
+func generateKey(key, data []byte) ([]byte, error) {
+	half := func(key []byte) ([]byte, error) {
+		c, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, err
+		}
+		res := xor(data, key[8:])
+		c.Encrypt(res, res)
+		return xor(res, key[8:]), nil
+	}
+	right, err := half(key)
+	if err != nil {
+		return nil, err
+	}
+	left, err := half(xor(key, keyMask))
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// currentKey returns the future key of the counter of the KSN, derived
+// from the IPEK with one key generation per bit of the counter
+func currentKey(ipek, ksn []byte) ([]byte, error) {
+	reg := binary.BigEndian.Uint64(ksn[2:])
+	counter := reg & (1<<counterBits - 1)
+	reg &^= 1<<counterBits - 1
+	key := ipek
+	data := make([]byte, 8)
+	for bit := uint64(1) << (counterBits - 1); bit > 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		reg |= bit
+		binary.BigEndian.PutUint64(data, reg)
+		var err error
+		if key, err = generateKey(key, data); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// TDESKey returns the working key of the variant for the KSN, derived from
+// the IPEK of the device
+func TDESKey(ipek, ksn []byte, variant Variant) ([]byte, error) {
+	if err := checkTDES(ipek, ksn); err != nil {
+		return nil, err
+	}
+	mask, ok := variantMasks[variant]
+	if !ok {
+		return nil, errors.New("invalid TDES DUKPT variant: " + strconv.Itoa(int(variant)))
+	}
+	key, err := currentKey(ipek, ksn)
+	if err != nil {
+		return nil, err
+	}
+	key = xor(key, append(mask[:], mask[:]...))
+	if variant == DataRequestVariant || variant == DataResponseVariant {
+		// the data keys are the variants enciphered with themselves
+		return tdesEncrypt(key, key)
+	}
+	return key, nil
+}
+
+// TDESKeyFromBDK returns the working key of the variant for the KSN,
+// derived from the BDK, e.g. by a host
+func TDESKeyFromBDK(bdk, ksn []byte, variant Variant) ([]byte, error) {
+	ipek, err := IPEK(bdk, ksn)
+	if err != nil {
+		return nil, err
+	}
+	return TDESKey(ipek, ksn, variant)
+}
+
+// AESKSNLength is the length of the KSN of AES DUKPT, the initial key ID of
+// 8 bytes followed by a counter of 4 bytes
+const AESKSNLength = 12
+
+// KeyType is the algorithm and length of an AES DUKPT key
+type KeyType uint16
+
+const (
+	// TDES2 is a double length TDES key
+	TDES2 KeyType = 0
+	// TDES3 is a triple length TDES key
+	TDES3 KeyType = 1
+	// AES128 is an AES key of 128 bits
+	AES128 KeyType = 2
+	// AES192 is an AES key of 192 bits
+	AES192 KeyType = 3
+	// AES256 is an AES key of 256 bits
+	AES256 KeyType = 4
+)
+
+// Length returns the length of a key of the type in bytes, 0 if the type is invalid
+func (t KeyType) Length() int {
+	switch t {
+	case TDES2, AES128:
+		return 16
+	case TDES3, AES192:
+		return 24
+	case AES256:
+		return 32
+	}
+	return 0
+}
+
+// aesKeyType returns the type of a BDK or an initial key from its length
+func aesKeyType(key []byte) (KeyType, error) {
+	switch len(key) {
+	case 16:
+		return AES128, nil
+	case 24:
+		return AES192, nil
+	case 32:
+		return AES256, nil
+	}
+	return 0, errors.New("invalid AES DUKPT key length: " + strconv.Itoa(len(key)))
+}
+
+// KeyUsage is the usage of an AES DUKPT key, part of its derivation data
+type KeyUsage uint16
+
+// the key usages of X9.24-3
+const (
+	KeyEncryptionKey     KeyUsage = 0x0002
+	PINEncryption        KeyUsage = 0x1000
+	MACGeneration        KeyUsage = 0x2000
+	MACVerification      KeyUsage = 0x2001
+	MACBothWays          KeyUsage = 0x2002
+	DataEncryption       KeyUsage = 0x3000
+	DataDecryption       KeyUsage = 0x3001
+	DataBothWays         KeyUsage = 0x3002
+	KeyDerivation        KeyUsage = 0x8000
+	InitialKeyDerivation KeyUsage = 0x8001
+)
+
+// derivationData returns the derivation data of a key, with the initial key
+// ID for the initial key, its 4 rightmost bytes and the counter otherwise
+func derivationData(usage KeyUsage, keyType KeyType, ksn []byte, initial bool) []byte {
+	data := make([]byte, 16)
+	data[0] = 0x01
+	binary.BigEndian.PutUint16(data[2:], uint16(usage))
+	binary.BigEndian.PutUint16(data[4:], uint16(keyType))
+	binary.BigEndian.PutUint16(data[6:], uint16(keyType.Length()*8))
+	if initial {
+		copy(data[8:], ksn[:8])
+	} else {
+		copy(data[8:], ksn[4:12])
+	}
+	return data
+}
+
+// deriveKey enciphers the derivation data with the key, once per block of
+// the derived key, with the block counter in its second byte
+func deriveKey(key []byte, keyType KeyType, data []byte) ([]byte, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	length := keyType.Length()
+	res := make([]byte, 0, length+16)
+	block := make([]byte, 16)
+	for i := 1; len(res) < length; i++ {
+		data[1] = byte(i)
+		c.Encrypt(block, data)
+		res = append(res, block...)
+	}
+	return res[:length], nil
+}
+
+// AESInitialKey returns the initial key of AES DUKPT, from the BDK of 16,
+// 24 or 32 bytes and the initial key ID of 8 bytes, or a KSN of the device
+func AESInitialKey(bdk, ksn []byte) ([]byte, error) {
+	keyType, err := aesKeyType(bdk)
+	if err != nil {
+		return nil, err
+	}
+	if len(ksn) != 8 && len(ksn) != AESKSNLength {
+		return nil, errors.New("invalid AES DUKPT KSN length: " + strconv.Itoa(len(ksn)))
+	}
+	return deriveKey(bdk, keyType, derivationData(InitialKeyDerivation, keyType, ksn, true))
+}
+
+// AESKey returns the working key of the usage and the type for the KSN,
+// derived from the initial key of the device
+func AESKey(initialKey, ksn []byte, usage KeyUsage, keyType KeyType) ([]byte, error) {
+	derivationType, err := aesKeyType(initialKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(ksn) != AESKSNLength {
+		return nil, errors.New("invalid AES DUKPT KSN length: " + strconv.Itoa(len(ksn)))
+	}
+	if keyType.Length() == 0 {
+		return nil, errors.New("invalid AES DUKPT key type: " + strconv.Itoa(int(keyType)))
+	}
+	if keyType.Length() > derivationType.Length() {
+		return nil, errors.New("AES DUKPT working key longer than the initial key")
+	}
+
+	// the intermediate derivation key, one derivation per bit of the counter
+	counter := binary.BigEndian.Uint32(ksn[8:])
+	reg := append([]byte(nil), ksn...)
+	var working uint32
+	key := initialKey
+	for bit := uint32(1) << 31; bit > 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		working |= bit
+		binary.BigEndian.PutUint32(reg[8:], working)
+		if key, err = deriveKey(key, derivationType, derivationData(KeyDerivation, derivationType, reg, false)); err != nil {
+			return nil, err
+		}
+	}
+	return deriveKey(key, keyType, derivationData(usage, keyType, ksn, false))
+}
+
+// AESKeyFromBDK returns the working key of the usage and the type for the
+// KSN, derived from the BDK, e.g. by a host
+func AESKeyFromBDK(bdk, ksn []byte, usage KeyUsage, keyType KeyType) ([]byte, error) {
+	if len(ksn) != AESKSNLength {
+		return nil, errors.New("invalid AES DUKPT KSN length: " + strconv.Itoa(len(ksn)))
+	}
+	initialKey, err := AESInitialKey(bdk, ksn)
+	if err != nil {
+		return nil, err
+	}
+	return AESKey(initialKey, ksn, usage, keyType)
+}
@@ -0,0 +1,185 @@
+package dukpt
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/pin"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func equalHex(t *testing.T, b []byte, expected, name string) {
+	if got := strings.ToUpper(hex.EncodeToString(b)); got != expected {
+		t.Errorf("%s should be %s, got %s", name, expected, got)
+	}
+}
+
+// the test vectors of ANSI X9.24-1
+var (
+	testBDK = "0123456789ABCDEFFEDCBA9876543210"
+	testKSN = "FFFF9876543210E00001"
+)
+
+func TestIPEK(t *testing.T) {
+	ipek, err := IPEK(mustHex(t, testBDK), mustHex(t, testKSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHex(t, ipek, "6AC292FAA1315B4D858AB3A3D7D5933A", "IPEK")
+
+	if _, err := IPEK(mustHex(t, testBDK), mustHex(t, "9876543210E00001")); err == nil {
+		t.Error("KSN of 8 bytes should fail")
+	}
+	if _, err := IPEK(make([]byte, 24), mustHex(t, testKSN)); err == nil {
+		t.Error("triple length BDK should fail")
+	}
+}
+
+func TestTDESKey(t *testing.T) {
+	ipek := mustHex(t, "6AC292FAA1315B4D858AB3A3D7D5933A")
+	for _, test := range []struct {
+		ksn      string
+		variant  Variant
+		expected string
+	}{
+		{"FFFF9876543210E00001", PINVariant, "042666B49184CF5C68DE9628D0397B36"},
+		{"FFFF9876543210E00001", MACRequestVariant, "042666B4918430A368DE9628D03984C9"},
+		{"FFFF9876543210E00001", MACResponseVariant, "042666B46E84CFA368DE96282F397BC9"},
+		{"FFFF9876543210E00001", DataRequestVariant, "448D3F076D8304036A55A3D7E0055A78"},
+		{"FFFF9876543210E00001", DataResponseVariant, "AD7BFC8B06AD3A08A560B4105CF8D9E5"},
+	} {
+		key, err := TDESKey(ipek, mustHex(t, test.ksn), test.variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equalHex(t, key, test.expected, "key of variant "+strconv.Itoa(int(test.variant)))
+	}
+
+	// the keys of the next counters, one key generation per bit of the counter
+	for ksn, expected := range map[string]string{
+		"FFFF9876543210E00002": "C46551CEF9FD244FAA9AD834130D3B38",
+		"FFFF9876543210E00003": "0DF3D9422ACA561A47676D07AD6BAD05",
+	} {
+		key, err := TDESKeyFromBDK(mustHex(t, testBDK), mustHex(t, ksn), PINVariant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equalHex(t, key, expected, "PIN key of "+ksn)
+	}
+
+	if _, err := TDESKey(ipek, mustHex(t, testKSN), Variant(5)); err == nil {
+		t.Error("invalid variant should fail")
+	}
+}
+
+func TestDecryptPINBlock(t *testing.T) {
+	// the test host receives DE52 enciphered with the DUKPT PIN key of DE53
+	sc, err := iso8583.ParseSecurityControl("20010100" + testKSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &iso8583.Message{Mti: "0200", DE52: iso8583.NewBinary64Hex("1B9C1845EB993A7A"), DE53: sc}
+
+	key, err := TDESKeyFromBDK(mustHex(t, testBDK), m.DE53.KSN, PINVariant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := pin.TDES(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := pin.FromField(m.DE52)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, format, err := pin.Decrypt(c, block, "4012345678909")
+	if err != nil || p != "1234" || format != pin.Format0 {
+		t.Errorf("PIN should be 1234 in format 0, got %s %d %v", p, format, err)
+	}
+}
+
+// the test vectors of ANSI X9.24-3
+var (
+	testAESBDK = "FEDCBA9876543210F1F1F1F1F1F1F1F1"
+	testAESKSN = "123456789012345600000001"
+)
+
+func TestAESInitialKey(t *testing.T) {
+	key, err := AESInitialKey(mustHex(t, testAESBDK), mustHex(t, "1234567890123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHex(t, key, "1273671EA26AC29AFA4D1084127652A1", "initial key")
+
+	// the counter of a KSN is ignored
+	fromKSN, err := AESInitialKey(mustHex(t, testAESBDK), mustHex(t, testAESKSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHex(t, fromKSN, "1273671EA26AC29AFA4D1084127652A1", "initial key of the KSN")
+
+	if _, err := AESInitialKey(mustHex(t, testBDK)[:8], mustHex(t, testAESKSN)); err == nil {
+		t.Error("BDK of 8 bytes should fail")
+	}
+}
+
+func TestAESKey(t *testing.T) {
+	initialKey := mustHex(t, "1273671EA26AC29AFA4D1084127652A1")
+	key, err := AESKey(initialKey, mustHex(t, testAESKSN), PINEncryption, AES128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHex(t, key, "AF8CB133A78F8DC2D1359F18527593FB", "PIN key")
+
+	fromBDK, err := AESKeyFromBDK(mustHex(t, testAESBDK), mustHex(t, testAESKSN), PINEncryption, AES128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHex(t, fromBDK, "AF8CB133A78F8DC2D1359F18527593FB", "PIN key from the BDK")
+
+	// the key of another usage or type is another key
+	mac, err := AESKey(initialKey, mustHex(t, testAESKSN), MACGeneration, AES128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(mac) == hex.EncodeToString(key) {
+		t.Error("MAC key should not be the PIN key")
+	}
+	tdes, err := AESKey(initialKey, mustHex(t, testAESKSN), PINEncryption, TDES2)
+	if err != nil || len(tdes) != 16 || hex.EncodeToString(tdes) == hex.EncodeToString(key) {
+		t.Errorf("TDES PIN key should differ from the AES PIN key, got %X %v", tdes, err)
+	}
+
+	// an AES PIN block of format 4 is deciphered with the PIN key
+	c, err := pin.AES(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := pin.Encrypt(c, pin.Format4, "1234", "4111111111111111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, err := pin.Decrypt(c, block, "4111111111111111"); err != nil || p != "1234" {
+		t.Errorf("PIN should be 1234, got %s %v", p, err)
+	}
+
+	if _, err := AESKey(initialKey, mustHex(t, testAESKSN), PINEncryption, AES256); err == nil {
+		t.Error("AES-256 key from an AES-128 initial key should fail")
+	}
+	if _, err := AESKey(initialKey, mustHex(t, testAESKSN), PINEncryption, KeyType(9)); err == nil {
+		t.Error("invalid key type should fail")
+	}
+	if _, err := AESKey(initialKey, mustHex(t, "1234567890123456"), PINEncryption, AES128); err == nil {
+		t.Error("KSN of 8 bytes should fail")
+	}
+}
@@ -2,8 +2,12 @@ package iso8583
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -21,6 +25,12 @@ type field interface {
 	isEmpty() bool
 }
 
+// configurable is implemented by the field types which read
+// extra settings from their struct tag, e.g. TLV
+type configurable interface {
+	configure(tag reflect.StructTag) error
+}
+
 type N struct {
 	Value []byte
 }
@@ -31,7 +41,7 @@ func NewNumeric(value string) *N {
 
 func (n *N) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := n.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add left padding with '0', else
@@ -70,6 +80,9 @@ func (n *N) Decode(raw []byte, encoder, length int, format, validator string) (i
 	case ASCII:
 		if format == "" {
 			//n.value = bytes.TrimLeft(raw[:length], "0")
+			if len(raw) < length {
+				return 0, errors.New("invalid value length")
+			}
 			n.Value = raw[:length]
 			nextFieldOffset = length
 		} else {
@@ -82,7 +95,7 @@ func (n *N) Decode(raw []byte, encoder, length int, format, validator string) (i
 		}
 	}
 
-	err := validate(string(n.Value), validator)
+	err := validateBytes(n.Value, validator)
 	return nextFieldOffset, err
 }
 
@@ -114,7 +127,7 @@ func NewAlphanumeric(value string) *AN {
 
 func (an *AN) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := an.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add right padding with ' ', else
@@ -151,6 +164,9 @@ func (an *AN) Decode(raw []byte, encoder, length int, format, validator string)
 	case BCDIC:
 	case ASCII:
 		if format == "" {
+			if len(raw) < length {
+				return 0, errors.New("invalid value length")
+			}
 			an.Value = bytes.TrimRight(raw[:length], " ")
 			nextFieldOffset = length
 		} else {
@@ -163,7 +179,7 @@ func (an *AN) Decode(raw []byte, encoder, length int, format, validator string)
 		}
 	}
 
-	err := validate(string(an.Value), validator)
+	err := validateBytes(an.Value, validator)
 	return nextFieldOffset, err
 }
 
@@ -208,7 +224,7 @@ func NewBinary64Hex(value string) *B64 {
 
 func (b *B64) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := b.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 
@@ -224,8 +240,11 @@ func (b *B64) Decode(raw []byte, encoder, length int, format, validator string)
 	switch encoder {
 	case BCDIC:
 	case ASCII:
+		if len(raw) < length/4 {
+			return 0, errors.New("invalid value length")
+		}
 		b.Value = raw[:length/4]
-		err := validate(string(b.Value), validator)
+		err := validateBytes(b.Value, validator)
 		return length / 4, err
 	}
 	return 0, nil
@@ -260,7 +279,7 @@ func NewBN(hex string) *BN {
 
 func (b *BN) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := b.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add right padding with ' ', else
@@ -302,7 +321,7 @@ func (b *BN) Decode(raw []byte, encoder, length int, format, validator string) (
 			nextFieldOffset = lenOfLen + l
 		}
 	}
-	err := validate(string(b.Value), validator)
+	err := validateBytes(b.Value, validator)
 	return nextFieldOffset, err
 }
 
@@ -334,7 +353,7 @@ func NewTrack2Code(value string) *Z {
 
 func (z *Z) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := z.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add right padding with ' ', else
@@ -378,7 +397,7 @@ func (z *Z) Decode(raw []byte, encoder, length int, format, validator string) (i
 		nextFieldOffset = lenOfLen + l
 	}
 
-	err := validate(string(z.Value), validator)
+	err := validateBytes(z.Value, validator)
 	return nextFieldOffset, err
 }
 func (z *Z) isEmpty() bool {
@@ -409,7 +428,7 @@ func NewANP(value string) *ANP {
 
 func (anp *ANP) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := anp.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add right padding with ' ', else
@@ -446,6 +465,9 @@ func (anp *ANP) Decode(raw []byte, encoder, length int, format, validator string
 	case BCDIC:
 	case ASCII:
 		if format == "" {
+			if len(raw) < length {
+				return 0, errors.New("invalid value length")
+			}
 			anp.Value = bytes.TrimRight(raw[:length], " ")
 			nextFieldOffset = length
 		} else {
@@ -458,7 +480,7 @@ func (anp *ANP) Decode(raw []byte, encoder, length int, format, validator string
 		}
 	}
 
-	err := validate(string(anp.Value), validator)
+	err := validateBytes(anp.Value, validator)
 	return nextFieldOffset, err
 }
 
@@ -490,7 +512,7 @@ func NewANS(value string) *ANS {
 
 func (ans *ANS) Encode(encoder, length int, format, validator string) ([]byte, error) {
 	val := ans.Value
-	if err := validate(string(val), validator); err != nil {
+	if err := validateBytes(val, validator); err != nil {
 		return []byte{}, err
 	}
 	// if field has fixed length, add right padding with ' ', else
@@ -527,6 +549,9 @@ func (ans *ANS) Decode(raw []byte, encoder, length int, format, validator string
 	case BCDIC:
 	case ASCII:
 		if format == "" {
+			if len(raw) < length {
+				return 0, errors.New("invalid value length")
+			}
 			ans.Value = bytes.TrimRight(raw[:length], " ")
 			nextFieldOffset = length
 		} else {
@@ -539,7 +564,7 @@ func (ans *ANS) Decode(raw []byte, encoder, length int, format, validator string
 		}
 	}
 
-	err := validate(string(ans.Value), validator)
+	err := validateBytes(ans.Value, validator)
 	return nextFieldOffset, err
 }
 
@@ -561,6 +586,221 @@ func (ans *ANS) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// TaggedANS is a fixed length ANS field, extended by optional tagged fields
+// appended to it, e.g. SE21 is ANS 194, or ANS 255 if tagged field 0008 is
+// included. The tagged fields and the length of their value are given by the
+// tagged struct tag, `tagged:"<tag>:<length>[,<tag>:<length>]"`
+type TaggedANS struct {
+	Value []byte
+	Tags  []TLVElement
+
+	// layout are the tagged fields of the struct tag, longest tag first
+	layout []taggedField
+}
+
+type taggedField struct {
+	tag    string
+	length int
+}
+
+func NewTaggedANS(value string) *TaggedANS {
+	return &TaggedANS{Value: []byte(value)}
+}
+
+// Get returns the value of the tagged field
+func (ta *TaggedANS) Get(tag string) ([]byte, bool) {
+	for _, e := range ta.Tags {
+		if e.Tag == tag {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of the tagged field, or appends it if it is missing.
+// The tag must be in the tagged struct tag of the field, or Encode fails.
+func (ta *TaggedANS) Set(tag string, value []byte) {
+	for i := range ta.Tags {
+		if ta.Tags[i].Tag == tag {
+			ta.Tags[i].Value = value
+			return
+		}
+	}
+	ta.Tags = append(ta.Tags, TLVElement{Tag: tag, Value: value})
+}
+
+func (ta *TaggedANS) Encode(encoder, length int, format, validator string) ([]byte, error) {
+	if err := validateBytes(ta.Value, validator); err != nil {
+		return []byte{}, err
+	}
+	if format != "" {
+		return []byte{}, errors.New("TaggedANS has fixed length")
+	}
+	if len(ta.Value) > length {
+		return nil, errors.New("invalid value length")
+	}
+
+	// the values may be subslices of a decoded message, they are copied
+	// into a new slice instead of being padded in place
+	n := length
+	for _, e := range ta.Tags {
+		// a tag out of the layout could not be found by Decode
+		l, ok := ta.length(e.Tag)
+		if !ok {
+			return nil, errors.New("tag out of the tagged layout: " + e.Tag)
+		}
+		if len(e.Value) > l {
+			return nil, errors.New("invalid value length of tag " + e.Tag)
+		}
+		n += len(e.Tag) + l
+	}
+	val := make([]byte, 0, n)
+	val = append(val, ta.Value...)
+	val = append(val, bytes.Repeat([]byte(" "), length-len(ta.Value))...)
+
+	for _, e := range ta.Tags {
+		if err := validateBytes(e.Value, validator); err != nil {
+			return []byte{}, err
+		}
+		l, _ := ta.length(e.Tag)
+		val = append(val, e.Tag...)
+		val = append(val, e.Value...)
+		val = append(val, bytes.Repeat([]byte(" "), l-len(e.Value))...)
+	}
+
+	switch encoder {
+	case BCDIC:
+		panic("implement me")
+	default: //ASCII encoding
+		return val, nil
+	}
+}
+
+func (ta *TaggedANS) Decode(raw []byte, encoder, length int, format, validator string) (int, error) {
+	var nextFieldOffset int
+	switch encoder {
+	case BCDIC:
+	case ASCII:
+		if format != "" {
+			return 0, errors.New("TaggedANS has fixed length")
+		}
+		if len(raw) < length {
+			return 0, errors.New("invalid value length")
+		}
+		ta.Value = bytes.TrimRight(raw[:length], " ")
+		nextFieldOffset = length
+		ta.Tags = nil
+
+		// tagged fields follow each other while the next bytes are a known tag
+	tags:
+		for {
+			for _, f := range ta.layout {
+				next := raw[nextFieldOffset:]
+				if len(next) >= len(f.tag)+f.length && string(next[:len(f.tag)]) == f.tag {
+					ta.Tags = append(ta.Tags, TLVElement{Tag: f.tag, Value: bytes.TrimRight(next[len(f.tag):len(f.tag)+f.length], " ")})
+					nextFieldOffset += len(f.tag) + f.length
+					continue tags
+				}
+			}
+			break
+		}
+	}
+
+	if err := validateBytes(ta.Value, validator); err != nil {
+		return nextFieldOffset, err
+	}
+	for _, e := range ta.Tags {
+		if err := validateBytes(e.Value, validator); err != nil {
+			return nextFieldOffset, err
+		}
+	}
+	return nextFieldOffset, nil
+}
+
+func (ta *TaggedANS) isEmpty() bool {
+	return len(ta.Value) == 0 && len(ta.Tags) == 0
+}
+
+// configure reads the tagged fields from the tagged struct tag
+func (ta *TaggedANS) configure(tag reflect.StructTag) error {
+	ta.layout = nil
+	if tag.Get("tagged") == "" {
+		return nil
+	}
+	for _, opt := range strings.Split(tag.Get("tagged"), ",") {
+		parts := strings.Split(opt, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.New("invalid tagged option: " + opt)
+		}
+		l, err := strconv.Atoi(parts[1])
+		if err != nil || l < 0 {
+			return errors.New("invalid tagged length: " + opt)
+		}
+		ta.layout = append(ta.layout, taggedField{tag: parts[0], length: l})
+	}
+	// a tag is matched before the shorter tags it starts with
+	sort.SliceStable(ta.layout, func(i, j int) bool {
+		return len(ta.layout[i].tag) > len(ta.layout[j].tag)
+	})
+	return nil
+}
+
+// length returns the length of the value of a tag of the layout
+func (ta *TaggedANS) length(tag string) (int, bool) {
+	for _, f := range ta.layout {
+		if f.tag == tag {
+			return f.length, true
+		}
+	}
+	return 0, false
+}
+
+func (ta TaggedANS) String() string {
+	return string(ta.Value)
+}
+
+// MarshalJSON encodes the field as a string, or as an object
+// if tagged fields are included
+func (ta *TaggedANS) MarshalJSON() ([]byte, error) {
+	if len(ta.Tags) == 0 {
+		return json.Marshal(string(ta.Value))
+	}
+	tags := make(map[string]string, len(ta.Tags))
+	for _, e := range ta.Tags {
+		tags[e.Tag] = string(e.Value)
+	}
+	return json.Marshal(struct {
+		Value string
+		Tags  map[string]string
+	}{string(ta.Value), tags})
+}
+
+func (ta *TaggedANS) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		ta.Value = []byte(value)
+		return nil
+	}
+	var obj struct {
+		Value string
+		Tags  map[string]string
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	ta.Value = []byte(obj.Value)
+	tags := make([]string, 0, len(obj.Tags))
+	for tag := range obj.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	ta.Tags = nil
+	for _, tag := range tags {
+		ta.Set(tag, []byte(obj.Tags[tag]))
+	}
+	return nil
+}
+
 type Reserved struct {
 	Value []byte
 }
@@ -626,55 +866,63 @@ func getFieldLength(raw []byte, encoder, maxLength int, format string) (length,
 	switch format {
 	case "LLVAR":
 		lenOfLen = 2
-		length, err = strconv.Atoi(string(raw[:2]))
-		if err != nil {
-			return length, lenOfLen, err
-		}
-		if length > maxLength {
-			return length, lenOfLen, errors.New("invalid length")
-		}
-		return length, lenOfLen, err
 	case "LLLVAR":
 		lenOfLen = 3
-		length, err := strconv.Atoi(string(raw[:3]))
-		if err != nil {
-			return length, lenOfLen, err
-
-		}
-		if length > maxLength {
-			return length, lenOfLen, errors.New("invalid length")
-		}
-		return length, lenOfLen, err
-
 	case "LLLLVAR":
 		lenOfLen = 4
-		length, err := strconv.Atoi(string(raw[4]))
-		if err != nil {
-			return length, lenOfLen, err
-		}
-		if length > maxLength {
-			return length, lenOfLen, errors.New("invalid length")
-		}
-		return length, lenOfLen, err
-
 	case "LLLLLVAR":
 		lenOfLen = 5
-		length, err := strconv.Atoi(string(raw[:5]))
-		if err != nil {
-			return length, lenOfLen, err
-		}
-		if length > maxLength {
-			return length, lenOfLen, errors.New("invalid length")
-		}
-		return length, lenOfLen, err
-
 	case "":
 		lenOfLen = 0
 		return length, lenOfLen, err
-
 	default:
 		return length, lenOfLen, errors.New("invalid format")
 	}
+
+	if len(raw) < lenOfLen {
+		return length, lenOfLen, errors.New("invalid length")
+	}
+	length, err = strconv.Atoi(string(raw[:lenOfLen]))
+	if err != nil {
+		return length, lenOfLen, err
+	}
+	if length > maxLength || len(raw) < lenOfLen+length {
+		return length, lenOfLen, errors.New("invalid length")
+	}
+	return length, lenOfLen, err
+}
+
+// validateBytes is validate without converting the value to a string if it
+// is valid, e.g. for the values aliasing the decoded bytes
+func validateBytes(value []byte, validator string) error {
+	var re *regexp.Regexp
+	switch validator {
+	case "N":
+		re = numberRegex
+	case "B64":
+		re = binary64Regex
+	case "BN":
+		re = binaryRegex
+	case "AN":
+		re = alphaNumericRegex
+	case "Z":
+		re = track2Regex
+	case "ANP":
+		re = anpRegex
+	case "ANS":
+		re = ansRegex
+	case "YYMMDDHHMMSS", "MMDDHHMMSS", "YYMMDD", "YYMM", "MMDD":
+		if p, err := splitDateTime(string(value), validator); err == nil && p.validCalendar() {
+			return nil
+		}
+	case "":
+		return nil
+	}
+	if re != nil && re.Match(value) {
+		return nil
+	}
+	// the calendar and the code validators, and the errors
+	return validate(string(value), validator)
 }
 
 func validate(value, validator string) error {
@@ -711,22 +959,33 @@ func validate(value, validator string) error {
 		if !yymmddhhmmssRegex.MatchString(value) {
 			return errors.New("invalid YYMMDDHHMMSS value format: " + value)
 		}
+		return validateCalendar(value, "YYMMDDHHMMSS")
 	case "MMDDHHMMSS":
 		if !mmddhhmmssRegex.MatchString(value) {
 			return errors.New("invalid MMDDHHMMSS value format: " + value)
 		}
+		return validateCalendar(value, "MMDDHHMMSS")
 	case "YYMM":
 		if !yymmRegex.MatchString(value) {
 			return errors.New("invalid YYMM value format: " + value)
 		}
+		return validateCalendar(value, "YYMM")
 	case "MMDD":
 		if !mmddRegex.MatchString(value) {
 			return errors.New("invalid MMDD value format: " + value)
 		}
+		return validateCalendar(value, "MMDD")
+	case "PAN":
+		return validatePAN(value)
+	case "CURRENCY":
+		return validateCurrency(value)
+	case "COUNTRY":
+		return validateCountry(value)
 	case "YYMMDD":
 		if !yymmddRegex.MatchString(value) {
 			return errors.New("invalid YYMMDD value format: " + value)
 		}
+		return validateCalendar(value, "YYMMDD")
 	}
 	return nil
 }
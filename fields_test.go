@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -205,6 +206,97 @@ func TestToLog(t *testing.T) {
 	}
 }
 
+func TestTaggedANS(t *testing.T) {
+	ta := NewTaggedANS("base")
+	if err := ta.configure(`tagged:"0008:5,09:2"`); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ta.Encode(ASCII, 6, "", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "base  ", "without tags")
+
+	ta.Set("0008", []byte("abc"))
+	ta.Set("09", []byte("xy"))
+	b, err = ta.Encode(ASCII, 6, "", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "base  0008abc  09xy", "with tags")
+
+	decoded := &TaggedANS{}
+	decoded.configure(`tagged:"0008:5,09:2"`)
+	n, err := decoded.Decode([]byte("base  0008abc  09xyNEXT"), ASCII, 6, "", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 19 {
+		t.Errorf("next field offset should be 19, instead of %d", n)
+	}
+	equals(t, decoded.String(), "base", "")
+	v, ok := decoded.Get("0008")
+	equals(t, string(v), "abc", "tag 0008")
+	if !ok {
+		t.Error("tag 0008 should be included")
+	}
+
+	ta.Set("0008", []byte("abcdef"))
+	if _, err := ta.Encode(ASCII, 6, "", "ANS"); err == nil {
+		t.Error("expecting error, value of tag 0008 is too long")
+	}
+	if err := ta.configure(`tagged:"0008"`); err == nil {
+		t.Error("expecting error, missing length of tag")
+	}
+}
+
+func TestTaggedANSDecodeThenEncode(t *testing.T) {
+	raw := []byte("base  0008abc  NEXT")
+	decoded := &TaggedANS{}
+	decoded.configure(`tagged:"0008:5"`)
+	if _, err := decoded.Decode(raw, ASCII, 6, "", "ANS"); err != nil {
+		t.Fatal(err)
+	}
+	// the trimmed values are subslices of raw, encoding must not pad them in place
+	decoded.Set("0008", []byte("ab"))
+	decoded.Value = decoded.Value[:2]
+	b, err := decoded.Encode(ASCII, 6, "", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "ba    0008ab   ", "")
+	equals(t, string(raw), "base  0008abc  NEXT", "decoded buffer")
+}
+
+func TestTaggedANSLongestTagFirst(t *testing.T) {
+	// 0008 starts with the tag 00, it must be matched first whatever the
+	// order of the struct tag
+	for _, tag := range []reflect.StructTag{`tagged:"00:4,0008:5"`, `tagged:"0008:5,00:4"`} {
+		for i := 0; i < 20; i++ {
+			decoded := &TaggedANS{}
+			decoded.configure(tag)
+			n, err := decoded.Decode([]byte("base  0008abc  "), ASCII, 6, "", "ANS")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 15 {
+				t.Fatalf("next field offset should be 15, instead of %d", n)
+			}
+			v, _ := decoded.Get("0008")
+			equals(t, string(v), "abc", string(tag))
+		}
+	}
+}
+
+func TestTaggedANSTagOutOfLayout(t *testing.T) {
+	ta := NewTaggedANS("base")
+	ta.configure(`tagged:"0008:5"`)
+	ta.Set("0009", []byte("abc"))
+	if _, err := ta.Encode(ASCII, 6, "", "ANS"); err == nil {
+		t.Error("expecting error, tag 0009 is out of the layout")
+	}
+}
+
 func equals(t *testing.T, actual, expected, description string) {
 	if description == "" {
 		description = "empty description"
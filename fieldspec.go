@@ -0,0 +1,193 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// FieldSpec describes a subfield of a bitmapped struct by its struct tags
+type FieldSpec struct {
+	// Name is the name of the struct field, e.g. DE2
+	Name string
+	// Index is the field number, e.g. 2, -1 if the name has no number
+	Index int
+	// Length is the (maximum) length, 0 if it has no length tag,
+	// -1 if the length tag is invalid
+	Length    int
+	Format    string
+	Validator string
+	// Tag is the whole struct tag, for the settings of the field type,
+	// e.g. `tlv:"2,2"`, and for the bitmap layout of submessages
+	Tag reflect.StructTag
+	// SubMessage is true if the field is a nested bitmapped struct
+	SubMessage bool
+}
+
+// Generated is implemented by the bitmapped structs with code generated by
+// cmd/iso8583gen. Encoding, decoding and validation of these structs
+// access their fields without reflection.
+type Generated interface {
+	// ISO8583Fields returns the specs of the fields, in ascending order.
+	// The slice is shared by every value of the type, it is read-only.
+	ISO8583Fields() []FieldSpec
+	// ISO8583Field returns the field i of ISO8583Fields, nil if it is empty
+	ISO8583Field(i int) interface{}
+	// ISO8583NewField sets the field i of ISO8583Fields to a new empty value,
+	// and returns it
+	ISO8583NewField(i int) interface{}
+	// ISO8583SetSecondaryBitmap sets the uint64 field with number 1, if any
+	ISO8583SetSecondaryBitmap(bitmap uint64)
+}
+
+// structOf returns the access to the fields of sm, a pointer to a bitmapped
+// struct, by its generated code if any, by reflection otherwise
+func structOf(sm interface{}) Generated {
+	if g, ok := sm.(Generated); ok {
+		return g
+	}
+	return newReflectStruct(reflect.ValueOf(sm))
+}
+
+// nestedStructOf returns the access to sm, a struct nested in the struct of
+// parent, by reflection if parent is accessed by reflection, e.g. in the
+// tests comparing the generated code to reflection
+func nestedStructOf(parent Generated, sm interface{}) Generated {
+	if v, ok := parent.(version1987); ok {
+		parent = v.Generated
+	}
+	if _, ok := parent.(*reflectStruct); ok {
+		return newReflectStruct(reflect.ValueOf(sm))
+	}
+	return structOf(sm)
+}
+
+// reflectStruct accesses the fields of a bitmapped struct by reflection
+type reflectStruct struct {
+	v reflect.Value
+	*structMeta
+}
+
+// structMeta is the field metadata of a bitmapped struct type
+type structMeta struct {
+	specs  []FieldSpec
+	fields []int
+	// secondary is the struct field of the secondary bitmap, -1 if there is none
+	secondary int
+}
+
+// structMetas caches the structMeta of every struct type, by reflect.Type
+var structMetas sync.Map
+
+func newReflectStruct(v reflect.Value) *reflectStruct {
+	v = reflect.Indirect(v)
+	return &reflectStruct{v: v, structMeta: structMetaOf(v.Type())}
+}
+
+func structMetaOf(t reflect.Type) *structMeta {
+	if meta, ok := structMetas.Load(t); ok {
+		return meta.(*structMeta)
+	}
+	meta := &structMeta{secondary: -1}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// skip unexported fields
+		if sf.PkgPath != "" {
+			continue
+		}
+		if sf.Type.Kind() == reflect.Uint64 {
+			if index, err := fieldIndex(sf.Name); err == nil && index == 1 {
+				meta.secondary = i
+			}
+			continue
+		}
+		if sf.Type.Kind() != reflect.Ptr {
+			continue
+		}
+		meta.specs = append(meta.specs, fieldSpecOf(sf))
+		meta.fields = append(meta.fields, i)
+	}
+	structMetas.Store(t, meta)
+	return meta
+}
+
+var fieldType = reflect.TypeOf((*field)(nil)).Elem()
+
+// fieldSpecOf returns the spec of a pointer struct field
+func fieldSpecOf(sf reflect.StructField) FieldSpec {
+	fs := FieldSpec{
+		Name:      sf.Name,
+		Index:     -1,
+		Format:    sf.Tag.Get("format"),
+		Validator: sf.Tag.Get("validator"),
+		Tag:       sf.Tag,
+		SubMessage: sf.Type.Elem().Kind() == reflect.Struct &&
+			!sf.Type.Implements(fieldType),
+	}
+	if index, err := fieldIndex(sf.Name); err == nil {
+		fs.Index = index
+	}
+	if l, ok := sf.Tag.Lookup("length"); ok {
+		fs.Length = -1
+		if length, err := strconv.Atoi(l); err == nil && length >= 0 {
+			fs.Length = length
+		}
+	}
+	return fs
+}
+
+func (s *reflectStruct) ISO8583Fields() []FieldSpec {
+	return s.specs
+}
+
+func (s *reflectStruct) ISO8583Field(i int) interface{} {
+	f := s.v.Field(s.fields[i])
+	if f.IsNil() {
+		return nil
+	}
+	return f.Interface()
+}
+
+func (s *reflectStruct) ISO8583NewField(i int) interface{} {
+	f := s.v.Field(s.fields[i])
+	f.Set(reflect.New(f.Type().Elem()))
+	return f.Interface()
+}
+
+func (s *reflectStruct) ISO8583SetSecondaryBitmap(bitmap uint64) {
+	if s.secondary >= 0 {
+		s.v.Field(s.secondary).SetUint(bitmap)
+	}
+}
+
+func (s *reflectStruct) setPrimaryBitmap(bitmap uint64) {
+	if h, ok := s.v.Addr().Interface().(bitmapHolder); ok {
+		h.setPrimaryBitmap(bitmap)
+	}
+}
+
+// check returns the error of an invalid field number or length tag
+func (fs *FieldSpec) check() error {
+	if fs.Index < 0 {
+		return errors.New("invalid field name: " + fs.Name)
+	}
+	if fs.Length < 0 {
+		return errors.New("invalid length of field: " + fs.Name)
+	}
+	return nil
+}
+
+// asField returns v as a field, or an error if its type is not a field type
+func (fs *FieldSpec) asField(v interface{}) (field, error) {
+	f, ok := v.(field)
+	if !ok {
+		return nil, errors.New("invalid type of field: " + fs.Name)
+	}
+	if c, ok := f.(configurable); ok {
+		if err := c.configure(fs.Tag); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
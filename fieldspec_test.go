@@ -0,0 +1,90 @@
+package iso8583
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGeneratedFieldSpecs(t *testing.T) {
+	for _, sm := range []Generated{&Message{}, &SubMessage{}} {
+		expected := newReflectStruct(reflect.ValueOf(sm)).ISO8583Fields()
+		if !reflect.DeepEqual(sm.ISO8583Fields(), expected) {
+			t.Errorf("generated field specs of %T differ from its struct, run go generate", sm)
+		}
+	}
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	newMessage := func() *Message {
+		m := &Message{
+			DE2:   NewNumeric("4846811212"),
+			DE3:   NewNumeric("201234"),
+			DE7:   NewDateTime("1107221800"),
+			DE41:  NewANS("termid12"),
+			DE102: NewANS("12341234234"),
+			DE125: &SubMessage{
+				SE2:  NewANS("Test Address"),
+				SE21: NewTaggedANS("Test Tagged"),
+				SE98: NewAlphanumeric("1"),
+			},
+		}
+		m.Mti = "1200"
+		return m
+	}
+
+	generated, err := newMessage().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reflected, err := newMessage().appendEncode(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != string(reflected) {
+		t.Errorf("generated encoding %s differs from reflection %s", generated, reflected)
+	}
+
+	m := &Message{}
+	if err := m.decode(generated, false); err != nil {
+		t.Fatal(err)
+	}
+	gm := &Message{}
+	if err := gm.Decode(generated); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, gm) {
+		t.Errorf("generated decoding %v differs from reflection %v", gm, m)
+	}
+}
+
+func TestReflectionFieldSpecErrors(t *testing.T) {
+	type invalid struct {
+		SF1 uint64
+		SFX *N `format:"" length:"4" validator:"N"`
+	}
+	if _, err := EncodeSubMessage(&invalid{SFX: NewNumeric("1")}, ASCII, DefaultBitmap); err == nil {
+		t.Error("field without number should fail")
+	}
+
+	type invalidLength struct {
+		SF2 *N `format:"" length:"four" validator:"N"`
+	}
+	if _, err := EncodeSubMessage(&invalidLength{SF2: NewNumeric("1")}, ASCII, DefaultBitmap); err == nil {
+		t.Error("field with invalid length should fail")
+	}
+}
+
+func TestStructMetaCache(t *testing.T) {
+	type custom struct {
+		SF1 uint64
+		SF2 *N `format:"" length:"4" validator:"N"`
+	}
+	typ := reflect.TypeOf(custom{})
+	meta := structMetaOf(typ)
+	if structMetaOf(typ) != meta {
+		t.Error("field metadata should be cached")
+	}
+	if len(meta.specs) != 1 || meta.specs[0].Length != 4 || meta.secondary != 0 {
+		t.Errorf("unexpected field metadata %+v", meta)
+	}
+}
@@ -0,0 +1,204 @@
+package iso8583
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Framer is the frame around every message on a link, the bytes before
+// the MTI: a length prefix, and an optional header, e.g. a TPDU
+type Framer interface {
+	// FrameSize returns the size of the frame before a message with the header
+	FrameSize(header []byte) (int, error)
+	// PutFrame writes the frame of a message of length n with the header
+	// to b, the first FrameSize bytes before the message
+	PutFrame(b, header []byte, n int) error
+	// ReadFrame reads the next framed message from r, and returns its
+	// header and the message. max limits the length of the message,
+	// 0 means DefaultMaxLength.
+	ReadFrame(r io.Reader, max int) (header, msg []byte, err error)
+}
+
+// DefaultMaxLength is the maximum length of the messages read without
+// limit, the length prefix comes from the peer and is allocated at once
+const DefaultMaxLength = 64 << 10
+
+var (
+	// Binary2Framer is a 2 byte binary length prefix
+	Binary2Framer Framer = LengthHeader{Size: 2, Encoding: LengthBinary}
+	// ASCII4Framer is a 4 digit ASCII length prefix
+	ASCII4Framer Framer = LengthHeader{Size: 4, Encoding: LengthASCII}
+	// TPDUFramer is a 2 byte binary length prefix, followed by a 5 byte TPDU
+	TPDUFramer Framer = HeaderFramer{Length: LengthHeader{Size: 2, Encoding: LengthBinary}, HeaderSize: TPDUSize}
+)
+
+// FrameSize returns the size of the length, the header must be empty
+func (h LengthHeader) FrameSize(header []byte) (int, error) {
+	if len(header) > 0 {
+		return 0, errors.New("length header frame without header")
+	}
+	return h.Size, nil
+}
+
+// PutFrame writes the length of a message of length n to b
+func (h LengthHeader) PutFrame(b, header []byte, n int) error {
+	if len(header) > 0 {
+		return errors.New("length header frame without header")
+	}
+	return h.encode(b[:h.Size], n)
+}
+
+// ReadFrame reads the length, and the message
+func (h LengthHeader) ReadFrame(r io.Reader, max int) (header, msg []byte, err error) {
+	prefix := make([]byte, h.Size)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, nil, err
+	}
+	l, err := h.decode(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if max <= 0 {
+		max = DefaultMaxLength
+	}
+	if l > max {
+		return nil, nil, errors.New("message too long: " + strconv.Itoa(l))
+	}
+	msg = make([]byte, l)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	return nil, msg, nil
+}
+
+// HeaderFramer is a length prefix followed by a fixed size header, e.g. a TPDU,
+// or a proprietary header. The length counts the header too.
+type HeaderFramer struct {
+	Length     LengthHeader
+	HeaderSize int
+}
+
+func (f HeaderFramer) FrameSize(header []byte) (int, error) {
+	if len(header) != f.HeaderSize {
+		return 0, errors.New("invalid frame header size: " + strconv.Itoa(len(header)))
+	}
+	return f.Length.Size + f.HeaderSize, nil
+}
+
+func (f HeaderFramer) PutFrame(b, header []byte, n int) error {
+	if len(header) != f.HeaderSize {
+		return errors.New("invalid frame header size: " + strconv.Itoa(len(header)))
+	}
+	if err := f.Length.encode(b[:f.Length.Size], f.HeaderSize+n); err != nil {
+		return err
+	}
+	copy(b[f.Length.Size:], header)
+	return nil
+}
+
+func (f HeaderFramer) ReadFrame(r io.Reader, max int) (header, msg []byte, err error) {
+	if max <= 0 {
+		max = DefaultMaxLength
+	}
+	_, payload, err := f.Length.ReadFrame(r, max+f.HeaderSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(payload) < f.HeaderSize {
+		return nil, nil, errors.New("frame shorter than its header")
+	}
+	return payload[:f.HeaderSize], payload[f.HeaderSize:], nil
+}
+
+// appendFrame appends the encoded message in its frame to dst
+func appendFrame(dst []byte, f Framer, header []byte, m *Message) ([]byte, error) {
+	size, err := f.FrameSize(header)
+	if err != nil {
+		return nil, err
+	}
+	start := len(dst)
+	dst = append(dst, make([]byte, size)...)
+	dst, err = m.AppendEncode(dst)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.PutFrame(dst[start:start+size], header, len(dst)-start-size); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// EncodeFrame encodes the message in the frame of f with the header
+func EncodeFrame(f Framer, header []byte, m *Message) ([]byte, error) {
+	return appendFrame(make([]byte, 0, 512), f, header, m)
+}
+
+// DecodeFrame decodes the message in the frame of f from raw into m,
+// and returns the header of the frame
+func DecodeFrame(f Framer, raw []byte, m *Message) ([]byte, error) {
+	r := bytes.NewReader(raw)
+	// the message cannot be longer than raw, which is in memory already
+	header, msg, err := f.ReadFrame(r, len(raw))
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() > 0 {
+		return nil, errors.New("data after the framed message")
+	}
+	return header, m.Decode(msg)
+}
+
+// TPDUSize is the size of a TPDU
+const TPDUSize = 5
+
+// TPDU is the transport protocol data unit before the MTI on some links:
+// the ID (usually 0x60), the destination and the source network
+// international identifiers (NII)
+type TPDU [TPDUSize]byte
+
+// NewTPDU returns a TPDU of the id and NIIs, e.g. NewTPDU(0x60, 0x0001, 0x0000)
+func NewTPDU(id byte, destination, source uint16) TPDU {
+	t := TPDU{id}
+	binary.BigEndian.PutUint16(t[1:3], destination)
+	binary.BigEndian.PutUint16(t[3:5], source)
+	return t
+}
+
+// ParseTPDU returns the TPDU of a frame header
+func ParseTPDU(header []byte) (TPDU, error) {
+	var t TPDU
+	if len(header) < TPDUSize {
+		return t, errors.New("invalid TPDU length: " + strconv.Itoa(len(header)))
+	}
+	copy(t[:], header)
+	return t, nil
+}
+
+func (t TPDU) ID() byte {
+	return t[0]
+}
+
+func (t TPDU) Destination() uint16 {
+	return binary.BigEndian.Uint16(t[1:3])
+}
+
+func (t TPDU) Source() uint16 {
+	return binary.BigEndian.Uint16(t[3:5])
+}
+
+// Swap returns the TPDU of the response, with the destination and
+// the source swapped
+func (t TPDU) Swap() TPDU {
+	return TPDU{t[0], t[3], t[4], t[1], t[2]}
+}
+
+// Bytes returns the TPDU as frame header
+func (t TPDU) Bytes() []byte {
+	return t[:]
+}
@@ -0,0 +1,114 @@
+package iso8583
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func framerTestMessage(mti string) *Message {
+	m := &Message{DE3: NewNumeric("000000"), DE11: NewNumeric("000001")}
+	m.Mti = mti
+	return m
+}
+
+func TestFramers(t *testing.T) {
+	var scenarios = []struct {
+		name   string
+		framer Framer
+		header []byte
+		prefix string
+	}{
+		{name: "binary2", framer: Binary2Framer, prefix: "\x00\x20"},
+		{name: "ascii4", framer: ASCII4Framer, prefix: "0032"},
+		{name: "tpdu", framer: TPDUFramer, header: NewTPDU(0x60, 0x0001, 0x0000).Bytes(), prefix: "\x00\x25\x60\x00\x01\x00\x00"},
+		{name: "proprietary", framer: HeaderFramer{Length: LengthHeader{Size: 4, Encoding: LengthASCII}, HeaderSize: 3}, header: []byte("ISO"), prefix: "0035ISO"},
+	}
+	for _, scenario := range scenarios {
+		raw, err := EncodeFrame(scenario.framer, scenario.header, framerTestMessage("0800"))
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.name, err)
+		}
+		if string(raw) != scenario.prefix+"08002020000000000000000000000001" {
+			t.Errorf("%s: unexpected frame %q", scenario.name, raw)
+		}
+
+		m := &Message{}
+		header, err := DecodeFrame(scenario.framer, raw, m)
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.name, err)
+		}
+		if !bytes.Equal(header, scenario.header) || m.DE11.String() != "000001" {
+			t.Errorf("%s: unexpected header %q or message %v", scenario.name, header, m)
+		}
+	}
+}
+
+func TestFramerErrors(t *testing.T) {
+	if _, err := EncodeFrame(TPDUFramer, []byte{0x60}, framerTestMessage("0800")); err == nil {
+		t.Error("short TPDU should fail")
+	}
+	if _, err := EncodeFrame(Binary2Framer, []byte{0x60}, framerTestMessage("0800")); err == nil {
+		t.Error("header of length framer should fail")
+	}
+	if _, err := DecodeFrame(TPDUFramer, []byte("\x00\x03\x60\x00\x01"), &Message{}); err == nil {
+		t.Error("frame shorter than TPDU should fail")
+	}
+	raw, _ := EncodeFrame(Binary2Framer, nil, framerTestMessage("0800"))
+	if _, err := DecodeFrame(Binary2Framer, append(raw, 'X'), &Message{}); err == nil {
+		t.Error("data after the frame should fail")
+	}
+}
+
+func TestFramerMaxLength(t *testing.T) {
+	// a length prefix of 2 GB from the peer fails before the message is allocated
+	huge := LengthHeader{Size: 4, Encoding: LengthBinary}
+	for _, f := range []Framer{huge, HeaderFramer{Length: huge, HeaderSize: TPDUSize}} {
+		_, _, err := f.ReadFrame(bytes.NewReader([]byte("\x7f\xff\xff\xff")), 0)
+		if err == nil || err.Error() != "message too long: 2147483647" {
+			t.Errorf("%T: oversized length should fail, got %v", f, err)
+		}
+	}
+	prefix := []byte{0, 0, 0x04, 0x01}
+	if _, _, err := huge.ReadFrame(bytes.NewReader(prefix), 1024); err == nil {
+		t.Error("length over max should fail")
+	}
+	if _, _, err := huge.ReadFrame(bytes.NewReader(prefix), 2048); err != io.ErrUnexpectedEOF {
+		t.Errorf("length under max should be read, got %v", err)
+	}
+}
+
+func TestTPDUSwap(t *testing.T) {
+	var stream bytes.Buffer
+	request := NewTPDU(0x60, 0x0012, 0x0034)
+	enc := NewEncoder(&stream)
+	enc.SetFramer(TPDUFramer, request.Bytes())
+	if err := enc.Encode(framerTestMessage("0800")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the host answers with the swapped TPDU
+	dec := NewDecoder(&stream)
+	dec.SetFramer(TPDUFramer)
+	header, err := dec.DecodeHeader(&Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpdu, err := ParseTPDU(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpdu.ID() != 0x60 || tpdu.Destination() != 0x0012 || tpdu.Source() != 0x0034 {
+		t.Errorf("unexpected TPDU %x", tpdu)
+	}
+	if err := enc.EncodeHeader(framerTestMessage("0810"), tpdu.Swap().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	header, err = dec.DecodeHeader(&Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(header, NewTPDU(0x60, 0x0034, 0x0012).Bytes()) {
+		t.Errorf("response TPDU should be swapped, got %x", header)
+	}
+}
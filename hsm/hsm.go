@@ -0,0 +1,173 @@
+// Package hsm defines KeyProvider, the PIN, MAC and ARQC operations of a
+// hardware security module, with keys referenced by name and never held by
+// the caller, and the helpers applying them to messages. Software is a
+// KeyProvider with keys in memory, for tests and local development.
+package hsm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/pin"
+)
+
+// ErrInvalidARQC is returned by VerifyARQC if the cryptogram is not the
+// ARQC of the card
+var ErrInvalidARQC = errors.New("invalid ARQC")
+
+// KeyProvider performs the cryptographic operations with the keys it holds,
+// e.g. an HSM on the network. It must be safe for concurrent use.
+type KeyProvider interface {
+	// EncryptPIN returns the PIN block of the format, enciphered with the
+	// PIN key
+	EncryptPIN(ctx context.Context, key string, format pin.Format, pinValue, pan string) ([]byte, error)
+	// TranslatePIN deciphers a PIN block and enciphers it with another key
+	TranslatePIN(ctx context.Context, t PINTranslation) ([]byte, error)
+	// GenerateMAC returns the MAC of the data with the MAC key
+	GenerateMAC(ctx context.Context, key string, data []byte) ([]byte, error)
+	// VerifyMAC returns iso8583.ErrInvalidMAC if mac is not the MAC of the
+	// data with the MAC key
+	VerifyMAC(ctx context.Context, key string, data, mac []byte) error
+	// VerifyARQC returns ErrInvalidARQC if the cryptogram is not the ARQC
+	// of the card, with the issuer master key of application cryptograms
+	VerifyARQC(ctx context.Context, key string, arqc ARQC) error
+}
+
+// PINTranslation is a PIN block to translate from a key to another
+type PINTranslation struct {
+	// From is the PIN key of the PIN block, or the BDK of DUKPT
+	From string
+	// KSN is the key serial number of the PIN block, with a BDK
+	KSN []byte
+	// To is the PIN key of the translated PIN block
+	To string
+	// Block is the enciphered PIN block
+	Block []byte
+	// PAN is the card number of the PIN block
+	PAN string
+	// Format is the format of the translated PIN block
+	Format pin.Format
+}
+
+// ARQCMethod is the key and the padding of an ARQC
+type ARQCMethod int
+
+const (
+	// ARQCCardKey is the MAC with the card master key and the zero padding,
+	// e.g. Visa CVN 10
+	ARQCCardKey ARQCMethod = iota
+	// ARQCSessionKey is the MAC with the EMV common session key of the ATC
+	// and the padding 80, e.g. Visa CVN 18
+	ARQCSessionKey
+)
+
+// ARQC is the authorization request cryptogram of an EMV card, with the
+// data it authenticates
+type ARQC struct {
+	// Method is the key and the padding of the cryptogram
+	Method ARQCMethod
+	// PAN is the card number, the card master key is derived from it with
+	// the EMV option A
+	PAN string
+	// PANSequence is the PAN sequence number, e.g. DE23 or tag 5F34,
+	// empty means 00
+	PANSequence string
+	// ATC is the application transaction counter of 2 bytes, tag 9F36
+	ATC []byte
+	// Data is the data of the cryptogram, e.g. the values of the tags 9F02,
+	// 9F03, 9F1A, 95, 5F2A, 9A, 9C, 9F37, 82 and 9F36 and the CVR
+	Data []byte
+	// Cryptogram is the ARQC of 8 bytes, tag 9F26
+	Cryptogram []byte
+}
+
+// MAC returns the MAC key of the provider, to sign and verify messages with
+// Message.Sign and Message.Verify. The verification is done by the provider.
+func MAC(ctx context.Context, p KeyProvider, key string) iso8583.MACKey {
+	return &macKey{ctx: ctx, p: p, key: key}
+}
+
+type macKey struct {
+	ctx context.Context
+	p   KeyProvider
+	key string
+}
+
+func (k *macKey) MAC(data []byte) ([]byte, error) {
+	return k.p.GenerateMAC(k.ctx, k.key, data)
+}
+
+func (k *macKey) VerifyMAC(data, mac []byte) error {
+	return k.p.VerifyMAC(k.ctx, k.key, data, mac)
+}
+
+// messagePAN returns the card number of DE2
+func messagePAN(m *iso8583.Message) (string, error) {
+	if m.DE2 == nil || len(m.DE2.Value) == 0 {
+		return "", errors.New("no PAN in DE2")
+	}
+	return string(m.DE2.Value), nil
+}
+
+// checkDE52 returns an error if the PIN blocks of the format do not fit
+// DE52, of 8 bytes
+func checkDE52(format pin.Format) error {
+	if format == pin.Format4 {
+		return errors.New("PIN block of format 4 (AES) does not fit DE52 of 8 bytes")
+	}
+	return nil
+}
+
+// SetPIN sets DE52 to the PIN block of the format, enciphered with the PIN
+// key, with the PAN of DE2. The format 4 of AES does not fit DE52.
+func SetPIN(ctx context.Context, p KeyProvider, m *iso8583.Message, key string, format pin.Format, pinValue string) error {
+	if err := checkDE52(format); err != nil {
+		return err
+	}
+	pan, err := messagePAN(m)
+	if err != nil {
+		return err
+	}
+	block, err := p.EncryptPIN(ctx, key, format, pinValue, pan)
+	if err != nil {
+		return err
+	}
+	field, err := pin.Field(block)
+	if err != nil {
+		return err
+	}
+	m.DE52 = field
+	return nil
+}
+
+// TranslatePIN translates the PIN block of DE52 from a key to another, in
+// the format, with the PAN of DE2. The key from is a BDK if DE53 has a KSN.
+// DE53 is left as it is. The format 4 of AES does not fit DE52.
+func TranslatePIN(ctx context.Context, p KeyProvider, m *iso8583.Message, from, to string, format pin.Format) error {
+	if err := checkDE52(format); err != nil {
+		return err
+	}
+	pan, err := messagePAN(m)
+	if err != nil {
+		return err
+	}
+	block, err := pin.FromField(m.DE52)
+	if err != nil {
+		return err
+	}
+	t := PINTranslation{From: from, To: to, Block: block, PAN: pan, Format: format}
+	if m.DE53 != nil {
+		t.KSN = m.DE53.KSN
+	}
+	translated, err := p.TranslatePIN(ctx, t)
+	if err != nil {
+		return err
+	}
+	field, err := pin.Field(translated)
+	if err != nil {
+		return err
+	}
+	m.DE52 = field
+	return nil
+}
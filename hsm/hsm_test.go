@@ -0,0 +1,95 @@
+package hsm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/pin"
+)
+
+func TestSetPIN(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	m := &iso8583.Message{Mti: "0200", DE2: iso8583.NewNumeric("4012345678909")}
+	if err := SetPIN(ctx, s, m, "ZPK1", pin.Format0, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pin.FromField(m.DE52)
+	c, _ := pin.TDES(s.Keys["ZPK1"].Value)
+	if p, _, err := pin.Decrypt(c, block, "4012345678909"); err != nil || p != "1234" {
+		t.Errorf("DE52 should be the PIN 1234, got %s %v", p, err)
+	}
+
+	if err := SetPIN(ctx, s, m, "AZPK", pin.Format4, "1234"); err == nil {
+		t.Error("PIN block of format 4 should not fit DE52")
+	}
+	if err := SetPIN(ctx, s, &iso8583.Message{Mti: "0200"}, "ZPK1", pin.Format0, "1234"); err == nil {
+		t.Error("message without PAN should fail")
+	}
+}
+
+func TestTranslatePIN(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	// the PIN block of a DUKPT terminal, with the KSN in DE53
+	sc, err := iso8583.ParseSecurityControl("20010100FFFF9876543210E00001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &iso8583.Message{
+		Mti:  "0200",
+		DE2:  iso8583.NewNumeric("4012345678909"),
+		DE52: iso8583.NewBinary64Hex("1B9C1845EB993A7A"),
+		DE53: sc,
+	}
+	if err := TranslatePIN(ctx, s, m, "BDK", "ZPK2", pin.Format0); err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pin.FromField(m.DE52)
+	c, _ := pin.TDES(s.Keys["ZPK2"].Value)
+	if p, _, err := pin.Decrypt(c, block, "4012345678909"); err != nil || p != "1234" {
+		t.Errorf("DE52 should be the PIN 1234 under ZPK2, got %s %v", p, err)
+	}
+
+	// without KSN, from a zone PIN key to another
+	m.DE53 = nil
+	if err := TranslatePIN(ctx, s, m, "ZPK2", "ZPK1", pin.Format0); err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pin.FromField(m.DE52)
+	c, _ = pin.TDES(s.Keys["ZPK1"].Value)
+	if p, _, err := pin.Decrypt(c, block, "4012345678909"); err != nil || p != "1234" {
+		t.Errorf("DE52 should be the PIN 1234 under ZPK1, got %s %v", p, err)
+	}
+
+	// format 4 is rejected before the translation
+	de52 := m.DE52
+	if err := TranslatePIN(ctx, s, m, "ZPK1", "AZPK", pin.Format4); err == nil || m.DE52 != de52 {
+		t.Error("PIN block of format 4 should not fit DE52")
+	}
+
+	m.DE52 = nil
+	if err := TranslatePIN(ctx, s, m, "ZPK1", "ZPK2", pin.Format0); err == nil {
+		t.Error("message without PIN block should fail")
+	}
+}
+
+func TestMAC(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	m := &iso8583.Message{Mti: "0200", DE3: iso8583.NewNumeric("000000"), DE11: iso8583.NewNumeric("000001")}
+	if err := m.Sign(MAC(ctx, s, "TAK")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(MAC(ctx, s, "TAK")); err != nil {
+		t.Errorf("MAC should be verified, got %v", err)
+	}
+	m.DE11 = iso8583.NewNumeric("000002")
+	if err := m.Verify(MAC(ctx, s, "TAK")); err != iso8583.ErrInvalidMAC {
+		t.Errorf("MAC of a changed message should be ErrInvalidMAC, got %v", err)
+	}
+	if err := m.Sign(MAC(ctx, s, "ZPK1")); err == nil {
+		t.Error("message should not be signed with a PIN key")
+	}
+}
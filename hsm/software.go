@@ -0,0 +1,262 @@
+package hsm
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/dukpt"
+	"github.com/fluidpay/iso8583/mac"
+	"github.com/fluidpay/iso8583/pin"
+)
+
+// KeyUsage is the usage of a key of Software, a key is used for its usage only
+type KeyUsage int
+
+const (
+	// PINKey enciphers PIN blocks, e.g. a zone PIN key
+	PINKey KeyUsage = iota + 1
+	// MACKey generates and verifies MACs
+	MACKey
+	// BDK is the base derivation key of DUKPT, it deciphers PIN blocks
+	BDK
+	// ARQCKey is the issuer master key of the application cryptograms
+	ARQCKey
+)
+
+// Key is a key of Software
+type Key struct {
+	// Usage is the usage of the key
+	Usage KeyUsage
+	// Value is the key in the clear, TDES of 16 or 24 bytes, or AES
+	Value []byte
+	// AES is true for the AES PIN keys, of PIN blocks of format 4, and the
+	// BDKs of AES DUKPT, the other keys are TDES keys
+	AES bool
+	// MAC is the algorithm of a MAC key, with MACPadding
+	MAC        mac.Algorithm
+	MACPadding mac.Padding
+	// MACLength is the length of the MACs verified with a MAC key, the
+	// leftmost bytes of the MAC, 0 means 8
+	MACLength int
+	// DUKPTKeyType is the type of the PIN keys derived from an AES BDK,
+	// AES128, AES192, AES256 or TDES3, 0 means AES128
+	DUKPTKeyType dukpt.KeyType
+}
+
+// Software is a KeyProvider with keys in memory, in the clear, for tests,
+// simulators and local development
+type Software struct {
+	// Keys are the keys by name, they must not be changed while in use
+	Keys map[string]Key
+}
+
+func (s *Software) key(name string, usage KeyUsage) (Key, error) {
+	k, ok := s.Keys[name]
+	if !ok {
+		return Key{}, errors.New("unknown key: " + name)
+	}
+	if k.Usage != usage {
+		return Key{}, errors.New("invalid usage of key " + name)
+	}
+	return k, nil
+}
+
+func (k Key) pinCipher() (cipher.Block, error) {
+	if k.AES {
+		return pin.AES(k.Value)
+	}
+	return pin.TDES(k.Value)
+}
+
+// dukptCipher returns the cipher of the DUKPT PIN key of the KSN
+func (k Key) dukptCipher(ksn []byte) (cipher.Block, error) {
+	if k.AES {
+		keyType := k.DUKPTKeyType
+		if keyType == dukpt.TDES2 {
+			keyType = dukpt.AES128
+		}
+		key, err := dukpt.AESKeyFromBDK(k.Value, ksn, dukpt.PINEncryption, keyType)
+		if err != nil {
+			return nil, err
+		}
+		if keyType == dukpt.TDES3 {
+			return pin.TDES(key)
+		}
+		return pin.AES(key)
+	}
+	key, err := dukpt.TDESKeyFromBDK(k.Value, ksn, dukpt.PINVariant)
+	if err != nil {
+		return nil, err
+	}
+	return pin.TDES(key)
+}
+
+func (s *Software) EncryptPIN(ctx context.Context, key string, format pin.Format, pinValue, pan string) ([]byte, error) {
+	k, err := s.key(key, PINKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := k.pinCipher()
+	if err != nil {
+		return nil, err
+	}
+	return pin.Encrypt(c, format, pinValue, pan)
+}
+
+func (s *Software) TranslatePIN(ctx context.Context, t PINTranslation) ([]byte, error) {
+	var from cipher.Block
+	if len(t.KSN) > 0 {
+		k, err := s.key(t.From, BDK)
+		if err != nil {
+			return nil, err
+		}
+		if from, err = k.dukptCipher(t.KSN); err != nil {
+			return nil, err
+		}
+	} else {
+		k, err := s.key(t.From, PINKey)
+		if err != nil {
+			return nil, err
+		}
+		if from, err = k.pinCipher(); err != nil {
+			return nil, err
+		}
+	}
+	k, err := s.key(t.To, PINKey)
+	if err != nil {
+		return nil, err
+	}
+	to, err := k.pinCipher()
+	if err != nil {
+		return nil, err
+	}
+	return pin.Translate(t.Block, t.PAN, from, to, t.Format)
+}
+
+func (s *Software) GenerateMAC(ctx context.Context, key string, data []byte) ([]byte, error) {
+	k, err := s.key(key, MACKey)
+	if err != nil {
+		return nil, err
+	}
+	return (&mac.Key{Algorithm: k.MAC, Key: k.Value, Padding: k.MACPadding}).MAC(data)
+}
+
+// VerifyMAC compares mac with the leftmost MACLength bytes of the MAC of
+// the data
+func (s *Software) VerifyMAC(ctx context.Context, key string, data, mac []byte) error {
+	expected, err := s.GenerateMAC(ctx, key, data)
+	if err != nil {
+		return err
+	}
+	length := s.Keys[key].MACLength
+	if length <= 0 {
+		length = 8
+	}
+	if length > len(expected) {
+		return errors.New("MAC length of key " + key + " over the MAC of " + strconv.Itoa(len(expected)) + " bytes")
+	}
+	if len(mac) != length || subtle.ConstantTimeCompare(mac, expected[:length]) != 1 {
+		return iso8583.ErrInvalidMAC
+	}
+	return nil
+}
+
+func (s *Software) VerifyARQC(ctx context.Context, key string, arqc ARQC) error {
+	expected, err := s.GenerateARQC(key, arqc)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(arqc.Cryptogram, expected) != 1 {
+		return ErrInvalidARQC
+	}
+	return nil
+}
+
+// GenerateARQC returns the ARQC of the card, e.g. for a card simulator,
+// the Cryptogram of arqc is ignored
+func (s *Software) GenerateARQC(key string, arqc ARQC) ([]byte, error) {
+	k, err := s.key(key, ARQCKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(arqc.ATC) != 2 {
+		return nil, errors.New("invalid ATC length")
+	}
+	cardKey, err := cardMasterKey(k.Value, arqc.PAN, arqc.PANSequence)
+	if err != nil {
+		return nil, err
+	}
+	m := &mac.Key{Algorithm: mac.ISO9797Alg3, Key: cardKey, Padding: mac.Padding1}
+	switch arqc.Method {
+	case ARQCCardKey:
+	case ARQCSessionKey:
+		// the halves of the common session key are derived from the ATC
+		data := make([]byte, 16)
+		copy(data, arqc.ATC)
+		copy(data[8:], arqc.ATC)
+		data[2], data[10] = 0xF0, 0x0F
+		if m.Key, err = tdesEncrypt(cardKey, data); err != nil {
+			return nil, err
+		}
+		m.Padding = mac.Padding2
+	default:
+		return nil, errors.New("invalid ARQC method")
+	}
+	return m.MAC(arqc.Data)
+}
+
+// cardMasterKey derives the card master key of the application cryptograms
+// from the issuer master key, with the EMV option A
+func cardMasterKey(imk []byte, pan, psn string) ([]byte, error) {
+	if psn == "" {
+		psn = "00"
+	}
+	digits := pan + psn
+	if len(digits) < 16 {
+		digits = strings.Repeat("0", 16-len(digits)) + digits
+	}
+	y, err := decodeDigits(digits[len(digits)-16:])
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 16)
+	copy(data, y)
+	for i := range y {
+		data[8+i] = y[i] ^ 0xFF
+	}
+	return tdesEncrypt(imk, data)
+}
+
+// decodeDigits packs 16 digits into 8 bytes
+func decodeDigits(digits string) ([]byte, error) {
+	b := make([]byte, len(digits)/2)
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, errors.New("invalid PAN or PAN sequence number")
+		}
+		b[i/2] |= (digits[i] - '0') << (4 * uint(1-i%2))
+	}
+	return b, nil
+}
+
+// tdesEncrypt enciphers the blocks with a TDES key of 16 or 24 bytes
+func tdesEncrypt(key, data []byte) ([]byte, error) {
+	if len(key) == 16 {
+		key = append(append([]byte(nil), key...), key[:8]...)
+	}
+	c, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(data))
+	for i := 0; i < len(data); i += des.BlockSize {
+		c.Encrypt(res[i:i+des.BlockSize], data[i:i+des.BlockSize])
+	}
+	return res, nil
+}
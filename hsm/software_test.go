@@ -0,0 +1,226 @@
+package hsm
+
+import (
+	"bytes"
+	"context"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/dukpt"
+	"github.com/fluidpay/iso8583/mac"
+	"github.com/fluidpay/iso8583/pin"
+)
+
+var _ KeyProvider = (*Software)(nil)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testSoftware(t *testing.T) *Software {
+	return &Software{Keys: map[string]Key{
+		"ZPK1":  {Usage: PINKey, Value: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")},
+		"ZPK2":  {Usage: PINKey, Value: mustHex(t, "89ABCDEF0123456776543210FEDCBA98")},
+		"AZPK":  {Usage: PINKey, Value: mustHex(t, "C1D0F8FB4958670DBA40AB1F3752EF0D"), AES: true},
+		"BDK":   {Usage: BDK, Value: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")},
+		"TAK":   {Usage: MACKey, Value: mustHex(t, "0123456789ABCDEFFEDCBA9876543210"), MAC: mac.X919},
+		"TAK4":  {Usage: MACKey, Value: mustHex(t, "0123456789ABCDEFFEDCBA9876543210"), MAC: mac.X919, MACLength: 4},
+		"CMAC":  {Usage: MACKey, Value: mustHex(t, "2B7E151628AED2A6ABF7158809CF4F3C"), MAC: mac.AESCMAC},
+		"IMKAC": {Usage: ARQCKey, Value: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")},
+	}}
+}
+
+func TestSoftwarePIN(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	pan := "4012345678909"
+	block, err := s.EncryptPIN(ctx, "ZPK1", pin.Format0, "1234", pan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated, err := s.TranslatePIN(ctx, PINTranslation{From: "ZPK1", To: "AZPK", Block: block, PAN: pan, Format: pin.Format4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := pin.AES(s.Keys["AZPK"].Value)
+	if p, f, err := pin.Decrypt(c, translated, pan); err != nil || p != "1234" || f != pin.Format4 {
+		t.Errorf("translated PIN block should be 1234 in format 4, got %s %d %v", p, f, err)
+	}
+
+	// the PIN block of the X9.24-1 test vector, enciphered with the DUKPT key
+	translated, err = s.TranslatePIN(ctx, PINTranslation{
+		From: "BDK", KSN: mustHex(t, "FFFF9876543210E00001"), To: "ZPK2",
+		Block: mustHex(t, "1B9C1845EB993A7A"), PAN: pan, Format: pin.Format3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ = pin.TDES(s.Keys["ZPK2"].Value)
+	if p, f, err := pin.Decrypt(c, translated, pan); err != nil || p != "1234" || f != pin.Format3 {
+		t.Errorf("translated DUKPT PIN block should be 1234 in format 3, got %s %d %v", p, f, err)
+	}
+
+	if _, err := s.EncryptPIN(ctx, "TAK", pin.Format0, "1234", pan); err == nil {
+		t.Error("PIN block should not be enciphered with a MAC key")
+	}
+	if _, err := s.EncryptPIN(ctx, "ZPK3", pin.Format0, "1234", pan); err == nil {
+		t.Error("unknown key should fail")
+	}
+	if _, err := s.TranslatePIN(ctx, PINTranslation{From: "BDK", To: "ZPK2", Block: block, PAN: pan}); err == nil {
+		t.Error("BDK without KSN should fail")
+	}
+}
+
+func TestSoftwareMAC(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	data := []byte("Now is the time for all ")
+	m, err := s.GenerateMAC(ctx, "TAK", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m, mustHex(t, "A1C72E74EA3FA9B6")) {
+		t.Errorf("MAC should be A1C72E74EA3FA9B6, got %X", m)
+	}
+	if err := s.VerifyMAC(ctx, "TAK", data, m); err != nil {
+		t.Errorf("MAC should be verified, got %v", err)
+	}
+	if err := s.VerifyMAC(ctx, "TAK", data[1:], m); err != iso8583.ErrInvalidMAC {
+		t.Errorf("MAC of other data should be ErrInvalidMAC, got %v", err)
+	}
+	// the MACs have the length of the key, a truncated MAC is not verified
+	for _, n := range []int{0, 2, 4} {
+		if err := s.VerifyMAC(ctx, "TAK", data, m[:n]); err != iso8583.ErrInvalidMAC {
+			t.Errorf("MAC of %d bytes should be ErrInvalidMAC, got %v", n, err)
+		}
+	}
+	if err := s.VerifyMAC(ctx, "TAK4", data, m[:4]); err != nil {
+		t.Errorf("MAC of 4 bytes should be verified with a key of MACLength 4, got %v", err)
+	}
+	if err := s.VerifyMAC(ctx, "TAK4", data, m); err != iso8583.ErrInvalidMAC {
+		t.Errorf("MAC of 8 bytes should be ErrInvalidMAC with a key of MACLength 4, got %v", err)
+	}
+
+	// the MAC of 8 bytes of a message is the left half of the AES-CMAC
+	cmac, _ := s.GenerateMAC(ctx, "CMAC", data)
+	if err := s.VerifyMAC(ctx, "CMAC", data, cmac[:8]); err != nil || len(cmac) != 16 {
+		t.Errorf("AES-CMAC should be verified, got %X %v", cmac, err)
+	}
+	if err := s.VerifyMAC(ctx, "CMAC", data, cmac); err != iso8583.ErrInvalidMAC {
+		t.Errorf("AES-CMAC of 16 bytes should be ErrInvalidMAC, got %v", err)
+	}
+	if _, err := s.GenerateMAC(ctx, "ZPK1", data); err == nil {
+		t.Error("MAC should not be generated with a PIN key")
+	}
+}
+
+func TestSoftwareDUKPTKeyType(t *testing.T) {
+	ctx := context.Background()
+	bdk := mustHex(t, "FEDCBA9876543210F1F1F1F1F1F1F1F10123456789ABCDEF0F0F0F0F0F0F0F0F")
+	ksn := mustHex(t, "123456789012345600000001")
+	pan := "4012345678909"
+	s := testSoftware(t)
+	s.Keys["ABDK"] = Key{Usage: BDK, Value: bdk, AES: true, DUKPTKeyType: dukpt.AES256}
+	s.Keys["ABDK128"] = Key{Usage: BDK, Value: bdk, AES: true}
+
+	// the PIN block of an AES DUKPT terminal with PIN keys of 256 bits
+	key, err := dukpt.AESKeyFromBDK(bdk, ksn, dukpt.PINEncryption, dukpt.AES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := pin.AES(key)
+	block, err := pin.Encrypt(c, pin.Format4, "1234", pan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated, err := s.TranslatePIN(ctx, PINTranslation{From: "ABDK", KSN: ksn, To: "AZPK", Block: block, PAN: pan, Format: pin.Format4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ = pin.AES(s.Keys["AZPK"].Value)
+	if p, _, err := pin.Decrypt(c, translated, pan); err != nil || p != "1234" {
+		t.Errorf("translated PIN block should be 1234, got %s %v", p, err)
+	}
+	// the PIN keys of 128 bits by default do not decipher it
+	if _, err := s.TranslatePIN(ctx, PINTranslation{From: "ABDK128", KSN: ksn, To: "AZPK", Block: block, PAN: pan, Format: pin.Format4}); err == nil {
+		t.Error("PIN block should not be deciphered with a PIN key of 128 bits")
+	}
+}
+
+func TestCardMasterKey(t *testing.T) {
+	imk := mustHex(t, "0123456789ABCDEFFEDCBA9876543210")
+	c, _ := des.NewTripleDESCipher(append(append([]byte(nil), imk...), imk[:8]...))
+	for _, test := range []struct {
+		pan, psn, y string
+	}{
+		{"4761739001010010", "01", "6173900101001001"},
+		{"541333008902", "", "0054133300890200"},
+		{"6011000990139424123", "00", "0099013942412300"},
+	} {
+		key, err := cardMasterKey(imk, test.pan, test.psn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y := mustHex(t, test.y)
+		left := make([]byte, 8)
+		c.Encrypt(left, y)
+		for i := range y {
+			y[i] ^= 0xFF
+		}
+		right := make([]byte, 8)
+		c.Encrypt(right, y)
+		if !bytes.Equal(key, append(left, right...)) {
+			t.Errorf("card master key of %s %s should be derived from %s", test.pan, test.psn, test.y)
+		}
+	}
+	if _, err := cardMasterKey(imk, "4761A39001010010", "01"); err == nil {
+		t.Error("invalid PAN should fail")
+	}
+}
+
+func TestSoftwareARQC(t *testing.T) {
+	s := testSoftware(t)
+	ctx := context.Background()
+	arqc := ARQC{
+		PAN:         "4761739001010010",
+		PANSequence: "01",
+		ATC:         []byte{0x00, 0x2A},
+		Data:        mustHex(t, "000000001000000000000000084000000000000840230101009901020304580000002A03A0A000"),
+	}
+	for _, method := range []ARQCMethod{ARQCCardKey, ARQCSessionKey} {
+		arqc.Method = method
+		cryptogram, err := s.GenerateARQC("IMKAC", arqc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arqc.Cryptogram = cryptogram
+		if err := s.VerifyARQC(ctx, "IMKAC", arqc); err != nil {
+			t.Errorf("ARQC of method %d should be verified, got %v", method, err)
+		}
+
+		other := arqc
+		other.ATC = []byte{0x00, 0x2B}
+		if method == ARQCSessionKey {
+			// the ATC of the session key
+			if err := s.VerifyARQC(ctx, "IMKAC", other); err != ErrInvalidARQC {
+				t.Errorf("ARQC of another ATC should be ErrInvalidARQC, got %v", err)
+			}
+		}
+		other = arqc
+		other.PANSequence = "02"
+		if err := s.VerifyARQC(ctx, "IMKAC", other); err != ErrInvalidARQC {
+			t.Errorf("ARQC of another card should be ErrInvalidARQC, got %v", err)
+		}
+	}
+
+	arqc.ATC = []byte{0x2A}
+	if err := s.VerifyARQC(ctx, "IMKAC", arqc); err == nil || err == ErrInvalidARQC {
+		t.Errorf("ATC of 1 byte should fail, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package iso8583
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidMAC is returned by Verify if the MAC field is not the MAC of
+// the message
+var ErrInvalidMAC = errors.New("invalid MAC")
+
+// MACKey computes the message authentication codes of the messages, e.g.
+// a key of the mac package
+type MACKey interface {
+	// MAC returns the MAC of the data, its 8 leftmost bytes are used
+	MAC(data []byte) ([]byte, error)
+}
+
+// MACVerifier is implemented by the MAC keys verifying the MACs themselves,
+// e.g. the keys of an HSM, Verify uses it instead of comparing the MAC
+type MACVerifier interface {
+	// VerifyMAC returns ErrInvalidMAC if mac is not the MAC of the data
+	VerifyMAC(data, mac []byte) error
+}
+
+// macLength is the number of hex digits of the MAC fields, 8 bytes
+const macLength = 16
+
+// macField returns the number of the MAC field of m, DE128 if m has a
+// secondary bitmap, DE64 otherwise, the MAC is always the last field
+func (m *Message) macField() int {
+	s := structOf(m)
+	specs := s.ISO8583Fields()
+	for i := range specs {
+		if specs[i].Index > 64 && specs[i].Index != 128 && s.ISO8583Field(i) != nil {
+			return 128
+		}
+	}
+	return 64
+}
+
+// macData returns the encoded message without the MAC field, the MAC field
+// is set and it must be the last field
+func (m *Message) macData() ([]byte, error) {
+	b, err := m.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return b[:len(b)-macLength], nil
+}
+
+// Sign sets the MAC of the message with the key, in DE64 or in DE128 with
+// a secondary bitmap. The MAC is computed over the encoded message without
+// the MAC field, the bitmap includes the bit of the MAC field.
+func (m *Message) Sign(key MACKey) error {
+	de64, de128 := m.DE64, m.DE128
+	placeholder := NewBinary64Hex(strings.Repeat("0", macLength))
+	if m.macField() == 128 {
+		m.DE64, m.DE128 = nil, placeholder
+	} else {
+		m.DE64, m.DE128 = placeholder, nil
+	}
+	data, err := m.macData()
+	var mac []byte
+	if err == nil {
+		mac, err = key.MAC(data)
+	}
+	if err == nil && len(mac) < macLength/2 {
+		err = errors.New("MAC shorter than 8 bytes")
+	}
+	if err != nil {
+		m.DE64, m.DE128 = de64, de128
+		return err
+	}
+	placeholder.Value = []byte(strings.ToUpper(hex.EncodeToString(mac[:macLength/2])))
+	return nil
+}
+
+// Verify checks the MAC of the message with the key, it returns ErrInvalidMAC
+// if the MAC field does not match
+func (m *Message) Verify(key MACKey) error {
+	field := m.DE128
+	if field == nil {
+		field = m.DE64
+		if field == nil {
+			return errors.New("no MAC field")
+		}
+		if m.macField() == 128 {
+			return errors.New("DE64 is not the last field")
+		}
+	}
+	data, err := m.macData()
+	if err != nil {
+		return err
+	}
+	if v, ok := key.(MACVerifier); ok {
+		mac, err := hex.DecodeString(string(field.Value))
+		if err != nil {
+			return ErrInvalidMAC
+		}
+		return v.VerifyMAC(data, mac)
+	}
+	mac, err := key.MAC(data)
+	if err != nil {
+		return err
+	}
+	if len(mac) < macLength/2 {
+		return errors.New("MAC shorter than 8 bytes")
+	}
+	expected := strings.ToUpper(hex.EncodeToString(mac[:macLength/2]))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToUpper(string(field.Value)))) != 1 {
+		return ErrInvalidMAC
+	}
+	return nil
+}
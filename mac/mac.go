@@ -0,0 +1,230 @@
+// Package mac computes the message authentication codes of ANSI X9.9,
+// ANSI X9.19, ISO 9797-1 algorithms 1 and 3 and AES-CMAC, with keys in the
+// clear. A Key signs and verifies messages, e.g. m.Sign(&mac.Key{...}).
+package mac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"errors"
+	"strconv"
+)
+
+// Algorithm is a MAC algorithm
+type Algorithm int
+
+const (
+	// X99 is the DES CBC-MAC of ANSI X9.9, with a key of 8 bytes
+	X99 Algorithm = iota
+	// X919 is the retail MAC of ANSI X9.19, the DES CBC-MAC with the last
+	// block enciphered with TDES, with a key of 16 bytes
+	X919
+	// ISO9797Alg1 is the CBC-MAC of ISO 9797-1 algorithm 1, with a DES key
+	// of 8 bytes or a TDES key of 16 or 24 bytes
+	ISO9797Alg1
+	// ISO9797Alg3 is the retail MAC of ISO 9797-1 algorithm 3, with a key
+	// of 16 bytes, or 24 bytes enciphering the last block with the third key
+	ISO9797Alg3
+	// AESCMAC is the CMAC of NIST SP 800-38B with an AES key of 16, 24 or
+	// 32 bytes, its MAC has 16 bytes
+	AESCMAC
+)
+
+// Padding is a padding method of ISO 9797-1
+type Padding int
+
+const (
+	// Padding1 fills the last block with zeros, the padding of X9.9 and X9.19
+	Padding1 Padding = 1
+	// Padding2 appends 80 and fills the last block with zeros
+	Padding2 Padding = 2
+)
+
+// Key is a MAC key of an algorithm, it signs and verifies messages
+type Key struct {
+	// Algorithm is the MAC algorithm
+	Algorithm Algorithm
+	// Key is the key in the clear
+	Key []byte
+	// Padding is the padding of the DES algorithms, 0 means Padding1.
+	// AES-CMAC has its own padding.
+	Padding Padding
+}
+
+// MAC returns the MAC of the data, 8 bytes with the DES algorithms and 16
+// bytes with AES-CMAC
+func (k *Key) MAC(data []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case X99:
+		if len(k.Key) != 8 {
+			return nil, invalidKeyLength(k)
+		}
+		return k.cbcMAC(data)
+	case ISO9797Alg1:
+		if len(k.Key) != 8 && len(k.Key) != 16 && len(k.Key) != 24 {
+			return nil, invalidKeyLength(k)
+		}
+		return k.cbcMAC(data)
+	case X919:
+		if len(k.Key) != 16 {
+			return nil, invalidKeyLength(k)
+		}
+		return k.retailMAC(data)
+	case ISO9797Alg3:
+		if len(k.Key) != 16 && len(k.Key) != 24 {
+			return nil, invalidKeyLength(k)
+		}
+		return k.retailMAC(data)
+	case AESCMAC:
+		return cmac(k.Key, data)
+	}
+	return nil, errors.New("invalid MAC algorithm: " + strconv.Itoa(int(k.Algorithm)))
+}
+
+func invalidKeyLength(k *Key) error {
+	return errors.New("invalid key length of MAC algorithm " + strconv.Itoa(int(k.Algorithm)) + ": " + strconv.Itoa(len(k.Key)))
+}
+
+// desCipher returns the DES cipher of a key of 8 bytes, or the TDES cipher
+// of a key of 16 or 24 bytes
+func desCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 8:
+		return des.NewCipher(key)
+	case 16:
+		k := make([]byte, 0, 24)
+		return des.NewTripleDESCipher(append(append(k, key...), key[:8]...))
+	}
+	return des.NewTripleDESCipher(key)
+}
+
+// pad returns the data padded to a multiple of 8 bytes
+func (k *Key) pad(data []byte) ([]byte, error) {
+	padded := append([]byte(nil), data...)
+	switch k.Padding {
+	case 0, Padding1:
+		// the empty data is a block of zeros
+		if len(padded) == 0 {
+			return make([]byte, 8), nil
+		}
+	case Padding2:
+		padded = append(padded, 0x80)
+	default:
+		return nil, errors.New("invalid MAC padding: " + strconv.Itoa(int(k.Padding)))
+	}
+	for len(padded)%8 != 0 {
+		padded = append(padded, 0)
+	}
+	return padded, nil
+}
+
+// chain enciphers the padded data in CBC mode, and returns the last block
+func chain(c cipher.Block, padded []byte) []byte {
+	block := make([]byte, c.BlockSize())
+	for i := 0; i < len(padded); i += len(block) {
+		for j := range block {
+			block[j] ^= padded[i+j]
+		}
+		c.Encrypt(block, block)
+	}
+	return block
+}
+
+func (k *Key) cbcMAC(data []byte) ([]byte, error) {
+	padded, err := k.pad(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := desCipher(k.Key)
+	if err != nil {
+		return nil, err
+	}
+	return chain(c, padded), nil
+}
+
+// retailMAC chains the data with the first key, then deciphers the last
+// block with the second key and enciphers it with the first or third key
+func (k *Key) retailMAC(data []byte) ([]byte, error) {
+	padded, err := k.pad(data)
+	if err != nil {
+		return nil, err
+	}
+	k1, err := des.NewCipher(k.Key[:8])
+	if err != nil {
+		return nil, err
+	}
+	k2, err := des.NewCipher(k.Key[8:16])
+	if err != nil {
+		return nil, err
+	}
+	k3 := k1
+	if len(k.Key) == 24 {
+		if k3, err = des.NewCipher(k.Key[16:]); err != nil {
+			return nil, err
+		}
+	}
+	block := chain(k1, padded)
+	k2.Decrypt(block, block)
+	k3.Encrypt(block, block)
+	return block, nil
+}
+
+// cmac returns the AES-CMAC of the data
+func cmac(key, data []byte) ([]byte, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return CMAC(c, data), nil
+}
+
+// CMAC returns the CMAC of NIST SP 800-38B of the data, with a block cipher
+// of 8 bytes (TDES) or 16 bytes (AES)
+func CMAC(c cipher.Block, data []byte) []byte {
+	size := c.BlockSize()
+	// the subkeys are the doubling of the enciphered zero block
+	k1 := make([]byte, size)
+	c.Encrypt(k1, k1)
+	k1 = double(k1)
+	k2 := double(k1)
+
+	n := (len(data) + size - 1) / size
+	last := make([]byte, size)
+	if n > 0 && len(data)%size == 0 {
+		copy(last, data[(n-1)*size:])
+		xorBlock(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		rest := data[(n-1)*size:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xorBlock(last, k2)
+	}
+	return chain(c, append(append([]byte(nil), data[:(n-1)*size]...), last...))
+}
+
+// double multiplies a block by x in GF(2^64) or GF(2^128)
+func double(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := 0; i < len(b)-1; i++ {
+		res[i] = b[i]<<1 | b[i+1]>>7
+	}
+	res[len(b)-1] = b[len(b)-1] << 1
+	if b[0]&0x80 != 0 {
+		if len(b) == 8 {
+			res[len(b)-1] ^= 0x1B
+		} else {
+			res[len(b)-1] ^= 0x87
+		}
+	}
+	return res
+}
+
+func xorBlock(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,136 @@
+package mac
+
+import (
+	"crypto/des"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/fluidpay/iso8583"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestMAC(t *testing.T) {
+	cmacKey := "2B7E151628AED2A6ABF7158809CF4F3C"
+	for _, test := range []struct {
+		key      Key
+		data     []byte
+		expected string
+	}{
+		// FIPS 113
+		{Key{Algorithm: X99, Key: mustHex(t, "0123456789ABCDEF")}, []byte("7654321 Now is the time for "), "F1D30F6849312CA4"},
+		{Key{Algorithm: ISO9797Alg1, Key: mustHex(t, "0123456789ABCDEF")}, []byte("7654321 Now is the time for "), "F1D30F6849312CA4"},
+		// ANSI X9.19
+		{Key{Algorithm: X919, Key: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")}, []byte("Now is the time for all "), "A1C72E74EA3FA9B6"},
+		{Key{Algorithm: ISO9797Alg3, Key: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")}, []byte("Now is the time for all "), "A1C72E74EA3FA9B6"},
+		// RFC 4493
+		{Key{Algorithm: AESCMAC, Key: mustHex(t, cmacKey)}, nil, "BB1D6929E95937287FA37D129B756746"},
+		{Key{Algorithm: AESCMAC, Key: mustHex(t, cmacKey)}, mustHex(t, "6BC1BEE22E409F96E93D7E117393172A"), "070A16B46B4D4144F79BDD9DD04A287C"},
+		{Key{Algorithm: AESCMAC, Key: mustHex(t, cmacKey)}, mustHex(t, "6BC1BEE22E409F96E93D7E117393172AAE2D8A571E03AC9C9EB76FAC45AF8E5130C81C46A35CE411"), "DFA66747DE9AE63030CA32611497C827"},
+	} {
+		mac, err := test.key.MAC(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(mac)); got != test.expected {
+			t.Errorf("MAC of algorithm %d should be %s, got %s", test.key.Algorithm, test.expected, got)
+		}
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	// NIST SP 800-38B, TDES of 3 keys
+	c, err := des.NewTripleDESCipher(mustHex(t, "8AA83BF8CBDA10620BC1BF19FBB6CD58BC313D4A371CA8B5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for data, expected := range map[string]string{
+		"":                 "B7A688E122FFAF95",
+		"6BC1BEE22E409F96": "8E8F293136283797",
+		"6BC1BEE22E409F96E93D7E117393172AAE2D8A57": "743DDBE0CE2DC2ED",
+	} {
+		if got := strings.ToUpper(hex.EncodeToString(CMAC(c, mustHex(t, data)))); got != expected {
+			t.Errorf("TDES CMAC of %s should be %s, got %s", data, expected, got)
+		}
+	}
+}
+
+func TestMACKeys(t *testing.T) {
+	data := []byte("Now is the time for all")
+	// a double length key of the same halves is a single DES key
+	single, _ := (&Key{Algorithm: ISO9797Alg1, Key: mustHex(t, "0123456789ABCDEF")}).MAC(data)
+	double, _ := (&Key{Algorithm: ISO9797Alg1, Key: mustHex(t, "0123456789ABCDEF0123456789ABCDEF")}).MAC(data)
+	if hex.EncodeToString(single) != hex.EncodeToString(double) {
+		t.Errorf("MAC of the double length key should be %X, got %X", single, double)
+	}
+	// the third key of algorithm 3 enciphers the last block
+	alg3, _ := (&Key{Algorithm: ISO9797Alg3, Key: mustHex(t, "0123456789ABCDEFFEDCBA98765432100123456789ABCDEF")}).MAC(data)
+	x919, _ := (&Key{Algorithm: X919, Key: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")}).MAC(data)
+	if hex.EncodeToString(alg3) != hex.EncodeToString(x919) {
+		t.Errorf("MAC of the triple length key K1K2K1 should be %X, got %X", x919, alg3)
+	}
+
+	for _, key := range []Key{
+		{Algorithm: X99, Key: make([]byte, 16)},
+		{Algorithm: X919, Key: make([]byte, 8)},
+		{Algorithm: ISO9797Alg1, Key: make([]byte, 12)},
+		{Algorithm: ISO9797Alg3, Key: make([]byte, 32)},
+		{Algorithm: AESCMAC, Key: make([]byte, 8)},
+		{Algorithm: Algorithm(9), Key: make([]byte, 16)},
+		{Algorithm: X919, Key: make([]byte, 16), Padding: Padding(3)},
+	} {
+		if _, err := key.MAC(data); err == nil {
+			t.Errorf("MAC of algorithm %d with key of %d bytes and padding %d should fail", key.Algorithm, len(key.Key), key.Padding)
+		}
+	}
+}
+
+func TestPadding(t *testing.T) {
+	k := &Key{}
+	for _, test := range []struct {
+		padding  Padding
+		data     string
+		expected string
+	}{
+		{Padding1, "", "0000000000000000"},
+		{Padding1, "0102", "0102000000000000"},
+		{Padding1, "0102030405060708", "0102030405060708"},
+		{Padding2, "", "8000000000000000"},
+		{Padding2, "0102030405060708", "01020304050607088000000000000000"},
+	} {
+		k.Padding = test.padding
+		padded, err := k.pad(mustHex(t, test.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(padded)); got != test.expected {
+			t.Errorf("padding %d of %s should be %s, got %s", test.padding, test.data, test.expected, got)
+		}
+	}
+}
+
+func TestSignMessage(t *testing.T) {
+	key := &Key{Algorithm: X919, Key: mustHex(t, "0123456789ABCDEFFEDCBA9876543210")}
+	m := &iso8583.Message{Mti: "0200", DE3: iso8583.NewNumeric("000000"), DE11: iso8583.NewNumeric("000001")}
+	if err := m.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac, _ := key.MAC(b[:len(b)-16])
+	if got := m.DE64.String(); got != strings.ToUpper(hex.EncodeToString(mac)) {
+		t.Errorf("DE64 should be %X, got %s", mac, got)
+	}
+	if err := m.Verify(&Key{Algorithm: AESCMAC, Key: make([]byte, 16)}); err != iso8583.ErrInvalidMAC {
+		t.Errorf("MAC of another key should be ErrInvalidMAC, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package iso8583
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testMACKey is the SHA-256 of a secret and the data
+type testMACKey string
+
+func (k testMACKey) MAC(data []byte) ([]byte, error) {
+	h := sha256.Sum256(append([]byte(k), data...))
+	return h[:], nil
+}
+
+func TestSign(t *testing.T) {
+	m := &Message{Mti: "0200", DE3: NewNumeric("000000"), DE11: NewNumeric("000001")}
+	key := testMACKey("secret")
+	if err := m.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if m.DE64 == nil || m.DE128 != nil {
+		t.Fatalf("MAC should be in DE64, got %v %v", m.DE64, m.DE128)
+	}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := key.MAC(b[:len(b)-16])
+	equals(t, m.DE64.String(), strings.ToUpper(hex.EncodeToString(expected[:8])), "DE64")
+
+	decoded := &Message{}
+	if err := decoded.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Verify(key); err != nil {
+		t.Errorf("MAC should be verified, got %v", err)
+	}
+	if err := decoded.Verify(testMACKey("other")); err != ErrInvalidMAC {
+		t.Errorf("MAC of another key should be ErrInvalidMAC, got %v", err)
+	}
+	decoded.DE11 = NewNumeric("000002")
+	if err := decoded.Verify(key); err != ErrInvalidMAC {
+		t.Errorf("MAC of a changed message should be ErrInvalidMAC, got %v", err)
+	}
+
+	// with a secondary bitmap, the MAC is DE128 and DE64 is dropped
+	m.DE70 = NewNumeric("301")
+	if err := m.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if m.DE64 != nil || m.DE128 == nil {
+		t.Fatalf("MAC should be in DE128, got %v %v", m.DE64, m.DE128)
+	}
+	if err := m.Verify(key); err != nil {
+		t.Errorf("MAC should be verified, got %v", err)
+	}
+	m.DE64, m.DE128 = m.DE128, nil
+	if err := m.Verify(key); err == nil {
+		t.Error("DE64 before the secondary fields should fail")
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	m := &Message{Mti: "0200", DE3: NewNumeric("000000")}
+	if err := m.Verify(testMACKey("secret")); err == nil {
+		t.Error("message without MAC should fail")
+	}
+	failing := macKeyFunc(func([]byte) ([]byte, error) { return nil, errors.New("HSM down") })
+	if err := m.Sign(failing); err == nil || m.DE64 != nil {
+		t.Errorf("failed MAC should not set DE64, got %v %v", m.DE64, err)
+	}
+	short := macKeyFunc(func([]byte) ([]byte, error) { return []byte{1, 2, 3, 4}, nil })
+	if err := m.Sign(short); err == nil {
+		t.Error("MAC of 4 bytes should fail")
+	}
+}
+
+type macKeyFunc func([]byte) ([]byte, error)
+
+func (f macKeyFunc) MAC(data []byte) ([]byte, error) {
+	return f(data)
+}
+
+// verifierKey verifies the MACs itself, like the keys of an HSM
+type verifierKey struct {
+	testMACKey
+	verified []byte
+}
+
+func (k *verifierKey) VerifyMAC(data, mac []byte) error {
+	k.verified = mac
+	return nil
+}
+
+func TestVerifyWithVerifier(t *testing.T) {
+	m := &Message{Mti: "0200", DE3: NewNumeric("000000")}
+	if err := m.Sign(testMACKey("secret")); err != nil {
+		t.Fatal(err)
+	}
+	key := &verifierKey{testMACKey: "other"}
+	if err := m.Verify(key); err != nil {
+		t.Errorf("MAC should be verified by the key, got %v", err)
+	}
+	equals(t, strings.ToUpper(hex.EncodeToString(key.verified)), m.DE64.String(), "verified MAC")
+}
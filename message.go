@@ -3,86 +3,99 @@ package iso8583
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
 )
 
+//go:generate go run ./cmd/iso8583gen -type Message,SubMessage -output message_gen.go
+
 type Message struct {
 	Mti string `json:",omitempty"`
 
 	packedBitmap bool
 	packedMsg    bool
 	encoder      int
+	lenient      bool
+	decodeErrors ValidationErrors
+	panValidator *PANValidator
+	copyOnDecode bool
 
 	bitmapPrimary uint64
 
 	SafeLog bool `json:"-"` // This determines whether or not to log DE2
 
-	DE1   uint64      `format:"" length:"64" json:",omitempty"` //secondary bitmap
-	DE2   *N          `format:"LLVAR" length:"19" validator:"N" json:",omitempty"`
-	DE3   *N          `format:"" length:"6" validator:"N" json:",omitempty"`
-	DE4   *N          `format:"" length:"12" validator:"N" json:",omitempty"`
-	DE5   *N          `format:"" length:"12" validator:"N" json:",omitempty"`
-	DE6   *N          `format:"" length:"12" validator:"N" json:",omitempty"`
-	DE7   *N          `format:"" length:"10" validator:"MMDDHHMMSS" json:",omitempty"`
-	DE9   *N          `format:"" length:"8" validator:"N" json:",omitempty"`
-	DE10  *N          `format:"" length:"8" validator:"N" json:",omitempty"`
-	DE11  *N          `format:"" length:"6" validator:"N" json:",omitempty"`
-	DE12  *N          `format:"" length:"12" validator:"YYMMDDHHMMSS" json:",omitempty"`
-	DE14  *N          `format:"" length:"4" validator:"YYMM" json:",omitempty"`
-	DE16  *N          `format:"" length:"4" validator:"MMDD" json:",omitempty"`
-	DE17  *N          `format:"" length:"4" validator:"MMDD" json:",omitempty"`
-	DE18  *N          `format:"" length:"4" validator:"N" json:",omitempty"`
-	DE19  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE22  *AN         `format:"" length:"12" validator:"AN" json:",omitempty"`
-	DE23  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE24  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE25  *N          `format:"" length:"4" validator:"N" json:",omitempty"`
-	DE26  *N          `format:"" length:"4" validator:"N" json:",omitempty"`
-	DE28  *N          `format:"" length:"6" validator:"YYMMDD" json:",omitempty"`
-	DE30  *N          `format:"" length:"24" validator:"N" json:",omitempty"`
-	DE32  *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE33  *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE34  *N          `format:"LLVAR" length:"28" validator:"N" json:",omitempty"`
-	DE35  *Z          `format:"LLVAR" length:"37" validator:"Z" json:",omitempty"`
-	DE37  *ANP        `format:"" length:"12" validator:"ANP" json:",omitempty"`
-	DE38  *ANP        `format:"" length:"6" validator:"ANP" json:",omitempty"`
-	DE39  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE41  *ANS        `format:"" length:"8" validator:"ANS" json:",omitempty"`
-	DE42  *ANS        `format:"" length:"15" validator:"ANS" json:",omitempty"`
-	DE43  *ANS        `format:"LLVAR" length:"99" validator:"ANS" json:",omitempty"`
-	DE46  *ANS        `format:"LLLVAR" length:"186" validator:"ANS" json:",omitempty"`
-	DE47  *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE48  *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE49  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE50  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE51  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE52  *B64        `format:"" length:"64" validator:"B64" json:",omitempty"`
-	DE54  *ANS        `format:"LLLVAR" length:"120" validator:"ANS" json:",omitempty"`
-	DE56  *N          `format:"LLVAR" length:"35" validator:"N" json:",omitempty"`
-	DE57  *N          `format:"" length:"3" validator:"N" json:",omitempty"`
-	DE58  *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE59  *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE62  *N          `format:"" length:"6" validator:"N" json:",omitempty"`
-	DE63  *N          `format:"" length:"4" validator:"MMDD" json:",omitempty"`
-	DE66  *ANS        `format:"LLLVAR" length:"204" validator:"ANS" json:",omitempty"`
-	DE72  *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE93  *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE94  *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE95  *ANS        `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
-	DE96  *ANS        `format:"LLLVAR" length:"100" validator:"ANS" json:",omitempty"`
-	DE100 *N          `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
-	DE101 *ANS        `format:"LLVAR" length:"17" validator:"ANS" json:",omitempty"`
-	DE102 *ANS        `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`
-	DE103 *ANS        `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`
-	DE111 *ANS        `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
-	DE123 *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE124 *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE125 *SubMessage `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE126 *ANS        `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
-	DE127 *ANS        `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
-	DE128 *ANS        `format:"LLLLLVAR" length:"99999" validator:"ANS" json:",omitempty"`
+	DE1   uint64           `format:"" length:"64" json:",omitempty"` //secondary bitmap
+	DE2   *N               `format:"LLVAR" length:"19" validator:"N" json:",omitempty"`
+	DE3   *N               `format:"" length:"6" validator:"N" json:",omitempty"`
+	DE4   *N               `format:"" length:"12" validator:"N" json:",omitempty"`
+	DE5   *N               `format:"" length:"12" validator:"N" json:",omitempty"`
+	DE6   *N               `format:"" length:"12" validator:"N" json:",omitempty"`
+	DE7   *DateTime        `format:"" length:"10" validator:"MMDDHHMMSS" json:",omitempty"`
+	DE9   *N               `format:"" length:"8" validator:"N" json:",omitempty"`
+	DE10  *N               `format:"" length:"8" validator:"N" json:",omitempty"`
+	DE11  *N               `format:"" length:"6" validator:"N" json:",omitempty"`
+	DE12  *DateTime        `format:"" length:"12" validator:"YYMMDDHHMMSS" json:",omitempty"`
+	DE13  *DateTime        `format:"" length:"4" validator:"YYMM" json:",omitempty"`
+	DE14  *DateTime        `format:"" length:"4" validator:"YYMM" json:",omitempty"`
+	DE15  *DateTime        `format:"" length:"6" validator:"YYMMDD" json:",omitempty"`
+	DE16  *DateTime        `format:"" length:"4" validator:"MMDD" json:",omitempty"`
+	DE17  *DateTime        `format:"" length:"4" validator:"MMDD" json:",omitempty"`
+	DE18  *N               `format:"" length:"4" validator:"N" json:",omitempty"`
+	DE19  *N               `format:"" length:"3" validator:"COUNTRY" json:",omitempty"`
+	DE22  *AN              `format:"" length:"12" validator:"AN" json:",omitempty"`
+	DE23  *N               `format:"" length:"3" validator:"N" json:",omitempty"`
+	DE24  *N               `format:"" length:"3" validator:"N" json:",omitempty"`
+	DE25  *N               `format:"" length:"4" validator:"N" json:",omitempty"`
+	DE26  *N               `format:"" length:"4" validator:"N" json:",omitempty"`
+	DE28  *DateTime        `format:"" length:"6" validator:"YYMMDD" json:",omitempty"`
+	DE30  *N               `format:"" length:"24" validator:"N" json:",omitempty"`
+	DE32  *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE33  *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE34  *N               `format:"LLVAR" length:"28" validator:"N" json:",omitempty"`
+	DE35  *Z               `format:"LLVAR" length:"37" validator:"Z" json:",omitempty"`
+	DE37  *ANP             `format:"" length:"12" validator:"ANP" json:",omitempty"`
+	DE38  *ANP             `format:"" length:"6" validator:"ANP" json:",omitempty"`
+	DE39  *AN              `format:"" length:"3" validator:"AN" json:",omitempty"` // 2 characters in version 0 (1987)
+	DE41  *ANS             `format:"" length:"8" validator:"ANS" json:",omitempty"`
+	DE42  *ANS             `format:"" length:"15" validator:"ANS" json:",omitempty"`
+	DE43  *ANS             `format:"LLVAR" length:"99" validator:"ANS" json:",omitempty"`
+	DE46  *ANS             `format:"LLLVAR" length:"186" validator:"ANS" json:",omitempty"`
+	DE47  *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE48  *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE49  *N               `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`
+	DE50  *N               `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`
+	DE51  *N               `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`
+	DE52  *B64             `format:"" length:"64" validator:"B64" json:",omitempty"`
+	DE53  *SecurityControl `format:"LLVAR" length:"48" validator:"BN" json:",omitempty"`
+	DE54  *ANS             `format:"LLLVAR" length:"120" validator:"ANS" json:",omitempty"`
+	DE56  *N               `format:"LLVAR" length:"35" validator:"N" json:",omitempty"`
+	DE57  *N               `format:"" length:"3" validator:"N" json:",omitempty"`
+	DE58  *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE59  *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE62  *N               `format:"" length:"6" validator:"N" json:",omitempty"`
+	DE63  *N               `format:"" length:"4" validator:"MMDD" json:",omitempty"`
+	DE64  *B64             `format:"" length:"64" validator:"B64" json:",omitempty"`
+	DE66  *ANS             `format:"LLLVAR" length:"204" validator:"ANS" json:",omitempty"`
+	DE70  *N               `format:"" length:"3" validator:"N" json:",omitempty"`
+	DE72  *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE90  *N               `format:"" length:"42" validator:"N" json:",omitempty"`
+	DE93  *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE94  *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE95  *ANS             `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
+	DE96  *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE100 *N               `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`
+	DE101 *ANS             `format:"LLVAR" length:"17" validator:"ANS" json:",omitempty"`
+	DE102 *ANS             `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`
+	DE103 *ANS             `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`
+	DE111 *ANS             `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
+	DE123 *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE124 *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE125 *SubMessage      `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE126 *ANS             `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`
+	DE127 *ANS             `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`
+	DE128 *B64             `format:"" length:"64" validator:"B64" json:",omitempty"`
 }
 
 func New() *Message {
@@ -94,193 +107,143 @@ func NewSafe() *Message {
 }
 
 func (m *Message) Encode() ([]byte, error) {
-	res := make([]byte, 0)
+	if len(m.Mti) != 4 {
+		return []byte{}, errors.New("invalid MTI length")
+	}
+	return m.AppendEncode(make([]byte, 0, 512))
+}
 
+// AppendEncode appends the encoded message to dst and returns the extended
+// buffer, e.g. to reuse the same buffer for every message
+func (m *Message) AppendEncode(dst []byte) ([]byte, error) {
+	return m.appendEncode(dst, true)
+}
+
+// appendEncode is AppendEncode, by the generated code or by reflection
+func (m *Message) appendEncode(dst []byte, generated bool) ([]byte, error) {
 	// append mti
 	if len(m.Mti) != 4 {
-		return []byte{}, errors.New("invalid MTI length")
+		return nil, errors.New("invalid MTI length")
 	}
 	switch m.encoder {
 	case BCDIC:
 	case ASCII:
-		res = append(res, []byte(m.Mti)...)
+		dst = append(dst, m.Mti...)
 	}
 
-	// initialize bitmaps
-	var bitmapPrimary uint64
-	var bitmapSecondary uint64
-
-	data := make([]byte, 0, 512)
-
-	v := reflect.Indirect(reflect.ValueOf(m))
-	t := v.Type()
-	// iterate through iso fields, if field is not empty,
-	// encode and append it to data, and set the proper bit in bitmap
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Kind() != reflect.Ptr || v.Field(i).IsNil() {
-			continue
-		}
-		sf := t.Field(i)
-		// get field index, e.g. for DE2 index=2
-		index, err := strconv.Atoi(strings.Trim(sf.Name, "DE"))
-		if err != nil {
-			return nil, err
-		}
-		// field length
-		length, err := strconv.Atoi(sf.Tag.Get("length"))
-		if err != nil {
-			return nil, err
-		}
-		// iso field format, "" means that field has fixed length, but
-		// e.g. LLVAR means that length indicator is encoded in the first 2 byte
-		// in this case length means "maximum length
-		format := sf.Tag.Get("format")
-		validator := sf.Tag.Get("validator")
-
-		if index <= 64 {
-			bitmapPrimary = addField(bitmapPrimary, uint8(index))
-		} else {
-			// if we need secondary bitmap, set first bit in primary bitmap
-			bitmapPrimary |= 1 << 63
-			bitmapSecondary = addField(bitmapSecondary, uint8(index-64))
-		}
-
-		// tweak of submessage
-		f, ok := v.Field(i).Interface().(field)
-		if !ok {
-			if sm, ok := v.Field(i).Interface().(*SubMessage); ok {
-				res, err := sm.Encode()
-				if err != nil {
-					return nil, err
-				}
-				d, err := NewANS(string(res)).Encode(m.encoder, length, format, validator)
-				data = append(data, d...)
-				continue
-			} else {
-				continue
-			}
-		}
-
-		// encode field, append it to data
-		d, err := f.Encode(m.encoder, length, format, validator)
-		if err != nil {
-			return nil, err
-		}
-		data = append(data, d...)
+	if err := m.validatePAN(); err != nil {
+		return nil, err
 	}
 
-	// append bitmaps to result
-	m.bitmapPrimary = bitmapPrimary
-	res = append(res, []byte(bitmapHex(bitmapPrimary))...)
+	// encode bitmaps and iso data elements, append them to result
+	return appendBitmapped(dst, m.fields(generated), m.encoder, DefaultBitmap)
+}
 
-	if bitmapSecondary != 0 {
-		m.DE1 = bitmapSecondary
-		res = append(res, []byte(bitmapHex(bitmapSecondary))...)
+// EncodeTo writes the encoded message to w, through a reused buffer,
+// and returns the number of bytes written
+func (m *Message) EncodeTo(w io.Writer) (int, error) {
+	buf := encodeBuffers.Get().(*[]byte)
+	defer encodeBuffers.Put(buf)
+	b, err := m.AppendEncode((*buf)[:0])
+	if err != nil {
+		return 0, err
 	}
+	*buf = b
+	return w.Write(b)
+}
 
-	// append iso data elements to result
-	res = append(res, data...)
-	return res, nil
+// encodeBuffers are reused by EncodeTo
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
 }
 
+// Decode decodes the message from bytes. It allocates the MTI and the struct
+// of every present field, the Values of the fields alias bytes without
+// allocations, unless SetCopyOnDecode is set.
 func (m *Message) Decode(bytes []byte) error {
-	var err error
+	return m.decode(bytes, true)
+}
 
+// decode is Decode, by the generated code or by reflection
+func (m *Message) decode(bytes []byte, generated bool) error {
 	// decode MTI
-
-	// it is an iterator, watching where we are currently in the iteration,
-	// which byte will be the starting position of the next decode
-	it := 4
-	m.Mti = string(bytes[:it])
-
-	// decode bitmaps
-	//decode primary bitmap
-	m.bitmapPrimary, err = decodeHexString(string(bytes[it : it+16]))
-	if err != nil {
-		return err
+	if len(bytes) < 4 {
+		return errors.New("invalid MTI length")
 	}
-	it += 16
-
-	// if first bit is 1, it means that we have secondary bitmap, decode secondary bitmap
-	if isBitSet(m.bitmapPrimary, 1) {
-		m.DE1, err = decodeHexString(string(bytes[it : it+16]))
-		if err != nil {
-			return err
-		}
-		it += 16
+	if m.copyOnDecode {
+		bytes = append([]byte(nil), bytes...)
 	}
+	m.Mti = string(bytes[:4])
 
-	v := reflect.Indirect(reflect.ValueOf(m))
-	t := v.Type()
-	// iterate through iso fields, if bitmap is not empty at bit position i,
-	// set field with index i with proper value
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Kind() != reflect.Ptr {
-			continue
-		}
-		sf := t.Field(i)
-		// get field index, e.g. for DE2 index=2
-		index, err := strconv.Atoi(strings.Trim(sf.Name, "DE"))
-		if err != nil {
+	// decode bitmaps and iso data elements
+	if !m.lenient {
+		if _, err := decodeBitmapped(bytes[4:], m.fields(generated), m.encoder, DefaultBitmap, nil, ""); err != nil {
 			return err
 		}
-		// if index < 64, search in primary bitmap if it is set
-		if index <= 64 && !isBitSet(m.bitmapPrimary, uint8(index)) {
-			continue
+		return m.validatePAN()
+	}
+	m.decodeErrors = nil
+	var errs ValidationErrors
+	_, err := decodeBitmapped(bytes[4:], m.fields(generated), m.encoder, DefaultBitmap, &errs, "")
+	if err == nil && !errs.has("DE2") {
+		if panErr := m.validatePAN(); panErr != nil {
+			errs = append(ValidationErrors{panErr.(*FieldError)}, errs...)
 		}
+	}
+	if len(errs) > 0 {
+		m.decodeErrors = errs
+	}
+	return err
+}
 
-		// if index > 64, search in secondary bitmap if it is set
-		if index > 64 && !isBitSet(m.DE1, uint8(index-64)) {
-			continue
-		}
+// SetLenient makes Decode keep the fields with invalid values instead of failing,
+// e.g. to answer with a format error. The errors of these fields are returned
+// by DecodeErrors, Decode fails only if the rest of the message cannot be found.
+func (m *Message) SetLenient(lenient bool) {
+	m.lenient = lenient
+}
 
-		// field (maximum) length
-		length, err := strconv.Atoi(sf.Tag.Get("length"))
-		if err != nil {
-			return err
-		}
-		// iso field format, "" means that field has fixed length, but
-		// e.g. LLVAR means that length indicator is encoded in the first 2 byte
-		// in this case length means "maximum length
-		format := sf.Tag.Get("format")
-		validator := sf.Tag.Get("validator")
-
-		// Decode field
-		structField := v.Field(i)
-
-		// skip if unexported
-		if !structField.CanSet() {
-			continue
-		}
-		// initialize field with empty struct
-		fieldTyp := reflect.New(structField.Type().Elem())
-		structField.Set(fieldTyp)
-
-		var nextFieldOffset int
-		f, ok := v.Field(i).Interface().(field)
-		if !ok {
-			if sm, ok := v.Field(i).Interface().(*SubMessage); ok {
-				ans := NewANS("")
-				nextFieldOffset, err = ans.Decode(bytes[it:], m.encoder, length, format, validator)
-				err = sm.Decode(ans.Value)
-				if err != nil {
-					return err
-				}
-				continue
-				//v.Field(i).Set(reflect.ValueOf(sm))
-			} else {
-				continue
-			}
-		}
-		nextFieldOffset, err = f.Decode(bytes[it:], m.encoder, length, format, validator)
-		if err != nil {
-			return err
+// SetCopyOnDecode sets the ownership of the decoded bytes. By default the
+// Values of the decoded fields alias the bytes passed to Decode, without
+// copying them, so these bytes must not be modified or reused as long as the
+// message is in use. With copy, Decode copies the bytes once, and the caller
+// can reuse them, e.g. as a read buffer.
+func (m *Message) SetCopyOnDecode(copy bool) {
+	m.copyOnDecode = copy
+}
+
+// DecodeErrors returns the errors of the invalid fields kept by the last lenient Decode
+func (m *Message) DecodeErrors() ValidationErrors {
+	return m.decodeErrors
+}
+
+// Validate checks the MTI and every present field without encoding the message,
+// and returns ValidationErrors with all of the invalid fields, or nil
+func (m *Message) Validate() error {
+	var errs ValidationErrors
+	if len(m.Mti) != 4 {
+		errs = append(errs, &FieldError{Field: "MTI", Err: errors.New("invalid MTI length")})
+	}
+	fieldErrs := validateBitmapped(m.fields(true), m.encoder, DefaultBitmap, "")
+	if !fieldErrs.has("DE2") {
+		if err := m.validatePAN(); err != nil {
+			errs = append(errs, err.(*FieldError))
 		}
-		it += nextFieldOffset
+	}
+	errs = append(errs, fieldErrs...)
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
+func (m *Message) setPrimaryBitmap(bitmap uint64) {
+	m.bitmapPrimary = bitmap
+}
+
 // String will take in the message struct and output to a string
 // if SafeLog is true clear out DE2 for safe logging
 func (m *Message) String() string {
@@ -305,3 +268,46 @@ func (m *Message) PackedMessage(packed bool) {
 func (m *Message) SetEncoder(encoder int) {
 	m.encoder = encoder
 }
+
+// fields returns the access to the fields of m, by the generated code or by
+// reflection, with the lengths of the version of the MTI, DE39 has 2
+// characters in version 0 (1987)
+func (m *Message) fields(generated bool) Generated {
+	s := structOf(m)
+	if !generated {
+		s = newReflectStruct(reflect.ValueOf(m))
+	}
+	if len(m.Mti) == 0 || m.Mti[0] != '0' {
+		return s
+	}
+	return version1987{s}
+}
+
+// version1987 are the fields of a message of version 0
+type version1987 struct {
+	Generated
+}
+
+// specs1987 are the field specs of version 0, computed once
+var specs1987 struct {
+	once  sync.Once
+	specs []FieldSpec
+}
+
+func (v version1987) ISO8583Fields() []FieldSpec {
+	specs1987.once.Do(func() {
+		specs1987.specs = append([]FieldSpec(nil), v.Generated.ISO8583Fields()...)
+		for i := range specs1987.specs {
+			if specs1987.specs[i].Index == 39 {
+				specs1987.specs[i].Length = 2
+			}
+		}
+	})
+	return specs1987.specs
+}
+
+func (v version1987) setPrimaryBitmap(bitmap uint64) {
+	if h, ok := v.Generated.(bitmapHolder); ok {
+		h.setPrimaryBitmap(bitmap)
+	}
+}
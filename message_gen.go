@@ -0,0 +1,1393 @@
+// Code generated by iso8583gen -type Message,SubMessage; DO NOT EDIT.
+
+package iso8583
+
+var messageFieldSpecs = []FieldSpec{
+	{Name: "DE2", Index: 2, Length: 19, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"19" validator:"N" json:",omitempty"`},
+	{Name: "DE3", Index: 3, Length: 6, Format: "", Validator: "N", Tag: `format:"" length:"6" validator:"N" json:",omitempty"`},
+	{Name: "DE4", Index: 4, Length: 12, Format: "", Validator: "N", Tag: `format:"" length:"12" validator:"N" json:",omitempty"`},
+	{Name: "DE5", Index: 5, Length: 12, Format: "", Validator: "N", Tag: `format:"" length:"12" validator:"N" json:",omitempty"`},
+	{Name: "DE6", Index: 6, Length: 12, Format: "", Validator: "N", Tag: `format:"" length:"12" validator:"N" json:",omitempty"`},
+	{Name: "DE7", Index: 7, Length: 10, Format: "", Validator: "MMDDHHMMSS", Tag: `format:"" length:"10" validator:"MMDDHHMMSS" json:",omitempty"`},
+	{Name: "DE9", Index: 9, Length: 8, Format: "", Validator: "N", Tag: `format:"" length:"8" validator:"N" json:",omitempty"`},
+	{Name: "DE10", Index: 10, Length: 8, Format: "", Validator: "N", Tag: `format:"" length:"8" validator:"N" json:",omitempty"`},
+	{Name: "DE11", Index: 11, Length: 6, Format: "", Validator: "N", Tag: `format:"" length:"6" validator:"N" json:",omitempty"`},
+	{Name: "DE12", Index: 12, Length: 12, Format: "", Validator: "YYMMDDHHMMSS", Tag: `format:"" length:"12" validator:"YYMMDDHHMMSS" json:",omitempty"`},
+	{Name: "DE13", Index: 13, Length: 4, Format: "", Validator: "YYMM", Tag: `format:"" length:"4" validator:"YYMM" json:",omitempty"`},
+	{Name: "DE14", Index: 14, Length: 4, Format: "", Validator: "YYMM", Tag: `format:"" length:"4" validator:"YYMM" json:",omitempty"`},
+	{Name: "DE15", Index: 15, Length: 6, Format: "", Validator: "YYMMDD", Tag: `format:"" length:"6" validator:"YYMMDD" json:",omitempty"`},
+	{Name: "DE16", Index: 16, Length: 4, Format: "", Validator: "MMDD", Tag: `format:"" length:"4" validator:"MMDD" json:",omitempty"`},
+	{Name: "DE17", Index: 17, Length: 4, Format: "", Validator: "MMDD", Tag: `format:"" length:"4" validator:"MMDD" json:",omitempty"`},
+	{Name: "DE18", Index: 18, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N" json:",omitempty"`},
+	{Name: "DE19", Index: 19, Length: 3, Format: "", Validator: "COUNTRY", Tag: `format:"" length:"3" validator:"COUNTRY" json:",omitempty"`},
+	{Name: "DE22", Index: 22, Length: 12, Format: "", Validator: "AN", Tag: `format:"" length:"12" validator:"AN" json:",omitempty"`},
+	{Name: "DE23", Index: 23, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N" json:",omitempty"`},
+	{Name: "DE24", Index: 24, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N" json:",omitempty"`},
+	{Name: "DE25", Index: 25, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N" json:",omitempty"`},
+	{Name: "DE26", Index: 26, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N" json:",omitempty"`},
+	{Name: "DE28", Index: 28, Length: 6, Format: "", Validator: "YYMMDD", Tag: `format:"" length:"6" validator:"YYMMDD" json:",omitempty"`},
+	{Name: "DE30", Index: 30, Length: 24, Format: "", Validator: "N", Tag: `format:"" length:"24" validator:"N" json:",omitempty"`},
+	{Name: "DE32", Index: 32, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE33", Index: 33, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE34", Index: 34, Length: 28, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"28" validator:"N" json:",omitempty"`},
+	{Name: "DE35", Index: 35, Length: 37, Format: "LLVAR", Validator: "Z", Tag: `format:"LLVAR" length:"37" validator:"Z" json:",omitempty"`},
+	{Name: "DE37", Index: 37, Length: 12, Format: "", Validator: "ANP", Tag: `format:"" length:"12" validator:"ANP" json:",omitempty"`},
+	{Name: "DE38", Index: 38, Length: 6, Format: "", Validator: "ANP", Tag: `format:"" length:"6" validator:"ANP" json:",omitempty"`},
+	{Name: "DE39", Index: 39, Length: 3, Format: "", Validator: "AN", Tag: `format:"" length:"3" validator:"AN" json:",omitempty"`},
+	{Name: "DE41", Index: 41, Length: 8, Format: "", Validator: "ANS", Tag: `format:"" length:"8" validator:"ANS" json:",omitempty"`},
+	{Name: "DE42", Index: 42, Length: 15, Format: "", Validator: "ANS", Tag: `format:"" length:"15" validator:"ANS" json:",omitempty"`},
+	{Name: "DE43", Index: 43, Length: 99, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"99" validator:"ANS" json:",omitempty"`},
+	{Name: "DE46", Index: 46, Length: 186, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"186" validator:"ANS" json:",omitempty"`},
+	{Name: "DE47", Index: 47, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE48", Index: 48, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE49", Index: 49, Length: 3, Format: "", Validator: "CURRENCY", Tag: `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`},
+	{Name: "DE50", Index: 50, Length: 3, Format: "", Validator: "CURRENCY", Tag: `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`},
+	{Name: "DE51", Index: 51, Length: 3, Format: "", Validator: "CURRENCY", Tag: `format:"" length:"3" validator:"CURRENCY" json:",omitempty"`},
+	{Name: "DE52", Index: 52, Length: 64, Format: "", Validator: "B64", Tag: `format:"" length:"64" validator:"B64" json:",omitempty"`},
+	{Name: "DE53", Index: 53, Length: 48, Format: "LLVAR", Validator: "BN", Tag: `format:"LLVAR" length:"48" validator:"BN" json:",omitempty"`},
+	{Name: "DE54", Index: 54, Length: 120, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"120" validator:"ANS" json:",omitempty"`},
+	{Name: "DE56", Index: 56, Length: 35, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"35" validator:"N" json:",omitempty"`},
+	{Name: "DE57", Index: 57, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N" json:",omitempty"`},
+	{Name: "DE58", Index: 58, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE59", Index: 59, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE62", Index: 62, Length: 6, Format: "", Validator: "N", Tag: `format:"" length:"6" validator:"N" json:",omitempty"`},
+	{Name: "DE63", Index: 63, Length: 4, Format: "", Validator: "MMDD", Tag: `format:"" length:"4" validator:"MMDD" json:",omitempty"`},
+	{Name: "DE64", Index: 64, Length: 64, Format: "", Validator: "B64", Tag: `format:"" length:"64" validator:"B64" json:",omitempty"`},
+	{Name: "DE66", Index: 66, Length: 204, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"204" validator:"ANS" json:",omitempty"`},
+	{Name: "DE70", Index: 70, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N" json:",omitempty"`},
+	{Name: "DE72", Index: 72, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE90", Index: 90, Length: 42, Format: "", Validator: "N", Tag: `format:"" length:"42" validator:"N" json:",omitempty"`},
+	{Name: "DE93", Index: 93, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE94", Index: 94, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE95", Index: 95, Length: 9999, Format: "LLLLVAR", Validator: "ANS", Tag: `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE96", Index: 96, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE100", Index: 100, Length: 11, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"11" validator:"N" json:",omitempty"`},
+	{Name: "DE101", Index: 101, Length: 17, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"17" validator:"ANS" json:",omitempty"`},
+	{Name: "DE102", Index: 102, Length: 28, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`},
+	{Name: "DE103", Index: 103, Length: 28, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"28" validator:"ANS" json:",omitempty"`},
+	{Name: "DE111", Index: 111, Length: 9999, Format: "LLLLVAR", Validator: "ANS", Tag: `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE123", Index: 123, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE124", Index: 124, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE125", Index: 125, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`, SubMessage: true},
+	{Name: "DE126", Index: 126, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE127", Index: 127, Length: 9999, Format: "LLLLVAR", Validator: "ANS", Tag: `format:"LLLLVAR" length:"9999" validator:"ANS" json:",omitempty"`},
+	{Name: "DE128", Index: 128, Length: 64, Format: "", Validator: "B64", Tag: `format:"" length:"64" validator:"B64" json:",omitempty"`},
+}
+
+// ISO8583Fields returns the specs of the fields of Message, the slice is read-only
+func (m *Message) ISO8583Fields() []FieldSpec {
+	return messageFieldSpecs
+}
+
+// ISO8583Field returns the field i of ISO8583Fields, nil if it is empty
+func (m *Message) ISO8583Field(i int) interface{} {
+	switch i {
+	case 0:
+		if m.DE2 != nil {
+			return m.DE2
+		}
+	case 1:
+		if m.DE3 != nil {
+			return m.DE3
+		}
+	case 2:
+		if m.DE4 != nil {
+			return m.DE4
+		}
+	case 3:
+		if m.DE5 != nil {
+			return m.DE5
+		}
+	case 4:
+		if m.DE6 != nil {
+			return m.DE6
+		}
+	case 5:
+		if m.DE7 != nil {
+			return m.DE7
+		}
+	case 6:
+		if m.DE9 != nil {
+			return m.DE9
+		}
+	case 7:
+		if m.DE10 != nil {
+			return m.DE10
+		}
+	case 8:
+		if m.DE11 != nil {
+			return m.DE11
+		}
+	case 9:
+		if m.DE12 != nil {
+			return m.DE12
+		}
+	case 10:
+		if m.DE13 != nil {
+			return m.DE13
+		}
+	case 11:
+		if m.DE14 != nil {
+			return m.DE14
+		}
+	case 12:
+		if m.DE15 != nil {
+			return m.DE15
+		}
+	case 13:
+		if m.DE16 != nil {
+			return m.DE16
+		}
+	case 14:
+		if m.DE17 != nil {
+			return m.DE17
+		}
+	case 15:
+		if m.DE18 != nil {
+			return m.DE18
+		}
+	case 16:
+		if m.DE19 != nil {
+			return m.DE19
+		}
+	case 17:
+		if m.DE22 != nil {
+			return m.DE22
+		}
+	case 18:
+		if m.DE23 != nil {
+			return m.DE23
+		}
+	case 19:
+		if m.DE24 != nil {
+			return m.DE24
+		}
+	case 20:
+		if m.DE25 != nil {
+			return m.DE25
+		}
+	case 21:
+		if m.DE26 != nil {
+			return m.DE26
+		}
+	case 22:
+		if m.DE28 != nil {
+			return m.DE28
+		}
+	case 23:
+		if m.DE30 != nil {
+			return m.DE30
+		}
+	case 24:
+		if m.DE32 != nil {
+			return m.DE32
+		}
+	case 25:
+		if m.DE33 != nil {
+			return m.DE33
+		}
+	case 26:
+		if m.DE34 != nil {
+			return m.DE34
+		}
+	case 27:
+		if m.DE35 != nil {
+			return m.DE35
+		}
+	case 28:
+		if m.DE37 != nil {
+			return m.DE37
+		}
+	case 29:
+		if m.DE38 != nil {
+			return m.DE38
+		}
+	case 30:
+		if m.DE39 != nil {
+			return m.DE39
+		}
+	case 31:
+		if m.DE41 != nil {
+			return m.DE41
+		}
+	case 32:
+		if m.DE42 != nil {
+			return m.DE42
+		}
+	case 33:
+		if m.DE43 != nil {
+			return m.DE43
+		}
+	case 34:
+		if m.DE46 != nil {
+			return m.DE46
+		}
+	case 35:
+		if m.DE47 != nil {
+			return m.DE47
+		}
+	case 36:
+		if m.DE48 != nil {
+			return m.DE48
+		}
+	case 37:
+		if m.DE49 != nil {
+			return m.DE49
+		}
+	case 38:
+		if m.DE50 != nil {
+			return m.DE50
+		}
+	case 39:
+		if m.DE51 != nil {
+			return m.DE51
+		}
+	case 40:
+		if m.DE52 != nil {
+			return m.DE52
+		}
+	case 41:
+		if m.DE53 != nil {
+			return m.DE53
+		}
+	case 42:
+		if m.DE54 != nil {
+			return m.DE54
+		}
+	case 43:
+		if m.DE56 != nil {
+			return m.DE56
+		}
+	case 44:
+		if m.DE57 != nil {
+			return m.DE57
+		}
+	case 45:
+		if m.DE58 != nil {
+			return m.DE58
+		}
+	case 46:
+		if m.DE59 != nil {
+			return m.DE59
+		}
+	case 47:
+		if m.DE62 != nil {
+			return m.DE62
+		}
+	case 48:
+		if m.DE63 != nil {
+			return m.DE63
+		}
+	case 49:
+		if m.DE64 != nil {
+			return m.DE64
+		}
+	case 50:
+		if m.DE66 != nil {
+			return m.DE66
+		}
+	case 51:
+		if m.DE70 != nil {
+			return m.DE70
+		}
+	case 52:
+		if m.DE72 != nil {
+			return m.DE72
+		}
+	case 53:
+		if m.DE90 != nil {
+			return m.DE90
+		}
+	case 54:
+		if m.DE93 != nil {
+			return m.DE93
+		}
+	case 55:
+		if m.DE94 != nil {
+			return m.DE94
+		}
+	case 56:
+		if m.DE95 != nil {
+			return m.DE95
+		}
+	case 57:
+		if m.DE96 != nil {
+			return m.DE96
+		}
+	case 58:
+		if m.DE100 != nil {
+			return m.DE100
+		}
+	case 59:
+		if m.DE101 != nil {
+			return m.DE101
+		}
+	case 60:
+		if m.DE102 != nil {
+			return m.DE102
+		}
+	case 61:
+		if m.DE103 != nil {
+			return m.DE103
+		}
+	case 62:
+		if m.DE111 != nil {
+			return m.DE111
+		}
+	case 63:
+		if m.DE123 != nil {
+			return m.DE123
+		}
+	case 64:
+		if m.DE124 != nil {
+			return m.DE124
+		}
+	case 65:
+		if m.DE125 != nil {
+			return m.DE125
+		}
+	case 66:
+		if m.DE126 != nil {
+			return m.DE126
+		}
+	case 67:
+		if m.DE127 != nil {
+			return m.DE127
+		}
+	case 68:
+		if m.DE128 != nil {
+			return m.DE128
+		}
+	}
+	return nil
+}
+
+// ISO8583NewField sets the field i of ISO8583Fields to a new empty value
+func (m *Message) ISO8583NewField(i int) interface{} {
+	switch i {
+	case 0:
+		m.DE2 = &N{}
+		return m.DE2
+	case 1:
+		m.DE3 = &N{}
+		return m.DE3
+	case 2:
+		m.DE4 = &N{}
+		return m.DE4
+	case 3:
+		m.DE5 = &N{}
+		return m.DE5
+	case 4:
+		m.DE6 = &N{}
+		return m.DE6
+	case 5:
+		m.DE7 = &DateTime{}
+		return m.DE7
+	case 6:
+		m.DE9 = &N{}
+		return m.DE9
+	case 7:
+		m.DE10 = &N{}
+		return m.DE10
+	case 8:
+		m.DE11 = &N{}
+		return m.DE11
+	case 9:
+		m.DE12 = &DateTime{}
+		return m.DE12
+	case 10:
+		m.DE13 = &DateTime{}
+		return m.DE13
+	case 11:
+		m.DE14 = &DateTime{}
+		return m.DE14
+	case 12:
+		m.DE15 = &DateTime{}
+		return m.DE15
+	case 13:
+		m.DE16 = &DateTime{}
+		return m.DE16
+	case 14:
+		m.DE17 = &DateTime{}
+		return m.DE17
+	case 15:
+		m.DE18 = &N{}
+		return m.DE18
+	case 16:
+		m.DE19 = &N{}
+		return m.DE19
+	case 17:
+		m.DE22 = &AN{}
+		return m.DE22
+	case 18:
+		m.DE23 = &N{}
+		return m.DE23
+	case 19:
+		m.DE24 = &N{}
+		return m.DE24
+	case 20:
+		m.DE25 = &N{}
+		return m.DE25
+	case 21:
+		m.DE26 = &N{}
+		return m.DE26
+	case 22:
+		m.DE28 = &DateTime{}
+		return m.DE28
+	case 23:
+		m.DE30 = &N{}
+		return m.DE30
+	case 24:
+		m.DE32 = &N{}
+		return m.DE32
+	case 25:
+		m.DE33 = &N{}
+		return m.DE33
+	case 26:
+		m.DE34 = &N{}
+		return m.DE34
+	case 27:
+		m.DE35 = &Z{}
+		return m.DE35
+	case 28:
+		m.DE37 = &ANP{}
+		return m.DE37
+	case 29:
+		m.DE38 = &ANP{}
+		return m.DE38
+	case 30:
+		m.DE39 = &AN{}
+		return m.DE39
+	case 31:
+		m.DE41 = &ANS{}
+		return m.DE41
+	case 32:
+		m.DE42 = &ANS{}
+		return m.DE42
+	case 33:
+		m.DE43 = &ANS{}
+		return m.DE43
+	case 34:
+		m.DE46 = &ANS{}
+		return m.DE46
+	case 35:
+		m.DE47 = &ANS{}
+		return m.DE47
+	case 36:
+		m.DE48 = &ANS{}
+		return m.DE48
+	case 37:
+		m.DE49 = &N{}
+		return m.DE49
+	case 38:
+		m.DE50 = &N{}
+		return m.DE50
+	case 39:
+		m.DE51 = &N{}
+		return m.DE51
+	case 40:
+		m.DE52 = &B64{}
+		return m.DE52
+	case 41:
+		m.DE53 = &SecurityControl{}
+		return m.DE53
+	case 42:
+		m.DE54 = &ANS{}
+		return m.DE54
+	case 43:
+		m.DE56 = &N{}
+		return m.DE56
+	case 44:
+		m.DE57 = &N{}
+		return m.DE57
+	case 45:
+		m.DE58 = &N{}
+		return m.DE58
+	case 46:
+		m.DE59 = &ANS{}
+		return m.DE59
+	case 47:
+		m.DE62 = &N{}
+		return m.DE62
+	case 48:
+		m.DE63 = &N{}
+		return m.DE63
+	case 49:
+		m.DE64 = &B64{}
+		return m.DE64
+	case 50:
+		m.DE66 = &ANS{}
+		return m.DE66
+	case 51:
+		m.DE70 = &N{}
+		return m.DE70
+	case 52:
+		m.DE72 = &ANS{}
+		return m.DE72
+	case 53:
+		m.DE90 = &N{}
+		return m.DE90
+	case 54:
+		m.DE93 = &N{}
+		return m.DE93
+	case 55:
+		m.DE94 = &N{}
+		return m.DE94
+	case 56:
+		m.DE95 = &ANS{}
+		return m.DE95
+	case 57:
+		m.DE96 = &ANS{}
+		return m.DE96
+	case 58:
+		m.DE100 = &N{}
+		return m.DE100
+	case 59:
+		m.DE101 = &ANS{}
+		return m.DE101
+	case 60:
+		m.DE102 = &ANS{}
+		return m.DE102
+	case 61:
+		m.DE103 = &ANS{}
+		return m.DE103
+	case 62:
+		m.DE111 = &ANS{}
+		return m.DE111
+	case 63:
+		m.DE123 = &ANS{}
+		return m.DE123
+	case 64:
+		m.DE124 = &ANS{}
+		return m.DE124
+	case 65:
+		m.DE125 = &SubMessage{}
+		return m.DE125
+	case 66:
+		m.DE126 = &ANS{}
+		return m.DE126
+	case 67:
+		m.DE127 = &ANS{}
+		return m.DE127
+	case 68:
+		m.DE128 = &B64{}
+		return m.DE128
+	}
+	return nil
+}
+
+// ISO8583SetSecondaryBitmap sets the secondary bitmap of Message
+func (m *Message) ISO8583SetSecondaryBitmap(bitmap uint64) {
+	m.DE1 = bitmap
+}
+
+var subMessageFieldSpecs = []FieldSpec{
+	{Name: "SE2", Index: 2, Length: 29, Format: "", Validator: "ANS", Tag: `format:"" length:"29" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE3", Index: 3, Length: 5, Format: "", Validator: "ANS", Tag: `format:"" length:"5" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE4", Index: 4, Length: 10, Format: "", Validator: "N", Tag: `format:"" length:"10" validator:"N"  json:",omitempty"`},
+	{Name: "SE5", Index: 5, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE6", Index: 6, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE7", Index: 7, Length: 3, Format: "", Validator: "ANS", Tag: `format:"" length:"3" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE8", Index: 8, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE9", Index: 9, Length: 3, Format: "", Validator: "AN", Tag: `format:"" length:"3" validator:"AN"  json:",omitempty"`},
+	{Name: "SE10", Index: 10, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE11", Index: 11, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE12", Index: 12, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE13", Index: 13, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE14", Index: 14, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE15", Index: 15, Length: 2, Format: "", Validator: "ANS", Tag: `format:"" length:"2" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE16", Index: 16, Length: 2, Format: "", Validator: "ANS", Tag: `format:"" length:"2" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE17", Index: 17, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE18", Index: 18, Length: 16, Format: "", Validator: "ANS", Tag: `format:"" length:"16" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE19", Index: 19, Length: 999, Format: "", Validator: "ANS", Tag: `format:"" length:"999" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE20", Index: 20, Length: 2, Format: "", Validator: "ANS", Tag: `format:"" length:"2" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE21", Index: 21, Length: 194, Format: "", Validator: "ANS", Tag: `format:"" length:"194" validator:"ANS" tagged:"0008:57" json:",omitempty"`},
+	{Name: "SE22", Index: 22, Length: 255, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"255" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE23", Index: 23, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE24", Index: 24, Length: 99, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"99" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE25", Index: 25, Length: 99, Format: "LLVAR", Validator: "ANS", Tag: `format:"LLVAR" length:"99" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE26", Index: 26, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N"  json:",omitempty"`},
+	{Name: "SE27", Index: 27, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE28", Index: 28, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE29", Index: 29, Length: 9, Format: "", Validator: "ANS", Tag: `format:"" length:"9" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE30", Index: 30, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N"  json:",omitempty"`},
+	{Name: "SE31", Index: 31, Length: 255, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"255" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE32", Index: 32, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE33", Index: 33, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE34", Index: 34, Length: 11, Format: "", Validator: "N", Tag: `format:"" length:"11" validator:"N"  json:",omitempty"`},
+	{Name: "SE35", Index: 35, Length: 11, Format: "", Validator: "N", Tag: `format:"" length:"11" validator:"N"  json:",omitempty"`},
+	{Name: "SE36", Index: 36, Length: 15, Format: "", Validator: "ANS", Tag: `format:"" length:"15" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE37", Index: 37, Length: 7, Format: "", Validator: "AN", Tag: `format:"" length:"7" validator:"AN"  json:",omitempty"`},
+	{Name: "SE38", Index: 38, Length: 15, Format: "", Validator: "ANP", Tag: `format:"" length:"15" validator:"ANP"  json:",omitempty"`},
+	{Name: "SE39", Index: 39, Length: 120, Format: "LLLVAR", Validator: "AN", Tag: `format:"LLLVAR" length:"120" validator:"AN"  json:",omitempty"`},
+	{Name: "SE40", Index: 40, Length: 2, Format: "", Validator: "N", Tag: `format:"" length:"2" validator:"N"  json:",omitempty"`},
+	{Name: "SE41", Index: 41, Length: 100, Format: "LLLVAR", Validator: "BN", Tag: `format:"LLLVAR" length:"100" validator:"BN"  json:",omitempty"`},
+	{Name: "SE42", Index: 42, Length: 3, Format: "", Validator: "N", Tag: `format:"" length:"3" validator:"N"  json:",omitempty"`},
+	{Name: "SE43", Index: 43, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE44", Index: 44, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE45", Index: 45, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE46", Index: 46, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE47", Index: 47, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE48", Index: 48, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE49", Index: 49, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE50", Index: 50, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE51", Index: 51, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE52", Index: 52, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE53", Index: 53, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE54", Index: 54, Length: 6, Format: "", Validator: "ANS", Tag: `format:"" length:"6" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE55", Index: 55, Length: 120, Format: "LLLVAR", Validator: "AN", Tag: `format:"LLLVAR" length:"120" validator:"AN"  json:",omitempty"`},
+	{Name: "SE56", Index: 56, Length: 45, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"45" validator:"N"  json:",omitempty"`},
+	{Name: "SE57", Index: 57, Length: 10, Format: "LLVAR", Validator: "AN", Tag: `format:"LLVAR" length:"10" validator:"AN"  json:",omitempty"`},
+	{Name: "SE58", Index: 58, Length: 30, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"30" validator:"N"  json:",omitempty"`},
+	{Name: "SE59", Index: 59, Length: 10, Format: "LLVAR", Validator: "AN", Tag: `format:"LLVAR" length:"10" validator:"AN"  json:",omitempty"`},
+	{Name: "SE60", Index: 60, Length: 1, Format: "", Validator: "N", Tag: `format:"" length:"1" validator:"N"  json:",omitempty"`},
+	{Name: "SE61", Index: 61, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N"  json:",omitempty"`},
+	{Name: "SE62", Index: 62, Length: 1, Format: "", Validator: "N", Tag: `format:"" length:"1" validator:"N"  json:",omitempty"`},
+	{Name: "SE63", Index: 63, Length: 94, Format: "", Validator: "ANS", Tag: `format:"" length:"94" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE64", Index: 64, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE65", Index: 65, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE66", Index: 66, Length: 6, Format: "", Validator: "AN", Tag: `format:"" length:"6" validator:"AN"  json:",omitempty"`},
+	{Name: "SE67", Index: 67, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE68", Index: 68, Length: 40, Format: "", Validator: "ANS", Tag: `format:"" length:"40" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE69", Index: 69, Length: 6, Format: "", Validator: "ANP", Tag: `format:"" length:"6" validator:"ANP"  json:",omitempty"`},
+	{Name: "SE70", Index: 70, Length: 15, Format: "", Validator: "ANS", Tag: `format:"" length:"15" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE71", Index: 71, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE72", Index: 72, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE73", Index: 73, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE74", Index: 74, Length: 999, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"999" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE75", Index: 75, Length: 3, Format: "", Validator: "ANS", Tag: `format:"" length:"3" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE76", Index: 76, Length: 23, Format: "", Validator: "ANS", Tag: `format:"" length:"23" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE77", Index: 77, Length: 12, Format: "", Validator: "ANS", Tag: `format:"" length:"12" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE78", Index: 78, Length: 15, Format: "", Validator: "ANS", Tag: `format:"" length:"15" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE79", Index: 79, Length: 4, Format: "", Validator: "ANS", Tag: `format:"" length:"4" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE80", Index: 80, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE81", Index: 81, Length: 2, Format: "", Validator: "ANS", Tag: `format:"" length:"2" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE82", Index: 82, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE83", Index: 83, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE84", Index: 84, Length: 11, Format: "", Validator: "ANS", Tag: `format:"" length:"11" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE85", Index: 85, Length: 256, Format: "LLLVAR", Validator: "BN", Tag: `format:"LLLVAR" length:"256" validator:"BN"  json:",omitempty"`},
+	{Name: "SE86", Index: 86, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE87", Index: 87, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE88", Index: 88, Length: 1, Format: "", Validator: "ANS", Tag: `format:"" length:"1" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE89", Index: 89, Length: 15, Format: "", Validator: "ANS", Tag: `format:"" length:"15" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE90", Index: 90, Length: 6, Format: "", Validator: "ANS", Tag: `format:"" length:"6" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE91", Index: 91, Length: 255, Format: "LLLVAR", Validator: "ANS", Tag: `format:"LLLVAR" length:"255" validator:"ANS"  json:",omitempty"`},
+	{Name: "SE92", Index: 92, Length: 0, Format: "", Validator: "", Tag: `json:",omitempty"`},
+	{Name: "SE93", Index: 93, Length: 4, Format: "", Validator: "N", Tag: `format:"" length:"4" validator:"N"  json:",omitempty"`},
+	{Name: "SE94", Index: 94, Length: 19, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"19" validator:"N"  json:",omitempty"`},
+	{Name: "SE95", Index: 95, Length: 2, Format: "", Validator: "AN", Tag: `format:"" length:"2" validator:"AN"  json:",omitempty"`},
+	{Name: "SE96", Index: 96, Length: 19, Format: "LLVAR", Validator: "N", Tag: `format:"LLVAR" length:"19" validator:"N"  json:",omitempty"`},
+	{Name: "SE97", Index: 97, Length: 11, Format: "", Validator: "N", Tag: `format:"" length:"11" validator:"N"  json:",omitempty"`},
+	{Name: "SE98", Index: 98, Length: 1, Format: "", Validator: "AN", Tag: `format:"" length:"1" validator:"AN"  json:",omitempty"`},
+	{Name: "SE99", Index: 99, Length: 99, Format: "LLVAR", Validator: "AN", Tag: `format:"LLVAR" length:"99" validator:"AN"  json:",omitempty"`},
+}
+
+// ISO8583Fields returns the specs of the fields of SubMessage, the slice is read-only
+func (m *SubMessage) ISO8583Fields() []FieldSpec {
+	return subMessageFieldSpecs
+}
+
+// ISO8583Field returns the field i of ISO8583Fields, nil if it is empty
+func (m *SubMessage) ISO8583Field(i int) interface{} {
+	switch i {
+	case 0:
+		if m.SE2 != nil {
+			return m.SE2
+		}
+	case 1:
+		if m.SE3 != nil {
+			return m.SE3
+		}
+	case 2:
+		if m.SE4 != nil {
+			return m.SE4
+		}
+	case 3:
+		if m.SE5 != nil {
+			return m.SE5
+		}
+	case 4:
+		if m.SE6 != nil {
+			return m.SE6
+		}
+	case 5:
+		if m.SE7 != nil {
+			return m.SE7
+		}
+	case 6:
+		if m.SE8 != nil {
+			return m.SE8
+		}
+	case 7:
+		if m.SE9 != nil {
+			return m.SE9
+		}
+	case 8:
+		if m.SE10 != nil {
+			return m.SE10
+		}
+	case 9:
+		if m.SE11 != nil {
+			return m.SE11
+		}
+	case 10:
+		if m.SE12 != nil {
+			return m.SE12
+		}
+	case 11:
+		if m.SE13 != nil {
+			return m.SE13
+		}
+	case 12:
+		if m.SE14 != nil {
+			return m.SE14
+		}
+	case 13:
+		if m.SE15 != nil {
+			return m.SE15
+		}
+	case 14:
+		if m.SE16 != nil {
+			return m.SE16
+		}
+	case 15:
+		if m.SE17 != nil {
+			return m.SE17
+		}
+	case 16:
+		if m.SE18 != nil {
+			return m.SE18
+		}
+	case 17:
+		if m.SE19 != nil {
+			return m.SE19
+		}
+	case 18:
+		if m.SE20 != nil {
+			return m.SE20
+		}
+	case 19:
+		if m.SE21 != nil {
+			return m.SE21
+		}
+	case 20:
+		if m.SE22 != nil {
+			return m.SE22
+		}
+	case 21:
+		if m.SE23 != nil {
+			return m.SE23
+		}
+	case 22:
+		if m.SE24 != nil {
+			return m.SE24
+		}
+	case 23:
+		if m.SE25 != nil {
+			return m.SE25
+		}
+	case 24:
+		if m.SE26 != nil {
+			return m.SE26
+		}
+	case 25:
+		if m.SE27 != nil {
+			return m.SE27
+		}
+	case 26:
+		if m.SE28 != nil {
+			return m.SE28
+		}
+	case 27:
+		if m.SE29 != nil {
+			return m.SE29
+		}
+	case 28:
+		if m.SE30 != nil {
+			return m.SE30
+		}
+	case 29:
+		if m.SE31 != nil {
+			return m.SE31
+		}
+	case 30:
+		if m.SE32 != nil {
+			return m.SE32
+		}
+	case 31:
+		if m.SE33 != nil {
+			return m.SE33
+		}
+	case 32:
+		if m.SE34 != nil {
+			return m.SE34
+		}
+	case 33:
+		if m.SE35 != nil {
+			return m.SE35
+		}
+	case 34:
+		if m.SE36 != nil {
+			return m.SE36
+		}
+	case 35:
+		if m.SE37 != nil {
+			return m.SE37
+		}
+	case 36:
+		if m.SE38 != nil {
+			return m.SE38
+		}
+	case 37:
+		if m.SE39 != nil {
+			return m.SE39
+		}
+	case 38:
+		if m.SE40 != nil {
+			return m.SE40
+		}
+	case 39:
+		if m.SE41 != nil {
+			return m.SE41
+		}
+	case 40:
+		if m.SE42 != nil {
+			return m.SE42
+		}
+	case 41:
+		if m.SE43 != nil {
+			return m.SE43
+		}
+	case 42:
+		if m.SE44 != nil {
+			return m.SE44
+		}
+	case 43:
+		if m.SE45 != nil {
+			return m.SE45
+		}
+	case 44:
+		if m.SE46 != nil {
+			return m.SE46
+		}
+	case 45:
+		if m.SE47 != nil {
+			return m.SE47
+		}
+	case 46:
+		if m.SE48 != nil {
+			return m.SE48
+		}
+	case 47:
+		if m.SE49 != nil {
+			return m.SE49
+		}
+	case 48:
+		if m.SE50 != nil {
+			return m.SE50
+		}
+	case 49:
+		if m.SE51 != nil {
+			return m.SE51
+		}
+	case 50:
+		if m.SE52 != nil {
+			return m.SE52
+		}
+	case 51:
+		if m.SE53 != nil {
+			return m.SE53
+		}
+	case 52:
+		if m.SE54 != nil {
+			return m.SE54
+		}
+	case 53:
+		if m.SE55 != nil {
+			return m.SE55
+		}
+	case 54:
+		if m.SE56 != nil {
+			return m.SE56
+		}
+	case 55:
+		if m.SE57 != nil {
+			return m.SE57
+		}
+	case 56:
+		if m.SE58 != nil {
+			return m.SE58
+		}
+	case 57:
+		if m.SE59 != nil {
+			return m.SE59
+		}
+	case 58:
+		if m.SE60 != nil {
+			return m.SE60
+		}
+	case 59:
+		if m.SE61 != nil {
+			return m.SE61
+		}
+	case 60:
+		if m.SE62 != nil {
+			return m.SE62
+		}
+	case 61:
+		if m.SE63 != nil {
+			return m.SE63
+		}
+	case 62:
+		if m.SE64 != nil {
+			return m.SE64
+		}
+	case 63:
+		if m.SE65 != nil {
+			return m.SE65
+		}
+	case 64:
+		if m.SE66 != nil {
+			return m.SE66
+		}
+	case 65:
+		if m.SE67 != nil {
+			return m.SE67
+		}
+	case 66:
+		if m.SE68 != nil {
+			return m.SE68
+		}
+	case 67:
+		if m.SE69 != nil {
+			return m.SE69
+		}
+	case 68:
+		if m.SE70 != nil {
+			return m.SE70
+		}
+	case 69:
+		if m.SE71 != nil {
+			return m.SE71
+		}
+	case 70:
+		if m.SE72 != nil {
+			return m.SE72
+		}
+	case 71:
+		if m.SE73 != nil {
+			return m.SE73
+		}
+	case 72:
+		if m.SE74 != nil {
+			return m.SE74
+		}
+	case 73:
+		if m.SE75 != nil {
+			return m.SE75
+		}
+	case 74:
+		if m.SE76 != nil {
+			return m.SE76
+		}
+	case 75:
+		if m.SE77 != nil {
+			return m.SE77
+		}
+	case 76:
+		if m.SE78 != nil {
+			return m.SE78
+		}
+	case 77:
+		if m.SE79 != nil {
+			return m.SE79
+		}
+	case 78:
+		if m.SE80 != nil {
+			return m.SE80
+		}
+	case 79:
+		if m.SE81 != nil {
+			return m.SE81
+		}
+	case 80:
+		if m.SE82 != nil {
+			return m.SE82
+		}
+	case 81:
+		if m.SE83 != nil {
+			return m.SE83
+		}
+	case 82:
+		if m.SE84 != nil {
+			return m.SE84
+		}
+	case 83:
+		if m.SE85 != nil {
+			return m.SE85
+		}
+	case 84:
+		if m.SE86 != nil {
+			return m.SE86
+		}
+	case 85:
+		if m.SE87 != nil {
+			return m.SE87
+		}
+	case 86:
+		if m.SE88 != nil {
+			return m.SE88
+		}
+	case 87:
+		if m.SE89 != nil {
+			return m.SE89
+		}
+	case 88:
+		if m.SE90 != nil {
+			return m.SE90
+		}
+	case 89:
+		if m.SE91 != nil {
+			return m.SE91
+		}
+	case 90:
+		if m.SE92 != nil {
+			return m.SE92
+		}
+	case 91:
+		if m.SE93 != nil {
+			return m.SE93
+		}
+	case 92:
+		if m.SE94 != nil {
+			return m.SE94
+		}
+	case 93:
+		if m.SE95 != nil {
+			return m.SE95
+		}
+	case 94:
+		if m.SE96 != nil {
+			return m.SE96
+		}
+	case 95:
+		if m.SE97 != nil {
+			return m.SE97
+		}
+	case 96:
+		if m.SE98 != nil {
+			return m.SE98
+		}
+	case 97:
+		if m.SE99 != nil {
+			return m.SE99
+		}
+	}
+	return nil
+}
+
+// ISO8583NewField sets the field i of ISO8583Fields to a new empty value
+func (m *SubMessage) ISO8583NewField(i int) interface{} {
+	switch i {
+	case 0:
+		m.SE2 = &ANS{}
+		return m.SE2
+	case 1:
+		m.SE3 = &ANS{}
+		return m.SE3
+	case 2:
+		m.SE4 = &N{}
+		return m.SE4
+	case 3:
+		m.SE5 = &Reserved{}
+		return m.SE5
+	case 4:
+		m.SE6 = &Reserved{}
+		return m.SE6
+	case 5:
+		m.SE7 = &ANS{}
+		return m.SE7
+	case 6:
+		m.SE8 = &ANS{}
+		return m.SE8
+	case 7:
+		m.SE9 = &AN{}
+		return m.SE9
+	case 8:
+		m.SE10 = &Reserved{}
+		return m.SE10
+	case 9:
+		m.SE11 = &AN{}
+		return m.SE11
+	case 10:
+		m.SE12 = &Reserved{}
+		return m.SE12
+	case 11:
+		m.SE13 = &Reserved{}
+		return m.SE13
+	case 12:
+		m.SE14 = &Reserved{}
+		return m.SE14
+	case 13:
+		m.SE15 = &ANS{}
+		return m.SE15
+	case 14:
+		m.SE16 = &ANS{}
+		return m.SE16
+	case 15:
+		m.SE17 = &Reserved{}
+		return m.SE17
+	case 16:
+		m.SE18 = &ANS{}
+		return m.SE18
+	case 17:
+		m.SE19 = &ANS{}
+		return m.SE19
+	case 18:
+		m.SE20 = &ANS{}
+		return m.SE20
+	case 19:
+		m.SE21 = &TaggedANS{}
+		return m.SE21
+	case 20:
+		m.SE22 = &ANS{}
+		return m.SE22
+	case 21:
+		m.SE23 = &Reserved{}
+		return m.SE23
+	case 22:
+		m.SE24 = &ANS{}
+		return m.SE24
+	case 23:
+		m.SE25 = &ANS{}
+		return m.SE25
+	case 24:
+		m.SE26 = &N{}
+		return m.SE26
+	case 25:
+		m.SE27 = &ANS{}
+		return m.SE27
+	case 26:
+		m.SE28 = &Reserved{}
+		return m.SE28
+	case 27:
+		m.SE29 = &ANS{}
+		return m.SE29
+	case 28:
+		m.SE30 = &N{}
+		return m.SE30
+	case 29:
+		m.SE31 = &ANS{}
+		return m.SE31
+	case 30:
+		m.SE32 = &Reserved{}
+		return m.SE32
+	case 31:
+		m.SE33 = &Reserved{}
+		return m.SE33
+	case 32:
+		m.SE34 = &N{}
+		return m.SE34
+	case 33:
+		m.SE35 = &N{}
+		return m.SE35
+	case 34:
+		m.SE36 = &ANS{}
+		return m.SE36
+	case 35:
+		m.SE37 = &AN{}
+		return m.SE37
+	case 36:
+		m.SE38 = &ANP{}
+		return m.SE38
+	case 37:
+		m.SE39 = &AN{}
+		return m.SE39
+	case 38:
+		m.SE40 = &N{}
+		return m.SE40
+	case 39:
+		m.SE41 = &BN{}
+		return m.SE41
+	case 40:
+		m.SE42 = &N{}
+		return m.SE42
+	case 41:
+		m.SE43 = &Reserved{}
+		return m.SE43
+	case 42:
+		m.SE44 = &Reserved{}
+		return m.SE44
+	case 43:
+		m.SE45 = &Reserved{}
+		return m.SE45
+	case 44:
+		m.SE46 = &Reserved{}
+		return m.SE46
+	case 45:
+		m.SE47 = &Reserved{}
+		return m.SE47
+	case 46:
+		m.SE48 = &Reserved{}
+		return m.SE48
+	case 47:
+		m.SE49 = &Reserved{}
+		return m.SE49
+	case 48:
+		m.SE50 = &Reserved{}
+		return m.SE50
+	case 49:
+		m.SE51 = &Reserved{}
+		return m.SE51
+	case 50:
+		m.SE52 = &Reserved{}
+		return m.SE52
+	case 51:
+		m.SE53 = &Reserved{}
+		return m.SE53
+	case 52:
+		m.SE54 = &ANS{}
+		return m.SE54
+	case 53:
+		m.SE55 = &AN{}
+		return m.SE55
+	case 54:
+		m.SE56 = &N{}
+		return m.SE56
+	case 55:
+		m.SE57 = &AN{}
+		return m.SE57
+	case 56:
+		m.SE58 = &N{}
+		return m.SE58
+	case 57:
+		m.SE59 = &AN{}
+		return m.SE59
+	case 58:
+		m.SE60 = &N{}
+		return m.SE60
+	case 59:
+		m.SE61 = &N{}
+		return m.SE61
+	case 60:
+		m.SE62 = &N{}
+		return m.SE62
+	case 61:
+		m.SE63 = &ANS{}
+		return m.SE63
+	case 62:
+		m.SE64 = &AN{}
+		return m.SE64
+	case 63:
+		m.SE65 = &AN{}
+		return m.SE65
+	case 64:
+		m.SE66 = &AN{}
+		return m.SE66
+	case 65:
+		m.SE67 = &AN{}
+		return m.SE67
+	case 66:
+		m.SE68 = &ANS{}
+		return m.SE68
+	case 67:
+		m.SE69 = &ANP{}
+		return m.SE69
+	case 68:
+		m.SE70 = &ANS{}
+		return m.SE70
+	case 69:
+		m.SE71 = &ANS{}
+		return m.SE71
+	case 70:
+		m.SE72 = &ANS{}
+		return m.SE72
+	case 71:
+		m.SE73 = &ANS{}
+		return m.SE73
+	case 72:
+		m.SE74 = &ANS{}
+		return m.SE74
+	case 73:
+		m.SE75 = &ANS{}
+		return m.SE75
+	case 74:
+		m.SE76 = &ANS{}
+		return m.SE76
+	case 75:
+		m.SE77 = &ANS{}
+		return m.SE77
+	case 76:
+		m.SE78 = &ANS{}
+		return m.SE78
+	case 77:
+		m.SE79 = &ANS{}
+		return m.SE79
+	case 78:
+		m.SE80 = &ANS{}
+		return m.SE80
+	case 79:
+		m.SE81 = &ANS{}
+		return m.SE81
+	case 80:
+		m.SE82 = &ANS{}
+		return m.SE82
+	case 81:
+		m.SE83 = &AN{}
+		return m.SE83
+	case 82:
+		m.SE84 = &ANS{}
+		return m.SE84
+	case 83:
+		m.SE85 = &BN{}
+		return m.SE85
+	case 84:
+		m.SE86 = &ANS{}
+		return m.SE86
+	case 85:
+		m.SE87 = &Reserved{}
+		return m.SE87
+	case 86:
+		m.SE88 = &ANS{}
+		return m.SE88
+	case 87:
+		m.SE89 = &ANS{}
+		return m.SE89
+	case 88:
+		m.SE90 = &ANS{}
+		return m.SE90
+	case 89:
+		m.SE91 = &ANS{}
+		return m.SE91
+	case 90:
+		m.SE92 = &Reserved{}
+		return m.SE92
+	case 91:
+		m.SE93 = &N{}
+		return m.SE93
+	case 92:
+		m.SE94 = &N{}
+		return m.SE94
+	case 93:
+		m.SE95 = &AN{}
+		return m.SE95
+	case 94:
+		m.SE96 = &N{}
+		return m.SE96
+	case 95:
+		m.SE97 = &N{}
+		return m.SE97
+	case 96:
+		m.SE98 = &AN{}
+		return m.SE98
+	case 97:
+		m.SE99 = &AN{}
+		return m.SE99
+	}
+	return nil
+}
+
+// ISO8583SetSecondaryBitmap sets the secondary bitmap of SubMessage
+func (m *SubMessage) ISO8583SetSecondaryBitmap(bitmap uint64) {
+	m.SE1 = bitmap
+}
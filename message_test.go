@@ -2,6 +2,7 @@ package iso8583
 
 import (
 	"bytes"
+	"io/ioutil"
 	"reflect"
 	"testing"
 )
@@ -24,12 +25,12 @@ func TestNewMessageWithSecondaryBitmap(t *testing.T) {
 		DE2:   NewNumeric("4846811212"),        // Primary Account Number
 		DE3:   NewNumeric("201234"),            // Processing Code
 		DE4:   NewNumeric("10000000"),          // Amount, Transaction
-		DE7:   NewNumeric("1107221800"),        // Date And Time, Transmission
+		DE7:   NewDateTime("1107221800"),       // Date And Time, Transmission
 		DE11:  NewNumeric("000001"),            // Systems Trace Audit Number
-		DE12:  NewNumeric("161204171926"),      // Date And Time, Local Transaction
+		DE12:  NewDateTime("161204171926"),     // Date And Time, Local Transaction
 		DE22:  NewAlphanumeric("FABCDE123ABD"), // Point Of Service Data Code
 		DE32:  NewNumeric("414243"),            // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),               // Action Code
+		DE39:  NewAlphanumeric("000"),          // Action Code
 		DE41:  NewANS("termid12"),              // Card Acceptor Terminal Identification
 		DE43:  NewANS("Community1"),            // Card Acceptor Name/Location
 		DE102: NewANS("12341234234"),           // Account Identification 1
@@ -56,9 +57,9 @@ func TestBalanceInquiryFromAnATM(t *testing.T) {
 	m := &Message{
 		DE2:  NewNumeric("00000000000000"),                                         // Primary Account Number
 		DE3:  NewNumeric("312000"),                                                 // Processing Code
-		DE7:  NewNumeric("0108204503"),                                             // Date And Time, Transmission
+		DE7:  NewDateTime("0108204503"),                                            // Date And Time, Transmission
 		DE11: NewNumeric("007530"),                                                 // Systems Trace Audit Number
-		DE12: NewNumeric("950108144500"),                                           // Date And Time, Local Transaction
+		DE12: NewDateTime("950108144500"),                                          // Date And Time, Local Transaction
 		DE18: NewNumeric("6011"),                                                   // Merchant Type
 		DE22: NewAlphanumeric("21120121014C"),                                      // Point Of Service Data Code
 		DE24: NewNumeric("100"),                                                    // Function Code
@@ -97,11 +98,11 @@ func TestBalanceInquiryResponse(t *testing.T) {
 	m := &Message{
 		DE2:   NewNumeric("00000000000000"),                       // Primary Account Number
 		DE3:   NewNumeric("312000"),                               // Processing Code
-		DE7:   NewNumeric("0108204506"),                           // Date And Time, Transmission
+		DE7:   NewDateTime("0108204506"),                          // Date And Time, Transmission
 		DE11:  NewNumeric("7530"),                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950108144500"),                         // Date And Time, Local Transaction
+		DE12:  NewDateTime("950108144500"),                        // Date And Time, Local Transaction
 		DE32:  NewNumeric("10111111118"),                          // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),                                  // Action Code
+		DE39:  NewAlphanumeric("000"),                             // Action Code
 		DE54:  NewANS("2001840C0000007000002002840C000000600000"), // Amounts, Additional
 		DE102: NewANS("00000012456184"),                           // Account Identification 1
 	}
@@ -134,14 +135,14 @@ func TestPurchaseWithCashBackRequest(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("20000"),                                                  // Amount, Transaction
 		DE5:   NewNumeric("20000"),                                                  // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("30402"),                                                  // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
@@ -186,20 +187,20 @@ func TestPurchaseWithCashBackPartialApprovalToAquirer(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("15000"),                                                  // Amount, Transaction
 		DE5:   NewNumeric("15000"),                                                  // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE30:  NewNumeric("000000020000000000020000"),                               // Amounts, Original
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
 		DE37:  NewANP("012401"),                                                     // Retrieval Reference Number
-		DE39:  NewNumeric("002"),                                                    // Action code
+		DE39:  NewAlphanumeric("002"),                                               // Action code
 		DE41:  NewANS("NJ020111"),                                                   // Card Acceptor Terminal Identification
 		DE42:  NewANS("73420"),                                                      // Card Acceptor Identification Code
 		DE43:  NewANS("NJ NEWARK          123 PINE STREET      USWRIGHT AID DRUGS"), // Card Acceptor Name/Location
@@ -240,20 +241,20 @@ func TestPurchaseWithCashBackPartialApprovalFromIssuer(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("000000000000"),                                           // Amount, Transaction
 		DE5:   NewNumeric("000000000000"),                                           // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE30:  NewNumeric("000000020000000000020000"),                               // Amounts, Original
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
 		DE37:  NewANP("012401"),                                                     // Retrieval Reference Number
-		DE39:  NewNumeric("002"),                                                    // Action code
+		DE39:  NewAlphanumeric("002"),                                               // Action code
 		DE41:  NewANS("NJ020111"),                                                   // Card Acceptor Terminal Identification
 		DE42:  NewANS("73420"),                                                      // Card Acceptor Identification Code
 		DE43:  NewANS("NJ NEWARK          123 PINE STREET      USWRIGHT AID DRUGS"), // Card Acceptor Name/Location
@@ -294,11 +295,11 @@ func TestPurchaseAuthorization(t *testing.T) {
 		DE2:   NewNumeric("0000000000000000"), // Primary Account Number
 		DE3:   NewNumeric("092000"),           // Processing Code
 		DE4:   NewNumeric("20000"),            // Amount, Transaction
-		DE7:   NewNumeric("0123205007"),       // Date And Time, Transmission
+		DE7:   NewDateTime("0123205007"),      // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),           // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),     // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),    // Date And Time, Local Transaction
 		DE32:  NewNumeric("10076401251"),      // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),              // Action code
+		DE39:  NewAlphanumeric("000"),         // Action code
 		DE41:  NewANS("NJ020111"),             // Card Acceptor Terminal Identification
 		DE49:  NewNumeric("840"),              // Currency Code, Transaction
 		DE54:  NewANS(""),                     // Amounts, Additional
@@ -334,14 +335,14 @@ func TestReversalAdvice(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("20000"),                                                  // Amount, Transaction
 		DE5:   NewNumeric("20000"),                                                  // Amount, Reconciliation
-		DE7:   NewNumeric("0123205206"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205206"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("075809"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("400"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("5421224887288158=99120010109"),                        // Track 2 Data
@@ -386,12 +387,12 @@ func TestReversalAdviceResponse(t *testing.T) {
 		DE2:  NewNumeric("000000000000000000"), // Primary Account Number
 		DE3:  NewNumeric("092000"),             // Processing Code
 		DE4:  NewNumeric("20000"),              // Amount, Transaction
-		DE7:  NewNumeric("0123210209"),         // Date And Time, Transmission
+		DE7:  NewDateTime("0123210209"),        // Date And Time, Transmission
 		DE11: NewNumeric("075809"),             // Systems Trace Audit Number
-		DE12: NewNumeric("950123210135"),       // Date And Time, Local Transaction
+		DE12: NewDateTime("950123210135"),      // Date And Time, Local Transaction
 		DE32: NewNumeric("10076401251"),        // Acquiring Institution Identification Code
 		DE33: NewNumeric("10222222226"),        // Forwarding Institution Identification Code
-		DE39: NewNumeric("400"),                // Action code
+		DE39: NewAlphanumeric("400"),           // Action code
 	}
 	m.encoder = ASCII
 	m.Mti = "1430"
@@ -419,9 +420,9 @@ func TestReversalAdviceResponse(t *testing.T) {
 
 func TestNetworkManagementRequest(t *testing.T) {
 	m := &Message{
-		DE7:  NewNumeric("0124081908"),   // Date And Time, Transmission
+		DE7:  NewDateTime("0124081908"),  // Date And Time, Transmission
 		DE11: NewNumeric("031972"),       // Systems Trace Audit Number
-		DE12: NewNumeric("950124081904"), // Date And Time, Local Transaction
+		DE12: NewDateTime("950124081904"), // Date And Time, Local Transaction
 		DE24: NewNumeric("801"),          // Function Code
 		DE93: NewNumeric("00000000001"),  // Transaction Destination Institution Identification Code
 		DE94: NewNumeric("00000000002"),  // Transaction Originator Institution Identification Code
@@ -452,11 +453,11 @@ func TestNetworkManagementRequest(t *testing.T) {
 
 func TestNetworkManagementRequestResponse(t *testing.T) {
 	m := &Message{
-		DE7:  NewNumeric("0124081920"),   // Date And Time, Transmission
+		DE7:  NewDateTime("0124081920"),  // Date And Time, Transmission
 		DE11: NewNumeric("031972"),       // Systems Trace Audit Number
-		DE12: NewNumeric("950124081904"), // Date And Time, Local Transaction
+		DE12: NewDateTime("950124081904"), // Date And Time, Local Transaction
 		DE24: NewNumeric("801"),          // Function Code
-		DE39: NewNumeric("800"),          // Action Code
+		DE39: NewAlphanumeric("800"),     // Action Code
 		DE93: NewNumeric("10222222226"),  // Transaction Destination Institution Identification Code
 		DE94: NewNumeric("10999999992"),  // Transaction Originator Institution Identification Code
 	}
@@ -492,12 +493,12 @@ func TestMessageDecode(t *testing.T) {
 		DE2:   NewNumeric("4846811212"),        // Primary Account Number
 		DE3:   NewNumeric("201234"),            // Processing Code
 		DE4:   NewNumeric("000010000000"),      // Amount, Transaction
-		DE7:   NewNumeric("1107221800"),        // Date And Time, Transmission
+		DE7:   NewDateTime("1107221800"),       // Date And Time, Transmission
 		DE11:  NewNumeric("000001"),            // Systems Trace Audit Number
-		DE12:  NewNumeric("161204171926"),      // Date And Time, Local Transaction
+		DE12:  NewDateTime("161204171926"),     // Date And Time, Local Transaction
 		DE22:  NewAlphanumeric("FABCDE123ABD"), // Point Of Service Data Code
 		DE32:  NewNumeric("414243"),            // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),               // Action Code
+		DE39:  NewAlphanumeric("000"),          // Action Code
 		DE41:  NewANS("termid12"),              // Card Acceptor Terminal Identification
 		DE43:  NewANS("Community1"),            // Card Acceptor Name/Location
 		DE102: NewANS("12341234234"),           // Account Identification 1
@@ -528,9 +529,9 @@ func TestDecodeBalanceInquiryFromAnATM(t *testing.T) {
 	expectedMsg := &Message{
 		DE2:  NewNumeric("00000000000000"),                                         // Primary Account Number
 		DE3:  NewNumeric("312000"),                                                 // Processing Code
-		DE7:  NewNumeric("0108204503"),                                             // Date And Time, Transmission
+		DE7:  NewDateTime("0108204503"),                                            // Date And Time, Transmission
 		DE11: NewNumeric("007530"),                                                 // Systems Trace Audit Number
-		DE12: NewNumeric("950108144500"),                                           // Date And Time, Local Transaction
+		DE12: NewDateTime("950108144500"),                                          // Date And Time, Local Transaction
 		DE18: NewNumeric("6011"),                                                   // Merchant Type
 		DE22: NewAlphanumeric("21120121014C"),                                      // Point Of Service Data Code
 		DE24: NewNumeric("100"),                                                    // Function Code
@@ -568,11 +569,11 @@ func TestDecodeBalanceInquiryResponse(t *testing.T) {
 	expectedMsg := &Message{
 		DE2:   NewNumeric("00000000000000"),                       // Primary Account Number
 		DE3:   NewNumeric("312000"),                               // Processing Code
-		DE7:   NewNumeric("0108204506"),                           // Date And Time, Transmission
+		DE7:   NewDateTime("0108204506"),                          // Date And Time, Transmission
 		DE11:  NewNumeric("007530"),                               // Systems Trace Audit Number
-		DE12:  NewNumeric("950108144500"),                         // Date And Time, Local Transaction
+		DE12:  NewDateTime("950108144500"),                        // Date And Time, Local Transaction
 		DE32:  NewNumeric("10111111118"),                          // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),                                  // Action Code
+		DE39:  NewAlphanumeric("000"),                             // Action Code
 		DE54:  NewANS("2001840C0000007000002002840C000000600000"), // Amounts, Additional
 		DE102: NewANS("00000012456184"),                           // Account Identification 1
 	}
@@ -604,14 +605,14 @@ func TestDecodePurchaseWithCashBackRequest(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("000000020000"),                                           // Amount, Transaction
 		DE5:   NewNumeric("000000020000"),                                           // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
@@ -654,20 +655,20 @@ func TestDecodePurchaseWithCashBackPartialApprovalToAquirer(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("000000015000"),                                           // Amount, Transaction
 		DE5:   NewNumeric("000000015000"),                                           // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE30:  NewNumeric("000000020000000000020000"),                               // Amounts, Original
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
 		DE37:  NewANP("012401"),                                                     // Retrieval Reference Number
-		DE39:  NewNumeric("002"),                                                    // Action code
+		DE39:  NewAlphanumeric("002"),                                               // Action code
 		DE41:  NewANS("NJ020111"),                                                   // Card Acceptor Terminal Identification
 		DE42:  NewANS("73420"),                                                      // Card Acceptor Identification Code
 		DE43:  NewANS("NJ NEWARK          123 PINE STREET      USWRIGHT AID DRUGS"), // Card Acceptor Name/Location
@@ -705,20 +706,20 @@ func TestDecodePurchaseWithCashBackPartialApprovalFromIssuer(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("000000000000"),                                           // Amount, Transaction
 		DE5:   NewNumeric("000000000000"),                                           // Amount, Reconciliation
-		DE7:   NewNumeric("0123205001"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205001"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("200"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE30:  NewNumeric("000000020000000000020000"),                               // Amounts, Original
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("54212248887288158=99120010109"),                       // Track 2 Data
 		DE37:  NewANP("012401"),                                                     // Retrieval Reference Number
-		DE39:  NewNumeric("002"),                                                    // Action code
+		DE39:  NewAlphanumeric("002"),                                               // Action code
 		DE41:  NewANS("NJ020111"),                                                   // Card Acceptor Terminal Identification
 		DE42:  NewANS("73420"),                                                      // Card Acceptor Identification Code
 		DE43:  NewANS("NJ NEWARK          123 PINE STREET      USWRIGHT AID DRUGS"), // Card Acceptor Name/Location
@@ -756,11 +757,11 @@ func TestDecodePurchaseAuthorization(t *testing.T) {
 		DE2:   NewNumeric("0000000000000000"), // Primary Account Number
 		DE3:   NewNumeric("092000"),           // Processing Code
 		DE4:   NewNumeric("000000020000"),     // Amount, Transaction
-		DE7:   NewNumeric("0123205007"),       // Date And Time, Transmission
+		DE7:   NewDateTime("0123205007"),      // Date And Time, Transmission
 		DE11:  NewNumeric("030402"),           // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),     // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),    // Date And Time, Local Transaction
 		DE32:  NewNumeric("10076401251"),      // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),              // Action code
+		DE39:  NewAlphanumeric("000"),         // Action code
 		DE41:  NewANS("NJ020111"),             // Card Acceptor Terminal Identification
 		DE49:  NewNumeric("840"),              // Currency Code, Transaction
 		DE54:  NewANS(""),                     // Amounts, Additional
@@ -794,14 +795,14 @@ func TestDecodeReversalAdvice(t *testing.T) {
 		DE3:   NewNumeric("092000"),                                                 // Processing Code
 		DE4:   NewNumeric("000000020000"),                                           // Amount, Transaction
 		DE5:   NewNumeric("000000020000"),                                           // Amount, Reconciliation
-		DE7:   NewNumeric("0123205206"),                                             // Date And Time, Transmission
+		DE7:   NewDateTime("0123205206"),                                            // Date And Time, Transmission
 		DE11:  NewNumeric("075809"),                                                 // Systems Trace Audit Number
-		DE12:  NewNumeric("950123154952"),                                           // Date And Time, Local Transaction
+		DE12:  NewDateTime("950123154952"),                                          // Date And Time, Local Transaction
 		DE18:  NewNumeric("5912"),                                                   // Merchant Type
 		DE22:  NewAlphanumeric("21010121314C"),                                      // Point of Service Data Code
 		DE24:  NewNumeric("400"),                                                    // Function Code
 		DE26:  NewNumeric("5912"),                                                   // Card Acceptor Business Code
-		DE28:  NewNumeric("950123"),                                                 // Date, Reconciliation
+		DE28:  NewDateTime("950123"),                                                // Date, Reconciliation
 		DE32:  NewNumeric("10076401251"),                                            // Acquiring Institution Identification Code
 		DE33:  NewNumeric("10111111118"),                                            // Forwarding Institution Identification Code
 		DE35:  NewTrack2Code("5421224887288158=99120010109"),                        // Track 2 Data
@@ -844,12 +845,12 @@ func TestDecodeReversalAdviceResponse(t *testing.T) {
 		DE2:  NewNumeric("000000000000000000"), // Primary Account Number
 		DE3:  NewNumeric("092000"),             // Processing Code
 		DE4:  NewNumeric("000000020000"),       // Amount, Transaction
-		DE7:  NewNumeric("0123210209"),         // Date And Time, Transmission
+		DE7:  NewDateTime("0123210209"),        // Date And Time, Transmission
 		DE11: NewNumeric("075809"),             // Systems Trace Audit Number
-		DE12: NewNumeric("950123210135"),       // Date And Time, Local Transaction
+		DE12: NewDateTime("950123210135"),      // Date And Time, Local Transaction
 		DE32: NewNumeric("10076401251"),        // Acquiring Institution Identification Code
 		DE33: NewNumeric("10222222226"),        // Forwarding Institution Identification Code
-		DE39: NewNumeric("400"),                // Action code
+		DE39: NewAlphanumeric("400"),           // Action code
 	}
 	expectedMsg.Mti = "1430"
 	expectedMsg.encoder = ASCII
@@ -875,9 +876,9 @@ func TestDecodeNetworkManagementRequest(t *testing.T) {
 	}
 
 	expectedMsg := &Message{
-		DE7:  NewNumeric("0124081908"),   // Date And Time, Transmission
+		DE7:  NewDateTime("0124081908"),  // Date And Time, Transmission
 		DE11: NewNumeric("031972"),       // Systems Trace Audit Number
-		DE12: NewNumeric("950124081904"), // Date And Time, Local Transaction
+		DE12: NewDateTime("950124081904"), // Date And Time, Local Transaction
 		DE24: NewNumeric("801"),          // Function Code
 		DE93: NewNumeric("00000000001"),  // Transaction Destination Institution Identification Code
 		DE94: NewNumeric("00000000002"),  // Transaction Originator Institution Identification Code
@@ -906,11 +907,11 @@ func TestDecodeNetworkManagementRequestResponse(t *testing.T) {
 	}
 
 	expectedMsg := &Message{
-		DE7:  NewNumeric("0124081920"),   // Date And Time, Transmission
+		DE7:  NewDateTime("0124081920"),  // Date And Time, Transmission
 		DE11: NewNumeric("031972"),       // Systems Trace Audit Number
-		DE12: NewNumeric("950124081904"), // Date And Time, Local Transaction
+		DE12: NewDateTime("950124081904"), // Date And Time, Local Transaction
 		DE24: NewNumeric("801"),          // Function Code
-		DE39: NewNumeric("800"),          // Action Code
+		DE39: NewAlphanumeric("800"),     // Action Code
 		DE93: NewNumeric("10222222226"),  // Transaction Destination Institution Identification Code
 		DE94: NewNumeric("10999999992"),  // Transaction Originator Institution Identification Code
 	}
@@ -927,3 +928,246 @@ func TestDecodeNetworkManagementRequestResponse(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestLenientDecode(t *testing.T) {
+	msgToDecode := "1200F230040102A0000000000000040000001048468112AB2012340000100000001307221800000001161204171926FABCDE123ABD06414243000termid1210Community11112341234234"
+	m := &Message{}
+	if err := m.Decode([]byte(msgToDecode)); err == nil {
+		t.Error("expecting error in strict decode")
+	}
+
+	m = &Message{}
+	m.SetLenient(true)
+	if err := m.Decode([]byte(msgToDecode)); err != nil {
+		t.Fatal(err)
+	}
+	errs := m.DecodeErrors()
+	if !reflect.DeepEqual(errs.Fields(), []string{"DE2", "DE7"}) {
+		t.Fatalf("invalid fields should be [DE2 DE7], instead of %v", errs.Fields())
+	}
+	if string(errs[0].Raw) != "1048468112AB" {
+		t.Errorf("raw DE2 should be 1048468112AB, instead of %s", errs[0].Raw)
+	}
+	if string(errs[1].Raw) != "1307221800" {
+		t.Errorf("raw DE7 should be 1307221800, instead of %s", errs[1].Raw)
+	}
+	if m.DE2.String() != "48468112AB" {
+		t.Errorf("DE2 should be kept, instead of %v", m.DE2)
+	}
+	if m.DE11.String() != "000001" || m.DE102.String() != "12341234234" {
+		t.Error("fields after the invalid ones should be decoded")
+	}
+
+	// a valid message leaves no errors behind
+	if err := m.Decode([]byte("1100700000000000000003123000011000000000012")); err != nil {
+		t.Fatal(err)
+	}
+	if m.DecodeErrors() != nil {
+		t.Errorf("there should be no errors, instead of %v", m.DecodeErrors())
+	}
+
+	// the message is truncated in DE43, decoded fields are kept
+	m = &Message{}
+	m.SetLenient(true)
+	err := m.Decode([]byte(msgToDecode[:130]))
+	fe, ok := err.(*FieldError)
+	if !ok || fe.Field != "DE43" {
+		t.Fatalf("error should be the FieldError of DE43, instead of %v", err)
+	}
+	if m.DE41.String() != "termid12" {
+		t.Errorf("DE41 should be termid12, instead of %v", m.DE41)
+	}
+}
+
+func TestLenientDecodeSubMessage(t *testing.T) {
+	msgToDecode := "1200C0000000000000000000000000000008104846811212107F3A35000000000000000000040000000Test Address                 123459876543210123A1-3112121111111100000000121"
+	m := &Message{}
+	m.SetLenient(true)
+	if err := m.Decode([]byte(msgToDecode)); err != nil {
+		t.Fatal(err)
+	}
+	errs := m.DecodeErrors()
+	if !reflect.DeepEqual(errs.Fields(), []string{"DE125.SE9"}) {
+		t.Fatalf("invalid fields should be [DE125.SE9], instead of %v", errs.Fields())
+	}
+	if m.DE125.SE98.String() != "1" {
+		t.Errorf("SE98 should be 1, instead of %v", m.DE125.SE98)
+	}
+}
+
+func TestAppendEncode(t *testing.T) {
+	expected, err := benchmarkMessage().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := []byte("prefix")
+	buf, err = benchmarkMessage().AppendEncode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "prefix"+string(expected) {
+		t.Errorf("Expected prefix%s, got %s", expected, buf)
+	}
+
+	var w bytes.Buffer
+	n, err := benchmarkMessage().EncodeTo(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(expected) || w.String() != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, w.String())
+	}
+
+	if _, err := (&Message{}).EncodeTo(&w); err == nil {
+		t.Error("encoding without MTI should fail")
+	}
+}
+
+func TestCopyOnDecode(t *testing.T) {
+	raw, err := benchmarkMessage().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := append([]byte(nil), raw...)
+
+	aliased := &Message{}
+	if err := aliased.Decode(input); err != nil {
+		t.Fatal(err)
+	}
+	copied := &Message{}
+	copied.SetCopyOnDecode(true)
+	if err := copied.Decode(input); err != nil {
+		t.Fatal(err)
+	}
+
+	// reuse the input buffer
+	for i := range input {
+		input[i] = 'X'
+	}
+	if aliased.DE41.String() != "XXXXXXXX" {
+		t.Errorf("DE41 should alias the input, got %s", aliased.DE41)
+	}
+	if copied.DE41.String() != "termid12" || copied.DE125.SE2.String() != "Test Address" {
+		t.Errorf("fields should be copied, got %s and %s", copied.DE41, copied.DE125.SE2)
+	}
+}
+
+// raceEnabled is true with the race detector
+var raceEnabled bool
+
+func TestDecodeAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are counted without the race detector")
+	}
+	raw, err := benchmarkMessage().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Message{}
+	allocs := testing.AllocsPerRun(100, func() {
+		*m = Message{}
+		if err := m.Decode(raw); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// the MTI, the 13 fields and SE2 of DE125 make 15 allocations, the
+	// margin is for the runtime, a copy of every value would make 29
+	if allocs > 20 {
+		t.Errorf("Decode should allocate at most 20 times, instead of %v", allocs)
+	}
+}
+
+func benchmarkMessage() *Message {
+	m := &Message{
+		DE2:   NewNumeric("4846811212"),        // Primary Account Number
+		DE3:   NewNumeric("201234"),            // Processing Code
+		DE4:   NewNumeric("10000000"),          // Amount, Transaction
+		DE7:   NewDateTime("1107221800"),       // Date And Time, Transmission
+		DE11:  NewNumeric("000001"),            // Systems Trace Audit Number
+		DE12:  NewDateTime("161204171926"),     // Date And Time, Local Transaction
+		DE22:  NewAlphanumeric("FABCDE123ABD"), // Point Of Service Data Code
+		DE32:  NewNumeric("414243"),            // Acquiring Institution Identification Code
+		DE39:  NewAlphanumeric("000"),          // Action Code
+		DE41:  NewANS("termid12"),              // Card Acceptor Terminal Identification
+		DE43:  NewANS("Community1"),            // Card Acceptor Name/Location
+		DE102: NewANS("12341234234"),           // Account Identification 1
+		DE125: &SubMessage{SE2: NewANS("Test Address")},
+	}
+	m.Mti = "1200"
+	return m
+}
+
+// BenchmarkEncode compares the generated field access with the reflection
+// over the cached field metadata. Both validate and encode the values the
+// same way, which takes most of the time, the generated access saves
+// allocations rather than time.
+func BenchmarkEncode(b *testing.B) {
+	m := benchmarkMessage()
+	run := func(generated bool) func(b *testing.B) {
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := m.appendEncode(make([]byte, 0, 512), generated); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	b.Run("generated", run(true))
+	b.Run("reflection", run(false))
+}
+
+func BenchmarkAppendEncode(b *testing.B) {
+	m := benchmarkMessage()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if buf, err = m.AppendEncode(buf[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeTo(b *testing.B) {
+	m := benchmarkMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.EncodeTo(ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode compares the generated field access, with and without
+// copy of the values, with the reflection over the cached field metadata,
+// see BenchmarkEncode
+func BenchmarkDecode(b *testing.B) {
+	raw, err := benchmarkMessage().Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(generated bool) func(b *testing.B) {
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m := &Message{}
+				if err := m.decode(raw, generated); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	b.Run("generated", run(true))
+	b.Run("copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := &Message{}
+			m.SetCopyOnDecode(true)
+			if err := m.Decode(raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflection", run(false))
+}
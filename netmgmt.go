@@ -0,0 +1,272 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NetworkDialect describes the network management messages of a host
+type NetworkDialect struct {
+	// MTI is the MTI of the requests, e.g. 0800 or 1804
+	MTI string
+	// Field is the number of the field of the function code, e.g. 70 or 24
+	Field int
+	// SignOn, SignOff and Echo are the function codes of the requests
+	SignOn  string
+	SignOff string
+	Echo    string
+}
+
+var (
+	// Dialect1987 is the 0800 request with the network management
+	// information code in DE70
+	Dialect1987 = NetworkDialect{MTI: "0800", Field: 70, SignOn: "001", SignOff: "002", Echo: "301"}
+	// Dialect1993 is the 1804 request with the function code in DE24
+	Dialect1993 = NetworkDialect{MTI: "1804", Field: 24, SignOn: "801", SignOff: "802", Echo: "831"}
+)
+
+// LinkState is the health of the link to a host
+type LinkState int
+
+const (
+	// LinkDown is a link not signed on, or with too many failed echo tests
+	LinkDown LinkState = iota
+	// LinkUp is a link signed on, with its last echo test answered
+	LinkUp
+	// LinkDegraded is a link with failed echo tests, not yet down
+	LinkDegraded
+)
+
+func (s LinkState) String() string {
+	switch s {
+	case LinkDown:
+		return "down"
+	case LinkUp:
+		return "up"
+	case LinkDegraded:
+		return "degraded"
+	}
+	return "LinkState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// NetworkManager signs on the connections of Client, tests the link with
+// periodic echo requests, and signs off on Stop
+type NetworkManager struct {
+	// Client is the client of the host, its OnConnect is set by Start
+	Client *Client
+	// Dialect is the network management messages of the host
+	Dialect NetworkDialect
+	// EchoInterval is the time between echo tests, 0 means no echo test
+	EchoInterval time.Duration
+	// EchoTimeout limits the wait for each echo response, 0 means EchoInterval
+	EchoTimeout time.Duration
+	// MaxEchoFailures is the number of successive failed echo tests bringing
+	// the link down and closing the connection, 0 means 3
+	MaxEchoFailures int
+	// STAN generates the STANs of the requests, e.g. the generator of the
+	// link, nil means a counter of the network manager
+	STAN *STANGenerator
+	// Prepare sets the fields of the host to every request, e.g. DE93 and DE94.
+	// DE7 and DE11 are set before, Prepare can change them.
+	Prepare func(m *Message)
+	// StateChanged is called on every change of the link state
+	StateChanged func(state LinkState)
+
+	mu       sync.Mutex
+	state    LinkState
+	failures int
+	stan     int
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Start connects Client, signing on the connection, and starts the echo
+// tests. The next connections are signed on too, e.g. after a reconnect.
+func (n *NetworkManager) Start(ctx context.Context) error {
+	n.mu.Lock()
+	if n.stop != nil {
+		n.mu.Unlock()
+		return errors.New("network manager already started")
+	}
+	n.stop, n.done = make(chan struct{}), make(chan struct{})
+	n.mu.Unlock()
+
+	n.Client.OnConnect = n.SignOn
+	err := n.Client.Connect(ctx)
+	go n.echoLoop(n.stop, n.done)
+	return err
+}
+
+// Stop stops the echo tests, signs off if the link is not down, and closes
+// Client
+func (n *NetworkManager) Stop(ctx context.Context) error {
+	n.mu.Lock()
+	stop, done := n.stop, n.done
+	n.stop = nil
+	state := n.state
+	n.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	var err error
+	if state != LinkDown {
+		err = n.SignOff(ctx)
+	}
+	n.Client.Close()
+	n.setState(LinkDown)
+	return err
+}
+
+// State returns the health of the link
+func (n *NetworkManager) State() LinkState {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state
+}
+
+// SignOn sends a sign-on request, the link is up if it is approved
+func (n *NetworkManager) SignOn(ctx context.Context) error {
+	_, err := n.send(ctx, n.Dialect.SignOn)
+	if err != nil {
+		n.setState(LinkDown)
+		return errors.New("sign-on: " + err.Error())
+	}
+	n.mu.Lock()
+	n.failures = 0
+	n.mu.Unlock()
+	n.setState(LinkUp)
+	return nil
+}
+
+// SignOff sends a sign-off request, the link is down after it
+func (n *NetworkManager) SignOff(ctx context.Context) error {
+	_, err := n.send(ctx, n.Dialect.SignOff)
+	n.setState(LinkDown)
+	if err != nil {
+		return errors.New("sign-off: " + err.Error())
+	}
+	return nil
+}
+
+// Echo sends an echo test request. The link is up if it is approved,
+// it is degraded if it fails, and down after MaxEchoFailures failures,
+// closing the connection.
+func (n *NetworkManager) Echo(ctx context.Context) error {
+	_, err := n.send(ctx, n.Dialect.Echo)
+	if err == nil {
+		n.mu.Lock()
+		n.failures = 0
+		n.mu.Unlock()
+		n.setState(LinkUp)
+		return nil
+	}
+
+	max := n.MaxEchoFailures
+	if max <= 0 {
+		max = 3
+	}
+	n.mu.Lock()
+	n.failures++
+	down := n.failures >= max
+	n.mu.Unlock()
+	if down {
+		n.setState(LinkDown)
+		n.Client.reset(errors.New("echo test failed"))
+	} else {
+		n.setState(LinkDegraded)
+	}
+	return errors.New("echo: " + err.Error())
+}
+
+func (n *NetworkManager) echoLoop(stop, done chan struct{}) {
+	defer close(done)
+	if n.EchoInterval <= 0 {
+		<-stop
+		return
+	}
+	timeout := n.EchoTimeout
+	if timeout <= 0 {
+		timeout = n.EchoInterval
+	}
+	ticker := time.NewTicker(n.EchoInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		// an unanswered echo test fails even without timeout of the client
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		// a link down is connected and signed on again by the echo
+		_ = n.Echo(ctx)
+		cancel()
+	}
+}
+
+// send sends the network management request of the function code,
+// and returns the approved response
+func (n *NetworkManager) send(ctx context.Context, code string) (*Message, error) {
+	m, err := n.request(code)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.Client.Send(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsApproved() {
+		rc, _ := resp.fieldString(39)
+		return resp, errors.New("declined with response code " + rc)
+	}
+	return resp, nil
+}
+
+func (n *NetworkManager) request(code string) (*Message, error) {
+	m := &Message{Mti: n.Dialect.MTI}
+	if err := m.setFieldString(n.Dialect.Field, code); err != nil {
+		return nil, err
+	}
+	m.DE7 = &DateTime{}
+	if err := m.DE7.SetTime(time.Now(), time.UTC); err != nil {
+		return nil, err
+	}
+	if n.STAN != nil {
+		if err := n.STAN.Set(m); err != nil {
+			return nil, err
+		}
+	} else {
+		n.mu.Lock()
+		n.stan = n.stan%maxSTAN + 1
+		stan := n.stan
+		n.mu.Unlock()
+		m.DE11 = NewNumeric(fmt.Sprintf("%06d", stan))
+	}
+	if n.Prepare != nil {
+		n.Prepare(m)
+	}
+	return m, nil
+}
+
+func (n *NetworkManager) setState(state LinkState) {
+	n.mu.Lock()
+	changed := n.state != state
+	n.state = state
+	n.mu.Unlock()
+	if changed && n.StateChanged != nil {
+		n.StateChanged(state)
+	}
+}
@@ -0,0 +1,210 @@
+package iso8583
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// networkHost is a test host answering network management requests,
+// and recording their function codes
+type networkHost struct {
+	mu    sync.Mutex
+	codes []string
+	// silent makes the host not answer the echo tests
+	silent bool
+}
+
+func (h *networkHost) handle(dialect NetworkDialect) func(req *Message) *Message {
+	return func(req *Message) *Message {
+		if req.Mti != dialect.MTI {
+			return echoResponse(req)
+		}
+		code, _ := req.fieldString(dialect.Field)
+		h.mu.Lock()
+		h.codes = append(h.codes, code)
+		silent := h.silent
+		h.mu.Unlock()
+		if silent && code == dialect.Echo {
+			return nil
+		}
+		resp := &Message{DE11: req.DE11, DE7: req.DE7}
+		resp.Mti, _ = ResponseMTI(req.Mti)
+		if resp.Mti[0] == '0' {
+			resp.DE39 = NewAlphanumeric("00")
+		} else {
+			resp.DE39 = NewAlphanumeric("800")
+		}
+		return resp
+	}
+}
+
+func (h *networkHost) received() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.codes...)
+}
+
+func TestNetworkManagerSignOn(t *testing.T) {
+	for _, dialect := range []NetworkDialect{Dialect1987, Dialect1993} {
+		host := &networkHost{}
+		addr, closeHost := testHost(t, 1, host.handle(dialect))
+		var states []LinkState
+		n := &NetworkManager{
+			Client:  &Client{Addr: addr, Timeout: time.Second},
+			Dialect: dialect,
+			Prepare: func(m *Message) {
+				m.DE93 = NewNumeric("00000000001")
+			},
+			StateChanged: func(state LinkState) {
+				states = append(states, state)
+			},
+		}
+		if err := n.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n.State() != LinkUp {
+			t.Errorf("link should be up after sign-on, got %s", n.State())
+		}
+		if _, err := n.Client.Send(context.Background(), clientTestRequest("000001")); err != nil {
+			t.Error(err)
+		}
+		if err := n.Echo(context.Background()); err != nil {
+			t.Error(err)
+		}
+		if err := n.Stop(context.Background()); err != nil {
+			t.Error(err)
+		}
+		if n.State() != LinkDown {
+			t.Errorf("link should be down after sign-off, got %s", n.State())
+		}
+		codes := host.received()
+		if len(codes) != 3 || codes[0] != dialect.SignOn || codes[1] != dialect.Echo || codes[2] != dialect.SignOff {
+			t.Errorf("requests of %s should be sign-on, echo and sign-off, got %v", dialect.MTI, codes)
+		}
+		if len(states) != 2 || states[0] != LinkUp || states[1] != LinkDown {
+			t.Errorf("states should be up and down, got %v", states)
+		}
+		closeHost()
+	}
+}
+
+func TestNetworkManagerEcho(t *testing.T) {
+	host := &networkHost{silent: true}
+	addr, closeHost := testHost(t, 1, host.handle(Dialect1993))
+	defer closeHost()
+	var mu sync.Mutex
+	var states []LinkState
+	n := &NetworkManager{
+		Client:          &Client{Addr: addr, Timeout: 50 * time.Millisecond},
+		Dialect:         Dialect1993,
+		EchoInterval:    20 * time.Millisecond,
+		MaxEchoFailures: 2,
+		StateChanged: func(state LinkState) {
+			mu.Lock()
+			states = append(states, state)
+			mu.Unlock()
+		},
+	}
+	if err := n.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop(context.Background())
+
+	// the unanswered echo tests degrade the link and bring it down,
+	// the next echo test connects and signs on again
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		seen := len(states) >= 4
+		mu.Unlock()
+		if seen || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []LinkState{LinkUp, LinkDegraded, LinkDown, LinkUp}
+	if len(states) < len(expected) {
+		t.Fatalf("states should be %v, got %v", expected, states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("states should be %v, got %v", expected, states)
+		}
+	}
+}
+
+func TestNetworkManagerEchoTimeout(t *testing.T) {
+	host := &networkHost{silent: true}
+	addr, closeHost := testHost(t, 1, host.handle(Dialect1987))
+	defer closeHost()
+	// the client has no timeout, the echo tests fail by EchoTimeout,
+	// or by EchoInterval without it
+	for _, timeout := range []time.Duration{30 * time.Millisecond, 0} {
+		down := make(chan struct{}, 1)
+		n := &NetworkManager{
+			Client:          &Client{Addr: addr},
+			Dialect:         Dialect1987,
+			EchoInterval:    20 * time.Millisecond,
+			EchoTimeout:     timeout,
+			MaxEchoFailures: 1,
+			StateChanged: func(state LinkState) {
+				if state == LinkDown {
+					select {
+					case down <- struct{}{}:
+					default:
+					}
+				}
+			},
+		}
+		if err := n.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-down:
+		case <-time.After(2 * time.Second):
+			t.Errorf("unanswered echo test should bring the link down, echo timeout %s", timeout)
+		}
+		n.Stop(context.Background())
+	}
+}
+
+func TestNetworkManagerSignOnDeclined(t *testing.T) {
+	addr, closeHost := testHost(t, 1, func(req *Message) *Message {
+		resp := &Message{DE11: req.DE11, DE39: NewAlphanumeric("05")}
+		resp.Mti, _ = ResponseMTI(req.Mti)
+		return resp
+	})
+	defer closeHost()
+	n := &NetworkManager{
+		Client:  &Client{Addr: addr, Timeout: time.Second},
+		Dialect: Dialect1987,
+	}
+	if err := n.Start(context.Background()); err == nil {
+		t.Error("declined sign-on should fail")
+	}
+	defer n.Stop(context.Background())
+	if n.State() != LinkDown {
+		t.Errorf("link should be down, got %s", n.State())
+	}
+	// the requests are not sent without sign-on
+	if _, err := n.Client.Send(context.Background(), clientTestRequest("000001")); err == nil {
+		t.Error("request should fail without sign-on")
+	}
+}
+
+func TestSetFieldString(t *testing.T) {
+	m := &Message{}
+	if err := m.setFieldString(70, "301"); err != nil || m.DE70.String() != "301" {
+		t.Errorf("DE70 should be set, got %v %v", m.DE70, err)
+	}
+	if err := m.setFieldString(52, "00"); err == nil {
+		t.Error("binary field should not be set")
+	}
+	if err := m.setFieldString(8, "00"); err == nil {
+		t.Error("missing field should not be set")
+	}
+}
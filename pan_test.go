@@ -0,0 +1,132 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLuhnValid(t *testing.T) {
+	var scenarios = []struct {
+		number string
+		valid  bool
+	}{
+		{number: "4111111111111111", valid: true},
+		{number: "4111111111111112", valid: false},
+		{number: "79927398713", valid: true},
+		{number: "0", valid: false},
+		{number: "41111111111111A1", valid: false},
+	}
+	for _, scenario := range scenarios {
+		if LuhnValid(scenario.number) != scenario.valid {
+			t.Errorf("Luhn of %s should be %v", scenario.number, scenario.valid)
+		}
+	}
+}
+
+func TestPANValidator(t *testing.T) {
+	var scenarios = []struct {
+		pan    string
+		scheme Scheme
+		err    bool
+	}{
+		{pan: "4111111111111111", scheme: SchemeVisa},
+		{pan: "5555555555554444", scheme: SchemeMastercard},
+		{pan: "2223003122003222", scheme: SchemeMastercard},
+		{pan: "378282246310005", scheme: SchemeAmex},
+		{pan: "6011111111111117", scheme: SchemeDiscover},
+		{pan: "6445644564456445", scheme: SchemeDiscover},
+		{pan: "3530111333300000", scheme: SchemeJCB},
+		{pan: "6200000000000005", scheme: SchemeUnionPay},
+		{pan: "9000000000000001", scheme: SchemeUnknown},
+		// invalid check digit
+		{pan: "4111111111111112", err: true},
+		// too short
+		{pan: "42424242426", err: true},
+		// Amex is 15 long
+		{pan: "3400000000000009", err: true},
+	}
+
+	v := &PANValidator{}
+	for _, scenario := range scenarios {
+		r, err := v.Validate(scenario.pan)
+		if scenario.err {
+			if err == nil {
+				t.Errorf("expecting error for %s", scenario.pan)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", scenario.pan, err)
+		} else if r.Scheme != scenario.scheme {
+			t.Errorf("scheme of %s should be %q, instead of %q", scenario.pan, scenario.scheme, r.Scheme)
+		}
+	}
+
+	v.RequireKnown = true
+	if _, err := v.Validate("9000000000000001"); err == nil {
+		t.Error("expecting error, unknown PAN range")
+	}
+}
+
+func TestBINTableProducts(t *testing.T) {
+	table := BINTable{
+		{Low: "4", High: "4", Scheme: SchemeVisa},
+		{Low: "411111", High: "411199", Scheme: SchemeVisa, Product: "DEBIT"},
+	}
+	r, ok := table.Lookup("4111111111111111")
+	if !ok || r.Product != "DEBIT" {
+		t.Errorf("the longest prefix should win, instead of %#v", r)
+	}
+	r, _ = table.Lookup("4242424242424242")
+	if r.Product != "" || r.Scheme != SchemeVisa {
+		t.Errorf("range should be the Visa one, instead of %#v", r)
+	}
+
+	m := &Message{DE2: NewNumeric("4111111111111111")}
+	m.SetPANValidator(&PANValidator{Table: table})
+	if r, _ := m.CardRange(); r.Product != "DEBIT" {
+		t.Errorf("product should be DEBIT, instead of %q", r.Product)
+	}
+}
+
+func TestMessagePANValidation(t *testing.T) {
+	m := &Message{
+		DE2: NewNumeric("4846811212"),
+		DE3: NewNumeric("201234"),
+	}
+	m.Mti = "1100"
+	if m.CardScheme() != SchemeVisa {
+		t.Errorf("scheme should be VISA, instead of %q", m.CardScheme())
+	}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.SetPANValidator(&PANValidator{})
+	if _, err := m.Encode(); err == nil {
+		t.Error("expecting error, PAN is too short")
+	}
+	var errs ValidationErrors
+	if err := m.Validate(); !errors.As(err, &errs) || errs[0].Field != "DE2" {
+		t.Errorf("DE2 should be invalid, instead of %v", err)
+	}
+
+	decoded := &Message{}
+	decoded.SetPANValidator(&PANValidator{})
+	if err := decoded.Decode(b); err == nil {
+		t.Error("expecting error, PAN is too short")
+	}
+	decoded.SetLenient(true)
+	if err := decoded.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if fields := decoded.DecodeErrors().Fields(); len(fields) != 1 || fields[0] != "DE2" {
+		t.Errorf("DE2 should be invalid, instead of %v", fields)
+	}
+
+	m.DE2 = NewNumeric("4111111111111111")
+	if err := m.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,288 @@
+// Package pin builds, parses and enciphers the PIN blocks of ISO 9564-1,
+// formats 0, 1, 2, 3 and 4, e.g. for DE52. The encipherment is done in
+// software, with TDES or AES keys in the clear.
+package pin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fluidpay/iso8583"
+)
+
+// Format is the format of a PIN block, its control field
+type Format int
+
+const (
+	// Format0 is the PIN XORed with the PAN, filled with F, ANSI X9.8
+	Format0 Format = 0
+	// Format1 is the PIN filled with a random transaction field, without PAN
+	Format1 Format = 1
+	// Format2 is the PIN filled with F, without PAN, for offline ICC PIN
+	Format2 Format = 2
+	// Format3 is the PIN XORed with the PAN, filled with random A to F
+	Format3 Format = 3
+	// Format4 is the 16 byte block of AES, the PIN and the PAN enciphered
+	// in two steps
+	Format4 Format = 4
+)
+
+// random is the source of the random fill, replaced in tests
+var random io.Reader = rand.Reader
+
+// TDES returns the TDES cipher of a key of 16 (double length) or 24 (triple
+// length) bytes
+func TDES(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16:
+		k := make([]byte, 0, 24)
+		k = append(append(k, key...), key[:8]...)
+		return des.NewTripleDESCipher(k)
+	case 24:
+		return des.NewTripleDESCipher(key)
+	}
+	return nil, errors.New("invalid TDES key length: " + strconv.Itoa(len(key)))
+}
+
+// AES returns the AES cipher of a key of 16, 24 or 32 bytes
+func AES(key []byte) (cipher.Block, error) {
+	return aes.NewCipher(key)
+}
+
+func checkPIN(pin string) error {
+	if len(pin) < 4 || len(pin) > 12 || !isDigits(pin) {
+		return errors.New("PIN must have 4 to 12 digits")
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// panField returns the PAN field of formats 0 and 3, 0000 followed by the
+// 12 rightmost digits of the PAN without its check digit
+func panField(pan string) ([]byte, error) {
+	if len(pan) < 2 || len(pan) > 19 || !isDigits(pan) {
+		return nil, errors.New("invalid PAN")
+	}
+	digits := pan[:len(pan)-1]
+	if len(digits) > 12 {
+		digits = digits[len(digits)-12:]
+	}
+	return hex.DecodeString("0000" + strings.Repeat("0", 12-len(digits)) + digits)
+}
+
+// panField4 returns the PAN field of format 4, the length of the PAN minus
+// 12 followed by the PAN of at least 12 digits, padded with 0
+func panField4(pan string) ([]byte, error) {
+	if len(pan) < 1 || len(pan) > 19 || !isDigits(pan) {
+		return nil, errors.New("invalid PAN")
+	}
+	m := 0
+	if len(pan) < 12 {
+		pan = strings.Repeat("0", 12-len(pan)) + pan
+	} else {
+		m = len(pan) - 12
+	}
+	field := strconv.Itoa(m) + pan
+	return hex.DecodeString(field + strings.Repeat("0", 32-len(field)))
+}
+
+// randomNibbles returns n random nibbles from min to 15, as hex digits
+func randomNibbles(n int, min byte) (string, error) {
+	span := int(16 - min)
+	// the bytes over the largest multiple of span are dropped, not to bias the nibbles
+	limit := 256 - 256%span
+	nibbles := make([]byte, 0, n)
+	b := make([]byte, n)
+	for len(nibbles) < n {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(nibbles) < n {
+				nibbles = append(nibbles, "0123456789ABCDEF"[int(min)+int(c)%span])
+			}
+		}
+	}
+	return string(nibbles), nil
+}
+
+// Build returns the clear PIN block of the format. The PAN is used by
+// formats 0 and 3 only, format 4 returns the PIN field of 16 bytes.
+func Build(format Format, pin, pan string) ([]byte, error) {
+	if err := checkPIN(pin); err != nil {
+		return nil, err
+	}
+	field := strconv.Itoa(int(format)) + strconv.FormatInt(int64(len(pin)), 16) + pin
+	var fill string
+	var err error
+	switch format {
+	case Format0, Format2:
+		fill = strings.Repeat("F", 16-len(field))
+	case Format1:
+		fill, err = randomNibbles(16-len(field), 0)
+	case Format3:
+		fill, err = randomNibbles(16-len(field), 10)
+	case Format4:
+		fill = strings.Repeat("A", 16-len(field))
+		var r string
+		r, err = randomNibbles(16, 0)
+		fill += r
+	default:
+		return nil, errors.New("invalid PIN block format: " + strconv.Itoa(int(format)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, err := hex.DecodeString(field + fill)
+	if err != nil {
+		return nil, err
+	}
+	if format == Format0 || format == Format3 {
+		p, err := panField(pan)
+		if err != nil {
+			return nil, err
+		}
+		xor(block, p)
+	}
+	return block, nil
+}
+
+// Parse returns the PIN and the format of a clear PIN block, the format
+// is given by its control field. The PAN is used by formats 0 and 3 only.
+func Parse(block []byte, pan string) (string, Format, error) {
+	if len(block) != 8 && len(block) != 16 {
+		return "", 0, errors.New("invalid PIN block length: " + strconv.Itoa(len(block)))
+	}
+	block = append([]byte(nil), block...)
+	format := Format(block[0] >> 4)
+	if (format == Format4) != (len(block) == 16) {
+		return "", 0, errors.New("invalid PIN block length of format " + strconv.Itoa(int(format)))
+	}
+	switch format {
+	case Format0, Format3:
+		p, err := panField(pan)
+		if err != nil {
+			return "", 0, err
+		}
+		xor(block, p)
+	case Format1, Format2, Format4:
+	default:
+		return "", 0, errors.New("invalid PIN block format: " + strconv.Itoa(int(format)))
+	}
+
+	field := strings.ToUpper(hex.EncodeToString(block[:8]))
+	n := int(block[0] & 0x0F)
+	if n < 4 || n > 12 {
+		return "", 0, errors.New("invalid PIN length in PIN block")
+	}
+	pin, fill := field[2:2+n], field[2+n:]
+	if !isDigits(pin) {
+		return "", 0, errors.New("invalid PIN in PIN block")
+	}
+	valid := true
+	for i := 0; i < len(fill); i++ {
+		switch format {
+		case Format0, Format2:
+			valid = valid && fill[i] == 'F'
+		case Format3:
+			valid = valid && fill[i] >= 'A'
+		case Format4:
+			valid = valid && fill[i] == 'A'
+		}
+	}
+	if !valid {
+		return "", 0, errors.New("invalid fill of PIN block")
+	}
+	return pin, format, nil
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// Encrypt returns the enciphered PIN block of the format, formats 0 to 3
+// with a TDES cipher, format 4 with an AES cipher
+func Encrypt(c cipher.Block, format Format, pin, pan string) ([]byte, error) {
+	block, err := Build(format, pin, pan)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != len(block) {
+		return nil, errors.New("invalid cipher of PIN block format " + strconv.Itoa(int(format)))
+	}
+	if format != Format4 {
+		c.Encrypt(block, block)
+		return block, nil
+	}
+	// the PIN field, XORed with the PAN field, is enciphered twice
+	p, err := panField4(pan)
+	if err != nil {
+		return nil, err
+	}
+	c.Encrypt(block, block)
+	xor(block, p)
+	c.Encrypt(block, block)
+	return block, nil
+}
+
+// Decrypt returns the PIN and the format of an enciphered PIN block
+func Decrypt(c cipher.Block, block []byte, pan string) (string, Format, error) {
+	if c.BlockSize() != len(block) {
+		return "", 0, errors.New("invalid PIN block length: " + strconv.Itoa(len(block)))
+	}
+	clear := make([]byte, len(block))
+	c.Decrypt(clear, block)
+	if len(block) == 16 {
+		p, err := panField4(pan)
+		if err != nil {
+			return "", 0, err
+		}
+		xor(clear, p)
+		c.Decrypt(clear, clear)
+	}
+	return Parse(clear, pan)
+}
+
+// Translate deciphers the PIN block with from, and enciphers the PIN in
+// the format with to, e.g. from the key of a terminal to the zone key of
+// a host
+func Translate(block []byte, pan string, from, to cipher.Block, format Format) ([]byte, error) {
+	pin, _, err := Decrypt(from, block, pan)
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(to, format, pin, pan)
+}
+
+// Field returns the DE52 value of an enciphered PIN block of 8 bytes
+func Field(block []byte) (*iso8583.B64, error) {
+	if len(block) != 8 {
+		return nil, errors.New("PIN block of " + strconv.Itoa(len(block)) + " bytes does not fit DE52")
+	}
+	return iso8583.NewBinary64Hex(strings.ToUpper(hex.EncodeToString(block))), nil
+}
+
+// FromField returns the enciphered PIN block of a DE52 value
+func FromField(f *iso8583.B64) ([]byte, error) {
+	if f == nil {
+		return nil, errors.New("no PIN block")
+	}
+	return hex.DecodeString(string(f.Value))
+}
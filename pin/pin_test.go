@@ -0,0 +1,188 @@
+package pin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// fixedRandom makes the random fill repeatable
+func fixedRandom(t *testing.T, s string) func() {
+	saved := random
+	random = bytes.NewReader(mustHex(t, strings.Repeat(s, 8)))
+	return func() { random = saved }
+}
+
+func TestBuild(t *testing.T) {
+	defer fixedRandom(t, "2F69ADDE2E9E7ACE")()
+	pan := "43219876543210987"
+	for _, test := range []struct {
+		format   Format
+		pin      string
+		expected string
+	}{
+		{Format0, "1234", "0412AC89ABCDEF67"},
+		{Format1, "1234", "141234F9DEEEAEF9"},
+		{Format2, "12345", "2512345FFFFFFFFF"},
+		{Format3, "1234", "3412AC"},
+		{Format4, "1234", "441234AAAAAAAAAA"},
+	} {
+		block, err := Build(test.format, test.pin, pan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(block)); !strings.HasPrefix(got, test.expected) {
+			t.Errorf("format %d block should start with %s, got %s", test.format, test.expected, got)
+		}
+		pin, format, err := Parse(block, pan)
+		if err != nil || pin != test.pin || format != test.format {
+			t.Errorf("format %d block should be parsed, got %s %d %v", test.format, pin, format, err)
+		}
+	}
+
+	for _, pin := range []string{"123", "1234567890123", "12a4"} {
+		if _, err := Build(Format0, pin, pan); err == nil {
+			t.Errorf("invalid PIN %s should fail", pin)
+		}
+	}
+	if _, err := Build(Format(5), "1234", pan); err == nil {
+		t.Error("invalid format should fail")
+	}
+	if _, err := Build(Format0, "1234", "4321A"); err == nil {
+		t.Error("invalid PAN should fail")
+	}
+}
+
+func TestBuildFormat3Fill(t *testing.T) {
+	defer fixedRandom(t, "00FF0F10")()
+	block, err := Build(Format3, "1234", "43219876543210987")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the clear PIN field is filled with A to F only, FF is dropped
+	p, _ := panField("43219876543210987")
+	xor(block, p)
+	if field := strings.ToUpper(hex.EncodeToString(block)); field[6:] != "ADEADEADEA" {
+		t.Errorf("fill should be from A to F, got %s", field)
+	}
+}
+
+func TestPANField(t *testing.T) {
+	for pan, expected := range map[string]string{
+		"43219876543210987": "0000987654321098",
+		"1234567":           "0000000000123456",
+	} {
+		if p, _ := panField(pan); strings.ToUpper(hex.EncodeToString(p)) != expected {
+			t.Errorf("PAN field of %s should be %s, got %X", pan, expected, p)
+		}
+	}
+	for pan, expected := range map[string]string{
+		"1234567890123456789": "71234567890123456789000000000000",
+		"432198765432":        "04321987654320000000000000000000",
+		"12345":               "00000000123450000000000000000000",
+	} {
+		if p, _ := panField4(pan); strings.ToUpper(hex.EncodeToString(p)) != expected {
+			t.Errorf("format 4 PAN field of %s should be %s, got %X", pan, expected, p)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	pan := "43219876543210987"
+	for _, block := range []string{
+		"0412AC89ABCDEF",                   // short
+		"0312AC89ABCDEF67",                 // PIN length 3
+		"0412AC89ABCDEF66",                 // fill
+		"5412AC89ABCDEF67",                 // format
+		"441234AAAAAAAAAA",                 // format 4 of 8 bytes
+		"041234FFFFFFFFFF2F69ADDE2E9E7ACE", // format 0 of 16 bytes
+		"241A34FFFFFFFFFF",                 // PIN digits
+	} {
+		if _, _, err := Parse(mustHex(t, block), pan); err == nil {
+			t.Errorf("invalid PIN block %s should fail", block)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	tdes, err := TDES(mustHex(t, "0123456789ABCDEFFEDCBA9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tdes3, err := TDES(mustHex(t, "0123456789ABCDEFFEDCBA987654321089ABCDEF01234567"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := AES(mustHex(t, "C1D0F8FB4958670DBA40AB1F3752EF0D"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pan := "4321987654321098"
+	for _, format := range []Format{Format0, Format1, Format2, Format3} {
+		block, err := Encrypt(tdes, format, "1234", pan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pin, f, err := Decrypt(tdes, block, pan); err != nil || pin != "1234" || f != format {
+			t.Errorf("format %d block should be decrypted, got %s %d %v", format, pin, f, err)
+		}
+		if _, err := Encrypt(aes, format, "1234", pan); err == nil {
+			t.Errorf("format %d should not be enciphered with AES", format)
+		}
+	}
+
+	block, err := Encrypt(aes, Format4, "123456", pan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin, f, err := Decrypt(aes, block, pan); err != nil || pin != "123456" || f != Format4 {
+		t.Errorf("format 4 block should be decrypted, got %s %d %v", pin, f, err)
+	}
+	// the PAN is bound to the format 4 block
+	if _, _, err := Decrypt(aes, block, "4321987654321099"); err == nil {
+		t.Error("format 4 block should not be decrypted with another PAN")
+	}
+	if _, err := Encrypt(tdes, Format4, "1234", pan); err == nil {
+		t.Error("format 4 should not be enciphered with TDES")
+	}
+
+	// from the AES key to a TDES key, in format 0
+	translated, err := Translate(block, pan, aes, tdes3, Format0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin, f, err := Decrypt(tdes3, translated, pan); err != nil || pin != "123456" || f != Format0 {
+		t.Errorf("translated block should be decrypted, got %s %d %v", pin, f, err)
+	}
+
+	if _, err := TDES(make([]byte, 8)); err == nil {
+		t.Error("single length key should fail")
+	}
+}
+
+func TestField(t *testing.T) {
+	block := mustHex(t, "0412ac89abcdef67")
+	f, err := Field(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.String() != "0412AC89ABCDEF67" {
+		t.Errorf("DE52 should be 0412AC89ABCDEF67, got %s", f)
+	}
+	b, err := FromField(f)
+	if err != nil || !bytes.Equal(b, block) {
+		t.Errorf("PIN block should be %X, got %X %v", block, b, err)
+	}
+	if _, err := Field(make([]byte, 16)); err == nil {
+		t.Error("16 byte block should not fit DE52")
+	}
+}
@@ -0,0 +1,344 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrNoLink is returned by the requests of a pool without any link up
+var ErrNoLink = errors.New("no link available")
+
+// Pool spreads the requests over many connections to a host, each one a
+// Client. A link lost by a read error or by failed echo tests is connected
+// again with backoff, failing over to the next endpoints. The response of
+// a request comes on the link of the request, a request in flight on a lost
+// link fails and is not sent again.
+type Pool struct {
+	// Endpoints are the TCP addresses of the host, the primary first
+	Endpoints []string
+	// Size is the number of connections, 0 means 1
+	Size int
+	// NewClient returns the client of a connection, e.g. with its Framer
+	// and Timeout, nil means a Client with the defaults. Its Addr is not used,
+	// the pool dials the endpoints. Its Dial and OnDisconnect are called by
+	// the ones of the pool.
+	NewClient func() *Client
+	// NewNetworkManager returns the network manager of a client, e.g. with
+	// its Dialect and EchoInterval, nil means no network management
+	NewNetworkManager func(c *Client) *NetworkManager
+	// MinBackoff is the first wait before connecting a lost link again, it
+	// is doubled after every failed attempt, 0 means 100ms
+	MinBackoff time.Duration
+	// MaxBackoff limits the wait between the attempts, 0 means 30s
+	MaxBackoff time.Duration
+
+	mu       sync.Mutex
+	links    []*poolLink
+	inFlight map[string]struct{}
+	next     int
+	closed   bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// poolLink is a connection of the pool
+type poolLink struct {
+	client *Client
+	nm     *NetworkManager
+	// endpoint is the index of the endpoint of the connection
+	endpoint     int
+	up           bool
+	reconnecting bool
+}
+
+func (p *Pool) backoff() (min, max time.Duration) {
+	min, max = p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	return min, max
+}
+
+// Start connects the links, signing them on with network management.
+// It fails if no link is up, the links keep connecting in the background
+// until Close.
+func (p *Pool) Start(ctx context.Context) error {
+	if len(p.Endpoints) == 0 {
+		return errors.New("no endpoint")
+	}
+	size := p.Size
+	if size <= 0 {
+		size = 1
+	}
+	p.mu.Lock()
+	if p.links != nil {
+		p.mu.Unlock()
+		return errors.New("pool already started")
+	}
+	p.inFlight = make(map[string]struct{})
+	p.stop = make(chan struct{})
+	for i := 0; i < size; i++ {
+		p.links = append(p.links, p.newLink())
+	}
+	links := p.links
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(links))
+	for i, l := range links {
+		wg.Add(1)
+		go func(i int, l *poolLink) {
+			defer wg.Done()
+			errs[i] = p.connect(ctx, l)
+		}(i, l)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.New("no link up: " + errs[0].Error())
+}
+
+func (p *Pool) newLink() *poolLink {
+	// the link is connected by Start first
+	l := &poolLink{reconnecting: true}
+	if p.NewClient != nil {
+		l.client = p.NewClient()
+	} else {
+		l.client = &Client{}
+	}
+	dial := l.client.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	l.client.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		p.mu.Lock()
+		addr := p.Endpoints[l.endpoint]
+		p.mu.Unlock()
+		return dial(ctx, network, addr)
+	}
+	onDisconnect := l.client.OnDisconnect
+	l.client.OnDisconnect = func(err error) {
+		p.setUp(l, false)
+		if onDisconnect != nil {
+			onDisconnect(err)
+		}
+	}
+	if p.NewNetworkManager != nil {
+		l.nm = p.NewNetworkManager(l.client)
+	}
+	if l.nm != nil {
+		stateChanged := l.nm.StateChanged
+		l.nm.StateChanged = func(state LinkState) {
+			if state == LinkUp || state == LinkDown {
+				p.setUp(l, state == LinkUp)
+			}
+			if stateChanged != nil {
+				stateChanged(state)
+			}
+		}
+	}
+	return l
+}
+
+// connect connects the link at start, on the endpoints in order
+func (p *Pool) connect(ctx context.Context, l *poolLink) error {
+	var err error
+	for i := range p.Endpoints {
+		p.mu.Lock()
+		l.endpoint = i
+		p.mu.Unlock()
+		if l.nm != nil && i == 0 {
+			err = l.nm.Start(ctx)
+		} else {
+			err = l.client.Connect(ctx)
+		}
+		if err == nil {
+			p.mu.Lock()
+			l.reconnecting = false
+			p.mu.Unlock()
+			p.setUp(l, true)
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	p.mu.Lock()
+	l.reconnecting = false
+	p.mu.Unlock()
+	p.setUp(l, false)
+	return err
+}
+
+// setUp sets the state of the link, a link down is connected again
+func (p *Pool) setUp(l *poolLink, up bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	l.up = up
+	if !up && !l.reconnecting {
+		l.reconnecting = true
+		p.wg.Add(1)
+		go p.reconnect(l)
+	}
+}
+
+// reconnect connects the link again with backoff, first on the same
+// endpoint, then on the next ones
+func (p *Pool) reconnect(l *poolLink) {
+	defer p.wg.Done()
+	backoff, max := p.backoff()
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(backoff):
+		}
+		p.mu.Lock()
+		if l.up {
+			// connected by the echo tests of the network manager
+			l.reconnecting = false
+			p.mu.Unlock()
+			return
+		}
+		if attempt > 0 {
+			l.endpoint = (l.endpoint + 1) % len(p.Endpoints)
+		}
+		p.mu.Unlock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-p.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := l.client.Connect(ctx)
+		cancel()
+		p.mu.Lock()
+		if err == nil || l.up {
+			l.up = !p.closed
+			l.reconnecting = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+		if backoff *= 2; backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// pick returns the link up with the fewest requests in flight
+func (p *Pool) pick() *poolLink {
+	p.mu.Lock()
+	// start after the last pick, to spread the links with the same load
+	var up []*poolLink
+	for i := range p.links {
+		if l := p.links[(p.next+i)%len(p.links)]; l.up {
+			up = append(up, l)
+		}
+	}
+	p.next++
+	p.mu.Unlock()
+
+	// the clients are locked without the pool, their Dial locks the pool
+	var best *poolLink
+	bestInFlight := 0
+	for _, l := range up {
+		if inFlight := l.client.InFlight(); best == nil || inFlight < bestInFlight {
+			best, bestInFlight = l, inFlight
+		}
+	}
+	return best
+}
+
+// Send sends the request on the least loaded link, and waits for its
+// response. A key of Matcher can be in flight once in the whole pool.
+func (p *Pool) Send(ctx context.Context, m *Message) (*Message, error) {
+	l := p.pick()
+	if l == nil {
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return nil, ErrClientClosed
+		}
+		return nil, ErrNoLink
+	}
+
+	key := l.client.match(m)
+	p.mu.Lock()
+	if _, ok := p.inFlight[key]; ok {
+		p.mu.Unlock()
+		return nil, errors.New("request already in flight: " + key)
+	}
+	p.inFlight[key] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.inFlight, key)
+		p.mu.Unlock()
+	}()
+
+	return l.client.Send(ctx, m)
+}
+
+// Up returns the number of links up
+func (p *Pool) Up() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	up := 0
+	for _, l := range p.links {
+		if l.up {
+			up++
+		}
+	}
+	return up
+}
+
+// Close stops connecting the links, signs off the links up with network
+// management, and closes the clients
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	links := p.links
+	if p.stop != nil {
+		close(p.stop)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	var err error
+	for _, l := range links {
+		if l.nm != nil {
+			if e := l.nm.Stop(ctx); e != nil && err == nil {
+				err = e
+			}
+		} else {
+			l.client.Close()
+		}
+	}
+	p.mu.Lock()
+	for _, l := range links {
+		l.up = false
+	}
+	p.mu.Unlock()
+	return err
+}
@@ -0,0 +1,289 @@
+package iso8583
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// poolHost is a test host echoing the requests, and counting them by connection
+type poolHost struct {
+	l        net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+	requests map[net.Conn]int
+}
+
+func newPoolHost(t *testing.T) *poolHost {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &poolHost{l: l, requests: make(map[net.Conn]int)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			h.mu.Lock()
+			h.conns = append(h.conns, conn)
+			h.mu.Unlock()
+			go func() {
+				defer conn.Close()
+				dec, enc := NewDecoder(conn), NewEncoder(conn)
+				for {
+					req := &Message{}
+					if err := dec.Decode(req); err != nil {
+						return
+					}
+					h.mu.Lock()
+					h.requests[conn]++
+					h.mu.Unlock()
+					if err := enc.Encode(echoResponse(req)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return h
+}
+
+func (h *poolHost) addr() string {
+	return h.l.Addr().String()
+}
+
+// counts returns the number of requests of every connection
+func (h *poolHost) counts() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var counts []int
+	for _, conn := range h.conns {
+		counts = append(counts, h.requests[conn])
+	}
+	return counts
+}
+
+// kill closes the listener and the connections
+func (h *poolHost) kill() {
+	h.l.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, conn := range h.conns {
+		conn.Close()
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolSpread(t *testing.T) {
+	host := newPoolHost(t)
+	defer host.kill()
+	p := &Pool{
+		Endpoints: []string{host.addr()},
+		Size:      3,
+		NewClient: func() *Client {
+			return &Client{Timeout: time.Second}
+		},
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(context.Background())
+	if p.Up() != 3 {
+		t.Errorf("3 links should be up, got %d", p.Up())
+	}
+	for i := 1; i <= 9; i++ {
+		stan := "00000" + string(rune('0'+i))
+		resp, err := p.Send(context.Background(), clientTestRequest(stan))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.DE11.String() != stan {
+			t.Errorf("response should match request %s, got %s", stan, resp.DE11)
+		}
+	}
+	counts := host.counts()
+	if len(counts) != 3 {
+		t.Fatalf("host should have 3 connections, got %d", len(counts))
+	}
+	for _, count := range counts {
+		if count != 3 {
+			t.Errorf("requests should be spread over the connections, got %v", counts)
+			break
+		}
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	primary, secondary := newPoolHost(t), newPoolHost(t)
+	defer secondary.kill()
+	disconnected := make(chan error, 1)
+	p := &Pool{
+		Endpoints:  []string{primary.addr(), secondary.addr()},
+		MinBackoff: 10 * time.Millisecond,
+		NewClient: func() *Client {
+			return &Client{Timeout: time.Second, OnDisconnect: func(err error) {
+				select {
+				case disconnected <- err:
+				default:
+				}
+			}}
+		},
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(context.Background())
+	if _, err := p.Send(context.Background(), clientTestRequest("000001")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lost link is connected again on the secondary endpoint
+	primary.kill()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Error("OnDisconnect of the client should be called")
+	}
+	waitFor(t, func() bool { return len(secondary.counts()) == 1 && p.Up() == 1 })
+	if _, err := p.Send(context.Background(), clientTestRequest("000002")); err != nil {
+		t.Fatal(err)
+	}
+	if counts := secondary.counts(); counts[0] != 1 {
+		t.Errorf("request should be sent to the secondary endpoint, got %v", counts)
+	}
+}
+
+func TestPoolEchoFailure(t *testing.T) {
+	host := &networkHost{silent: true}
+	addr, closeHost := testHost(t, 1, host.handle(Dialect1993))
+	defer closeHost()
+	var mu sync.Mutex
+	var states []LinkState
+	p := &Pool{
+		Endpoints:  []string{addr},
+		MinBackoff: 10 * time.Millisecond,
+		NewClient: func() *Client {
+			return &Client{Timeout: 30 * time.Millisecond}
+		},
+		NewNetworkManager: func(c *Client) *NetworkManager {
+			return &NetworkManager{
+				Client:          c,
+				Dialect:         Dialect1993,
+				EchoInterval:    20 * time.Millisecond,
+				MaxEchoFailures: 1,
+				StateChanged: func(state LinkState) {
+					mu.Lock()
+					states = append(states, state)
+					mu.Unlock()
+				},
+			}
+		},
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the failed echo test brings the link down, it is connected and
+	// signed on again
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(states) >= 3
+	})
+	mu.Lock()
+	if states[0] != LinkUp || states[1] != LinkDown || states[2] != LinkUp {
+		t.Errorf("states should be up, down and up, got %v", states)
+	}
+	mu.Unlock()
+
+	// the link up is signed off
+	host.mu.Lock()
+	host.silent = false
+	host.mu.Unlock()
+	waitFor(t, func() bool { return p.Up() == 1 })
+	if err := p.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if codes := host.received(); codes[len(codes)-1] != Dialect1993.SignOff {
+		t.Errorf("link should be signed off, got %v", codes)
+	}
+}
+
+func TestPoolInFlight(t *testing.T) {
+	// the host holds the response of the first request
+	release := make(chan struct{})
+	addr, closeHost := testHost(t, 1, func(req *Message) *Message {
+		if req.DE11.String() == "000001" {
+			<-release
+		}
+		return echoResponse(req)
+	})
+	defer closeHost()
+	p := &Pool{
+		Endpoints: []string{addr},
+		Size:      2,
+		NewClient: func() *Client {
+			return &Client{Timeout: time.Second}
+		},
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Send(context.Background(), clientTestRequest("000001"))
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.inFlight) == 1
+	})
+	// the same STAN is not sent on another link
+	if _, err := p.Send(context.Background(), clientTestRequest("000001")); err == nil || !strings.Contains(err.Error(), "already in flight") {
+		t.Errorf("request already in flight should fail, got %v", err)
+	}
+	// the next request goes to the idle link
+	if _, err := p.Send(context.Background(), clientTestRequest("000002")); err != nil {
+		t.Error(err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolNoLink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	p := &Pool{Endpoints: []string{addr}, MinBackoff: time.Hour}
+	if err := p.Start(context.Background()); err == nil {
+		t.Error("start without host should fail")
+	}
+	if _, err := p.Send(context.Background(), clientTestRequest("000001")); err != ErrNoLink {
+		t.Errorf("request should fail with ErrNoLink, got %v", err)
+	}
+	p.Close(context.Background())
+	if _, err := p.Send(context.Background(), clientTestRequest("000001")); err != ErrClientClosed {
+		t.Errorf("request should fail with ErrClientClosed, got %v", err)
+	}
+}
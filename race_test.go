@@ -0,0 +1,9 @@
+//go:build race
+// +build race
+
+package iso8583
+
+func init() {
+	// the race detector allocates on its own
+	raceEnabled = true
+}
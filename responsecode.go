@@ -0,0 +1,265 @@
+package iso8583
+
+// ResponseCategory is the meaning of a response or action code (DE39)
+// for the acquirer
+type ResponseCategory string
+
+const (
+	ResponseUnknown     ResponseCategory = ""
+	ResponseApprove     ResponseCategory = "APPROVE"
+	ResponseDecline     ResponseCategory = "DECLINE"
+	ResponseRefer       ResponseCategory = "REFER"
+	ResponsePickUp      ResponseCategory = "PICKUP"
+	ResponseRetry       ResponseCategory = "RETRY"
+	ResponseFormatError ResponseCategory = "FORMAT_ERROR"
+)
+
+// ResponseCode is a response code of ISO 8583:1987 (2 characters) or an
+// action code of ISO 8583:1993 (3 digits)
+type ResponseCode struct {
+	Code        string
+	Description string
+	Category    ResponseCategory
+}
+
+// IsApproved reports whether the code approves the request
+func (c ResponseCode) IsApproved() bool {
+	return c.Category == ResponseApprove
+}
+
+// ShouldRetry reports whether the request may succeed if it is sent again
+func (c ResponseCode) ShouldRetry() bool {
+	return c.Category == ResponseRetry
+}
+
+// responseCode87 is a 1987 response code with its 1993 action code,
+// empty if it has no equivalent
+type responseCode87 struct {
+	ResponseCode
+	action string
+}
+
+var responseCodes87 = []responseCode87{
+	{ResponseCode{"00", "Approved", ResponseApprove}, "000"},
+	{ResponseCode{"01", "Refer to card issuer", ResponseRefer}, "107"},
+	{ResponseCode{"02", "Refer to card issuer's special conditions", ResponseRefer}, "108"},
+	{ResponseCode{"03", "Invalid merchant", ResponseDecline}, "109"},
+	{ResponseCode{"04", "Pick-up", ResponsePickUp}, "200"},
+	{ResponseCode{"05", "Do not honour", ResponseDecline}, "100"},
+	{ResponseCode{"06", "Error", ResponseDecline}, ""},
+	{ResponseCode{"07", "Pick-up card, special condition", ResponsePickUp}, "207"},
+	{ResponseCode{"08", "Honour with identification", ResponseApprove}, "001"},
+	{ResponseCode{"09", "Request in progress", ResponseRetry}, "923"},
+	{ResponseCode{"10", "Approved for partial amount", ResponseApprove}, "002"},
+	{ResponseCode{"11", "Approved (VIP)", ResponseApprove}, "003"},
+	{ResponseCode{"12", "Invalid transaction", ResponseDecline}, "902"},
+	{ResponseCode{"13", "Invalid amount", ResponseDecline}, "110"},
+	{ResponseCode{"14", "Invalid card number (no such number)", ResponseDecline}, "111"},
+	{ResponseCode{"15", "No such issuer", ResponseDecline}, "908"},
+	{ResponseCode{"16", "Approved, update track 3", ResponseApprove}, "004"},
+	{ResponseCode{"17", "Customer cancellation", ResponseDecline}, ""},
+	{ResponseCode{"18", "Customer dispute", ResponseDecline}, ""},
+	{ResponseCode{"19", "Re-enter transaction", ResponseRetry}, "903"},
+	{ResponseCode{"20", "Invalid response", ResponseDecline}, ""},
+	{ResponseCode{"21", "No action taken", ResponseDecline}, ""},
+	{ResponseCode{"22", "Suspected malfunction", ResponseRetry}, ""},
+	{ResponseCode{"23", "Unacceptable transaction fee", ResponseDecline}, "113"},
+	{ResponseCode{"24", "File update not supported by receiver", ResponseDecline}, "301"},
+	{ResponseCode{"25", "Unable to locate record on file", ResponseDecline}, "302"},
+	{ResponseCode{"26", "Duplicate file update record, old record replaced", ResponseDecline}, "303"},
+	{ResponseCode{"27", "File update field edit error", ResponseFormatError}, "304"},
+	{ResponseCode{"28", "File update file locked out", ResponseRetry}, "305"},
+	{ResponseCode{"29", "File update not successful, contact acquirer", ResponseDecline}, "306"},
+	{ResponseCode{"30", "Format error", ResponseFormatError}, "904"},
+	{ResponseCode{"31", "Bank not supported by switch", ResponseDecline}, "905"},
+	{ResponseCode{"32", "Completed partially", ResponseApprove}, ""},
+	{ResponseCode{"33", "Expired card", ResponsePickUp}, "201"},
+	{ResponseCode{"34", "Suspected fraud", ResponsePickUp}, "202"},
+	{ResponseCode{"35", "Card acceptor contact acquirer", ResponsePickUp}, "203"},
+	{ResponseCode{"36", "Restricted card", ResponsePickUp}, "204"},
+	{ResponseCode{"37", "Card acceptor call acquirer security", ResponsePickUp}, "205"},
+	{ResponseCode{"38", "Allowable PIN tries exceeded", ResponsePickUp}, "206"},
+	{ResponseCode{"39", "No credit account", ResponseDecline}, "114"},
+	{ResponseCode{"40", "Requested function not supported", ResponseDecline}, "115"},
+	{ResponseCode{"41", "Lost card", ResponsePickUp}, "208"},
+	{ResponseCode{"42", "No universal account", ResponseDecline}, ""},
+	{ResponseCode{"43", "Stolen card, pick-up", ResponsePickUp}, "209"},
+	{ResponseCode{"44", "No investment account", ResponseDecline}, ""},
+	{ResponseCode{"51", "Not sufficient funds", ResponseDecline}, "116"},
+	{ResponseCode{"52", "No checking account", ResponseDecline}, ""},
+	{ResponseCode{"53", "No savings account", ResponseDecline}, ""},
+	{ResponseCode{"54", "Expired card", ResponseDecline}, "101"},
+	{ResponseCode{"55", "Incorrect personal identification number", ResponseDecline}, "117"},
+	{ResponseCode{"56", "No card record", ResponseDecline}, "118"},
+	{ResponseCode{"57", "Transaction not permitted to cardholder", ResponseDecline}, "119"},
+	{ResponseCode{"58", "Transaction not permitted to terminal", ResponseDecline}, "120"},
+	{ResponseCode{"59", "Suspected fraud", ResponseDecline}, "102"},
+	{ResponseCode{"60", "Card acceptor contact acquirer", ResponseDecline}, "103"},
+	{ResponseCode{"61", "Exceeds withdrawal amount limit", ResponseDecline}, "121"},
+	{ResponseCode{"62", "Restricted card", ResponseDecline}, "104"},
+	{ResponseCode{"63", "Security violation", ResponseDecline}, "122"},
+	{ResponseCode{"64", "Original amount incorrect", ResponseDecline}, ""},
+	{ResponseCode{"65", "Exceeds withdrawal frequency limit", ResponseDecline}, "123"},
+	{ResponseCode{"66", "Card acceptor call acquirer's security department", ResponseDecline}, "105"},
+	{ResponseCode{"67", "Hard capture (requires that card be picked up at ATM)", ResponsePickUp}, ""},
+	{ResponseCode{"68", "Response received too late", ResponseRetry}, "911"},
+	{ResponseCode{"75", "Allowable number of PIN tries exceeded", ResponseDecline}, "106"},
+	{ResponseCode{"90", "Cutoff is in process", ResponseRetry}, "906"},
+	{ResponseCode{"91", "Issuer or switch is inoperative", ResponseRetry}, "907"},
+	{ResponseCode{"92", "Financial institution or intermediate network facility cannot be found for routing", ResponseDecline}, ""},
+	{ResponseCode{"93", "Transaction cannot be completed, violation of law", ResponseDecline}, "124"},
+	{ResponseCode{"94", "Duplicate transmission", ResponseDecline}, "913"},
+	{ResponseCode{"95", "Reconcile error", ResponseDecline}, "915"},
+	{ResponseCode{"96", "System malfunction", ResponseRetry}, "909"},
+}
+
+var actionCodes93 = []ResponseCode{
+	{"000", "Approved", ResponseApprove},
+	{"001", "Honour with identification", ResponseApprove},
+	{"002", "Approved for partial amount", ResponseApprove},
+	{"003", "Approved (VIP)", ResponseApprove},
+	{"004", "Approved, update track 3", ResponseApprove},
+	{"005", "Approved, account type specified by card issuer", ResponseApprove},
+	{"006", "Approved for partial amount, account type specified by card issuer", ResponseApprove},
+	{"007", "Approved, update ICC", ResponseApprove},
+	{"100", "Do not honour", ResponseDecline},
+	{"101", "Expired card", ResponseDecline},
+	{"102", "Suspected fraud", ResponseDecline},
+	{"103", "Card acceptor contact acquirer", ResponseDecline},
+	{"104", "Restricted card", ResponseDecline},
+	{"105", "Card acceptor call acquirer's security department", ResponseDecline},
+	{"106", "Allowable PIN tries exceeded", ResponseDecline},
+	{"107", "Refer to card issuer", ResponseRefer},
+	{"108", "Refer to card issuer's special conditions", ResponseRefer},
+	{"109", "Invalid merchant", ResponseDecline},
+	{"110", "Invalid amount", ResponseDecline},
+	{"111", "Invalid card number", ResponseDecline},
+	{"112", "PIN data required", ResponseDecline},
+	{"113", "Unacceptable fee", ResponseDecline},
+	{"114", "No account of type requested", ResponseDecline},
+	{"115", "Requested function not supported", ResponseDecline},
+	{"116", "Not sufficient funds", ResponseDecline},
+	{"117", "Incorrect PIN", ResponseDecline},
+	{"118", "No card record", ResponseDecline},
+	{"119", "Transaction not permitted to cardholder", ResponseDecline},
+	{"120", "Transaction not permitted to terminal", ResponseDecline},
+	{"121", "Exceeds withdrawal amount limit", ResponseDecline},
+	{"122", "Security violation", ResponseDecline},
+	{"123", "Exceeds withdrawal frequency limit", ResponseDecline},
+	{"124", "Violation of law", ResponseDecline},
+	{"125", "Card not effective", ResponseDecline},
+	{"200", "Do not honour", ResponsePickUp},
+	{"201", "Expired card", ResponsePickUp},
+	{"202", "Suspected fraud", ResponsePickUp},
+	{"203", "Card acceptor contact acquirer", ResponsePickUp},
+	{"204", "Restricted card", ResponsePickUp},
+	{"205", "Card acceptor call acquirer's security department", ResponsePickUp},
+	{"206", "Allowable PIN tries exceeded", ResponsePickUp},
+	{"207", "Special conditions", ResponsePickUp},
+	{"208", "Lost card", ResponsePickUp},
+	{"209", "Stolen card", ResponsePickUp},
+	{"210", "Suspected counterfeit card", ResponsePickUp},
+	{"300", "Successful", ResponseApprove},
+	{"301", "Not supported by receiver", ResponseDecline},
+	{"302", "Unable to locate record on file", ResponseDecline},
+	{"303", "Duplicate record, old record replaced", ResponseDecline},
+	{"304", "Field edit error", ResponseFormatError},
+	{"305", "File locked out", ResponseRetry},
+	{"306", "Not successful", ResponseDecline},
+	{"307", "Format error", ResponseFormatError},
+	{"400", "Accepted", ResponseApprove},
+	{"500", "Reconciled, in balance", ResponseApprove},
+	{"501", "Reconciled, out of balance", ResponseDecline},
+	{"800", "Accepted", ResponseApprove},
+	{"900", "Advice acknowledged, no financial liability accepted", ResponseApprove},
+	{"901", "Advice acknowledged, financial liability accepted", ResponseApprove},
+	{"902", "Invalid transaction", ResponseDecline},
+	{"903", "Re-enter transaction", ResponseRetry},
+	{"904", "Format error", ResponseFormatError},
+	{"905", "Acquirer not supported by switch", ResponseDecline},
+	{"906", "Cutover in process", ResponseRetry},
+	{"907", "Card issuer or switch inoperative", ResponseRetry},
+	{"908", "Transaction destination cannot be found for routing", ResponseDecline},
+	{"909", "System malfunction", ResponseRetry},
+	{"910", "Card issuer signed off", ResponseRetry},
+	{"911", "Card issuer timed out", ResponseRetry},
+	{"912", "Card issuer unavailable", ResponseRetry},
+	{"913", "Duplicate transmission", ResponseDecline},
+	{"914", "Not able to trace back to original transaction", ResponseDecline},
+	{"915", "Reconciliation cutover or checkpoint error", ResponseRetry},
+	{"916", "MAC incorrect", ResponseDecline},
+	{"917", "MAC key sync error", ResponseDecline},
+	{"918", "No communication keys available for use", ResponseDecline},
+	{"919", "Encryption key sync error", ResponseDecline},
+	{"920", "Security software/hardware error, try again", ResponseRetry},
+	{"921", "Security software/hardware error, no action", ResponseDecline},
+	{"922", "Message number out of sequence", ResponseDecline},
+	{"923", "Request in progress", ResponseRetry},
+}
+
+var (
+	responseCodesByCode87 = make(map[string]responseCode87, len(responseCodes87))
+	actionCodesByCode93   = make(map[string]ResponseCode, len(actionCodes93))
+	// responseCodesByAction is the first 1987 response code of each 1993 action code
+	responseCodesByAction = make(map[string]string, len(responseCodes87))
+)
+
+func init() {
+	for _, c := range responseCodes87 {
+		responseCodesByCode87[c.Code] = c
+		if _, ok := responseCodesByAction[c.action]; c.action != "" && !ok {
+			responseCodesByAction[c.action] = c.Code
+		}
+	}
+	for _, c := range actionCodes93 {
+		actionCodesByCode93[c.Code] = c
+	}
+}
+
+// ResponseCode1987 returns the ISO 8583:1987 response code, e.g. 05
+func ResponseCode1987(code string) (ResponseCode, bool) {
+	c, ok := responseCodesByCode87[code]
+	return c.ResponseCode, ok
+}
+
+// ActionCode1993 returns the ISO 8583:1993 action code, e.g. 100
+func ActionCode1993(code string) (ResponseCode, bool) {
+	c, ok := actionCodesByCode93[code]
+	return c, ok
+}
+
+// ActionCodeFor1987 maps a 1987 response code to its 1993 action code
+func ActionCodeFor1987(code string) (string, bool) {
+	c, ok := responseCodesByCode87[code]
+	return c.action, ok && c.action != ""
+}
+
+// ResponseCodeFor1993 maps a 1993 action code to its 1987 response code
+func ResponseCodeFor1993(code string) (string, bool) {
+	c, ok := responseCodesByAction[code]
+	return c, ok
+}
+
+// ResponseCode returns the code of DE39, a 1987 response code if the MTI
+// is of version 0, a 1993 action code otherwise
+func (m *Message) ResponseCode() (ResponseCode, bool) {
+	if m.DE39 == nil {
+		return ResponseCode{}, false
+	}
+	if len(m.Mti) > 0 && m.Mti[0] == '0' {
+		return ResponseCode1987(m.DE39.String())
+	}
+	return ActionCode1993(m.DE39.String())
+}
+
+// IsApproved reports whether DE39 approves the request
+func (m *Message) IsApproved() bool {
+	c, _ := m.ResponseCode()
+	return c.IsApproved()
+}
+
+// ShouldRetry reports whether the request of DE39 may succeed if it is sent again
+func (m *Message) ShouldRetry() bool {
+	c, _ := m.ResponseCode()
+	return c.ShouldRetry()
+}
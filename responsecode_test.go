@@ -0,0 +1,106 @@
+package iso8583
+
+import "testing"
+
+func TestResponseCodeCatalog(t *testing.T) {
+	c, ok := ResponseCode1987("05")
+	if !ok || c.Category != ResponseDecline || c.Description != "Do not honour" {
+		t.Errorf("unexpected 05: %+v", c)
+	}
+	c, ok = ActionCode1993("911")
+	if !ok || !c.ShouldRetry() {
+		t.Errorf("911 should be retried: %+v", c)
+	}
+	if _, ok := ActionCode1993("05"); ok {
+		t.Error("05 should not be a 1993 action code")
+	}
+
+	if code, ok := ActionCodeFor1987("51"); !ok || code != "116" {
+		t.Errorf("51 should map to 116, got %s", code)
+	}
+	if code, ok := ResponseCodeFor1993("116"); !ok || code != "51" {
+		t.Errorf("116 should map to 51, got %s", code)
+	}
+	if code, ok := ResponseCodeFor1993("909"); !ok || code != "96" {
+		t.Errorf("909 should map to 96, got %s", code)
+	}
+	if _, ok := ActionCodeFor1987("17"); ok {
+		t.Error("17 should have no action code")
+	}
+
+	// every mapped code exists in the other catalog with the same approval
+	for _, c := range responseCodes87 {
+		if c.action == "" {
+			continue
+		}
+		a, ok := ActionCode1993(c.action)
+		if !ok {
+			t.Errorf("%s maps to unknown action code %s", c.Code, c.action)
+			continue
+		}
+		if a.IsApproved() != c.IsApproved() {
+			t.Errorf("%s and %s differ in approval", c.Code, c.action)
+		}
+	}
+}
+
+func TestMessageResponseCode(t *testing.T) {
+	var scenarios = []struct {
+		mti      string
+		de39     string
+		approved bool
+		retry    bool
+	}{
+		{mti: "0110", de39: "00", approved: true},
+		{mti: "0110", de39: "05"},
+		{mti: "0110", de39: "96", retry: true},
+		{mti: "0110", de39: "N7"},
+		{mti: "1110", de39: "005", approved: true},
+		{mti: "1110", de39: "911", retry: true},
+		{mti: "1110", de39: "999"},
+	}
+	for _, scenario := range scenarios {
+		m := &Message{Mti: scenario.mti, DE39: NewAlphanumeric(scenario.de39)}
+		if m.IsApproved() != scenario.approved {
+			t.Errorf("%s %s: approved should be %v", scenario.mti, scenario.de39, scenario.approved)
+		}
+		if m.ShouldRetry() != scenario.retry {
+			t.Errorf("%s %s: retry should be %v", scenario.mti, scenario.de39, scenario.retry)
+		}
+	}
+	if (&Message{Mti: "0110"}).IsApproved() {
+		t.Error("message without DE39 should not be approved")
+	}
+}
+
+func TestResponseCodeLength(t *testing.T) {
+	var scenarios = []struct {
+		mti     string
+		de39    string
+		encoded string
+	}{
+		// 2 characters in version 0 (1987), e.g. Visa N7, 3 digits in 1993
+		{mti: "0110", de39: "00", encoded: "0110" + "0000000002800000" + "00" + "TERM0001"},
+		{mti: "0110", de39: "N7", encoded: "0110" + "0000000002800000" + "N7" + "TERM0001"},
+		{mti: "1110", de39: "000", encoded: "1110" + "0000000002800000" + "000" + "TERM0001"},
+	}
+	for _, scenario := range scenarios {
+		m := &Message{Mti: scenario.mti, DE39: NewAlphanumeric(scenario.de39), DE41: NewANS("TERM0001")}
+		b, err := m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, string(b), scenario.encoded, scenario.de39)
+
+		decoded := &Message{}
+		if err := decoded.Decode(b); err != nil {
+			t.Fatalf("%s %s: %v", scenario.mti, scenario.de39, err)
+		}
+		equals(t, decoded.DE39.String(), scenario.de39, scenario.de39)
+		equals(t, decoded.DE41.String(), "TERM0001", scenario.de39)
+	}
+
+	if _, err := (&Message{Mti: "0110", DE39: NewAlphanumeric("000")}).Encode(); err == nil {
+		t.Error("expecting error, DE39 has 2 characters in version 0")
+	}
+}
@@ -0,0 +1,76 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReasonTimeout is the message reason code (DE25) of a reversal sent after
+// the response timeout of the original request, ISO 8583:1993
+const ReasonTimeout = "4021"
+
+// Reversible reports whether a request of the MTI is reversed if its
+// response does not arrive, the authorizations and financial requests,
+// e.g. 1100, 1200 and their repeats
+func Reversible(mti string) bool {
+	if len(mti) != 4 || !numberRegex.MatchString(mti) {
+		return false
+	}
+	return (mti[1] == '1' || mti[1] == '2') && mti[2] == '0'
+}
+
+// ReversalOf returns the reversal advice of the request, e.g. 1420 for
+// 1200, with the same STAN, RRN, terminal, card and amount. The original
+// data elements are in DE56 for the version 1993, in DE90 for 1987.
+func ReversalOf(m *Message) (*Message, error) {
+	if !Reversible(m.Mti) {
+		return nil, errors.New("MTI is not reversible: " + m.Mti)
+	}
+	r := &Message{
+		encoder: m.encoder,
+		DE2:     m.DE2,
+		DE3:     m.DE3,
+		DE4:     m.DE4,
+		DE5:     m.DE5,
+		DE6:     m.DE6,
+		DE11:    m.DE11,
+		DE12:    m.DE12,
+		DE14:    m.DE14,
+		DE22:    m.DE22,
+		DE32:    m.DE32,
+		DE33:    m.DE33,
+		DE37:    m.DE37,
+		DE41:    m.DE41,
+		DE42:    m.DE42,
+		DE49:    m.DE49,
+		DE50:    m.DE50,
+		DE51:    m.DE51,
+	}
+	r.Mti = m.Mti[:1] + "420"
+	r.DE7 = &DateTime{}
+	if err := r.DE7.SetTime(time.Now(), time.UTC); err != nil {
+		return nil, err
+	}
+
+	mti := m.Mti[:3] + "0"
+	stan, _ := m.fieldString(11)
+	if stan == "" {
+		return nil, errors.New("request has no STAN")
+	}
+	acquirer, _ := m.fieldString(32)
+	if m.Mti[0] == '0' {
+		// MTI, STAN, transmission date and time, acquirer and forwarder
+		transmission, _ := m.fieldString(7)
+		forwarder, _ := m.fieldString(33)
+		r.DE90 = NewNumeric(fmt.Sprintf("%s%06s%010s%011s%011s", mti, stan, transmission, acquirer, forwarder))
+		return r, nil
+	}
+
+	// MTI, STAN, local date and time, LLVAR acquirer
+	local, _ := m.fieldString(12)
+	r.DE56 = NewNumeric(fmt.Sprintf("%s%06s%012s%02d%s", mti, stan, local, len(acquirer), acquirer))
+	r.DE24 = NewNumeric("400")
+	r.DE25 = NewNumeric(ReasonTimeout)
+	return r, nil
+}
@@ -0,0 +1,152 @@
+package iso8583
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReversible(t *testing.T) {
+	for mti, expected := range map[string]bool{"1100": true, "1200": true, "1201": true, "0200": true, "1420": false, "1804": false, "1110": false, "12": false} {
+		if Reversible(mti) != expected {
+			t.Errorf("%s should be reversible: %v", mti, expected)
+		}
+	}
+}
+
+func TestReversalOf(t *testing.T) {
+	m := &Message{
+		DE2:  NewNumeric("000000000000000000"),
+		DE3:  NewNumeric("092000"),
+		DE4:  NewNumeric("20000"),
+		DE11: NewNumeric("030402"),
+		DE12: NewDateTime("950123154952"),
+		DE32: NewNumeric("10076401251"),
+		DE37: NewANP("012401"),
+		DE41: NewANS("NJ020111"),
+	}
+	m.Mti = "1201"
+	r, err := ReversalOf(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Mti != "1420" {
+		t.Errorf("MTI should be 1420, got %s", r.Mti)
+	}
+	// DE56 of TestReversalAdvice
+	if r.DE56.String() != "12000304029501231549521110076401251" {
+		t.Errorf("invalid DE56 %s", r.DE56)
+	}
+	if r.DE24.String() != "400" || r.DE25.String() != ReasonTimeout || r.DE90 != nil {
+		t.Errorf("invalid function or reason code %s %s", r.DE24, r.DE25)
+	}
+	if r.DE11.String() != "030402" || r.DE37.String() != "012401" || r.DE41.String() != "NJ020111" || r.DE4.String() != "20000" {
+		t.Errorf("reversal should keep the fields of the request, got %v", r)
+	}
+	if r.DE7 == nil {
+		t.Error("reversal should have a transmission date and time")
+	}
+	if _, err := r.Encode(); err != nil {
+		t.Error(err)
+	}
+
+	m = &Message{
+		DE7:  NewDateTime("0123205206"),
+		DE11: NewNumeric("75809"),
+		DE32: NewNumeric("123456"),
+	}
+	m.Mti = "0200"
+	r, err = ReversalOf(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Mti != "0420" || r.DE56 != nil || r.DE24 != nil {
+		t.Errorf("invalid 1987 reversal %v", r)
+	}
+	if r.DE90.String() != "020007580901232052060000012345600000000000" {
+		t.Errorf("invalid DE90 %s", r.DE90)
+	}
+	if _, err := r.Encode(); err != nil {
+		t.Error(err)
+	}
+
+	m.Mti = "0800"
+	if _, err := ReversalOf(m); err == nil {
+		t.Error("network management request should not be reversed")
+	}
+	m.Mti, m.DE11 = "0200", nil
+	if _, err := ReversalOf(m); err == nil {
+		t.Error("request without STAN should not be reversed")
+	}
+}
+
+func TestClientTimeoutReversal(t *testing.T) {
+	// the host answers the reversals only
+	addr, closeHost := testHost(t, 1, func(req *Message) *Message {
+		if req.Mti[1] != '4' {
+			return nil
+		}
+		resp := echoResponse(req)
+		resp.Mti = "1430"
+		resp.DE39 = NewAlphanumeric("400")
+		return resp
+	})
+	defer closeHost()
+
+	type timeout struct {
+		reversal *Message
+		err      error
+	}
+	timedOut := make(chan timeout, 1)
+	acked := make(chan *Message, 1)
+	c := &Client{
+		Addr:    addr,
+		Timeout: 50 * time.Millisecond,
+		TimedOut: func(m, reversal *Message, err error) {
+			timedOut <- timeout{reversal, err}
+		},
+	}
+	defer c.Close()
+	c.Reversals = &StoreAndForward{
+		Sender: c,
+		Store:  &MemoryStore{},
+		Acknowledged: func(m, resp *Message) {
+			acked <- resp
+		},
+	}
+	if err := c.Reversals.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Reversals.Stop()
+
+	req := clientTestRequest("000001")
+	req.Mti = "1100"
+	req.DE12 = NewDateTime("950123154952")
+	if _, err := c.Send(context.Background(), req); err != ErrTimeout {
+		t.Fatalf("request should time out, got %v", err)
+	}
+	res := <-timedOut
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	reversal := res.reversal
+	if reversal.Mti != "1420" || reversal.DE11.String() != "000001" || reversal.DE56.String() != "110000000195012315495200" {
+		t.Errorf("invalid reversal %s %s %s", reversal.Mti, reversal.DE11, reversal.DE56)
+	}
+	select {
+	case resp := <-acked:
+		if resp.Mti != "1430" || resp.DE11.String() != "000001" {
+			t.Errorf("invalid reversal response %s %s", resp.Mti, resp.DE11)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reversal should be acknowledged")
+	}
+
+	// a request without STAN is not reversed, TimedOut has the error
+	if _, err := c.Send(context.Background(), &Message{Mti: "1200"}); err != ErrTimeout {
+		t.Fatalf("request should time out, got %v", err)
+	}
+	if res := <-timedOut; res.err == nil || res.reversal != nil {
+		t.Errorf("reversal without STAN should fail, got %v", res.err)
+	}
+}
@@ -0,0 +1,444 @@
+package iso8583
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sender sends requests and returns their responses, e.g. Client or Pool
+type Sender interface {
+	Send(ctx context.Context, m *Message) (*Message, error)
+}
+
+// RepeatMTI returns the MTI of the repeat of a request, e.g. 1421 for 1420
+func RepeatMTI(mti string) (string, error) {
+	if len(mti) != 4 || !numberRegex.MatchString(mti) {
+		return "", errors.New("invalid MTI: " + mti)
+	}
+	if (mti[3]-'0')%2 != 0 {
+		return mti, nil
+	}
+	return mti[:3] + string(mti[3]+1), nil
+}
+
+// SAFEntry is a message stored for forwarding
+type SAFEntry struct {
+	// ID orders the entries by their time of storing
+	ID string
+	// Message is the encoded message
+	Message []byte
+	// Encoding is the encoding of Message, e.g. ASCII
+	Encoding int
+	// Attempts is the number of times the message was sent
+	Attempts int
+	// Created is the time of storing
+	Created time.Time
+	// Next is the time of the next attempt
+	Next time.Time
+}
+
+// SAFStore keeps the entries of a StoreAndForward
+type SAFStore interface {
+	// Put adds the entry, or updates the entry with the same ID
+	Put(e *SAFEntry) error
+	// Delete removes the entry of the ID
+	Delete(id string) error
+	// List returns the entries in order of ID
+	List() ([]*SAFEntry, error)
+}
+
+// MemoryStore is a SAFStore in memory, the entries are lost on exit
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]SAFEntry
+}
+
+func (s *MemoryStore) Put(e *SAFEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]SAFEntry)
+	}
+	s.entries[e.ID] = *e
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *MemoryStore) List() ([]*SAFEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]*SAFEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		e := e
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// FileStore is a SAFStore keeping every entry in a JSON file of a directory,
+// written atomically
+type FileStore struct {
+	dir string
+}
+
+// safFileExt is the extension of the entry files
+const safFileExt = ".saf"
+
+// NewFileStore returns the store of the directory, created if missing
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+safFileExt)
+}
+
+func (s *FileStore) Put(e *SAFEntry) error {
+	if e.ID == "" || strings.ContainsAny(e.ID, `/\.`) {
+		return errors.New("invalid entry ID: " + e.ID)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(e.ID), b)
+}
+
+// writeFileAtomic writes the file by renaming a temporary file, a crash
+// leaves either the old or the new content
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s *FileStore) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) List() ([]*SAFEntry, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*SAFEntry
+	// ReadDir sorts by name, the names are the IDs
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != safFileExt {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(s.dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		e := &SAFEntry{}
+		if err := json.Unmarshal(b, e); err != nil {
+			return nil, errors.New("invalid entry " + fi.Name() + ": " + err.Error())
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// DefaultSAFRetry waits 10 seconds before the first repeat, doubled after
+// every attempt up to 5 minutes
+func DefaultSAFRetry(attempts int) time.Duration {
+	d := 10 * time.Second
+	for i := 1; i < attempts && d < 5*time.Minute; i++ {
+		d *= 2
+	}
+	if d > 5*time.Minute {
+		d = 5 * time.Minute
+	}
+	return d
+}
+
+// ErrNoSAFStore is returned by a StoreAndForward without Store
+var ErrNoSAFStore = errors.New("store and forward without store")
+
+// StoreAndForward delivers advices and reversals, e.g. 1420, even if the
+// host is down. The messages are stored, and sent in order of storing, one
+// at a time, until their response arrives, e.g. 1430. The attempts after the first one
+// are sent with the repeat MTI, e.g. 1421.
+type StoreAndForward struct {
+	// Sender sends the messages, e.g. a Client or a Pool
+	Sender Sender
+	// Store keeps the messages, it is required, e.g. a FileStore. With a
+	// MemoryStore the messages not forwarded are lost on exit.
+	Store SAFStore
+	// Retry returns the wait after the failed attempt number attempts,
+	// nil means DefaultSAFRetry
+	Retry func(attempts int) time.Duration
+	// AttemptTimeout limits the wait for the response of each attempt, even
+	// with a Sender without timeout, 0 means 30 seconds
+	AttemptTimeout time.Duration
+	// MaxAttempts is the number of attempts before the message expires,
+	// 0 means no limit
+	MaxAttempts int
+	// MaxAge is the time after storing the message expires, 0 means no limit
+	MaxAge time.Duration
+	// Acknowledged is called with a message and its response
+	Acknowledged func(m, resp *Message)
+	// Expired is called with a message dropped after its last attempt,
+	// and the error of the attempt
+	Expired func(m *Message, err error)
+
+	mu     sync.Mutex
+	lastID int64
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (s *StoreAndForward) init() error {
+	if s.Store == nil {
+		return ErrNoSAFStore
+	}
+	if s.wake == nil {
+		s.wake = make(chan struct{}, 1)
+	}
+	return nil
+}
+
+// Enqueue stores the message for forwarding, and returns its ID. It is
+// sent when the messages stored before are acknowledged or expired.
+func (s *StoreAndForward) Enqueue(m *Message) (string, error) {
+	b, err := m.Encode()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	if err := s.init(); err != nil {
+		s.mu.Unlock()
+		return "", err
+	}
+	// the IDs are ordered, even with the same time
+	id := now.UnixNano()
+	if id <= s.lastID {
+		id = s.lastID + 1
+	}
+	s.lastID = id
+	store, wake := s.Store, s.wake
+	s.mu.Unlock()
+
+	e := &SAFEntry{
+		// the padding keeps the order of the IDs as strings
+		ID:       fmt.Sprintf("%020d", id),
+		Message:  b,
+		Encoding: m.encoder,
+		Created:  now,
+		Next:     now,
+	}
+	if err := store.Put(e); err != nil {
+		return "", err
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return e.ID, nil
+}
+
+// Pending returns the number of stored messages
+func (s *StoreAndForward) Pending() (int, error) {
+	s.mu.Lock()
+	err := s.init()
+	store := s.Store
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := store.List()
+	return len(entries), err
+}
+
+// Start starts forwarding the stored messages, including the ones stored
+// before a restart
+func (s *StoreAndForward) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return errors.New("store and forward already started")
+	}
+	if err := s.init(); err != nil {
+		return err
+	}
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+	return nil
+}
+
+// Stop stops forwarding, the message being sent is sent again as a repeat
+// on the next Start
+func (s *StoreAndForward) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+func (s *StoreAndForward) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		wait, err := s.forward(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// store error, e.g. a full disk
+			wait = s.retry(1)
+		}
+		if wait == 0 {
+			continue
+		}
+		// without message, wait for the next one
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (s *StoreAndForward) retry(attempts int) time.Duration {
+	if s.Retry == nil {
+		return DefaultSAFRetry(attempts)
+	}
+	return s.Retry(attempts)
+}
+
+// forward makes an attempt of the first stored message, and returns the wait
+// before the next one, -1 if there is no message
+func (s *StoreAndForward) forward(ctx context.Context) (time.Duration, error) {
+	entries, err := s.Store.List()
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return -1, nil
+	}
+	e := entries[0]
+	if wait := time.Until(e.Next); wait > 0 {
+		return wait, nil
+	}
+
+	m := &Message{encoder: e.Encoding}
+	if err := m.Decode(e.Message); err != nil {
+		// a message which can not be decoded is never sent
+		if err := s.Store.Delete(e.ID); err != nil {
+			return 0, err
+		}
+		if s.Expired != nil {
+			s.Expired(m, err)
+		}
+		return 0, nil
+	}
+	if e.Attempts > 0 {
+		if m.Mti, err = RepeatMTI(m.Mti); err != nil {
+			return 0, err
+		}
+	}
+	// the attempt is stored first, a message possibly received by the host
+	// is sent again as a repeat, even after a crash
+	e.Attempts++
+	e.Next = time.Now().Add(s.retry(e.Attempts))
+	if err := s.Store.Put(e); err != nil {
+		return 0, err
+	}
+	timeout := s.AttemptTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	resp, sendErr := s.Sender.Send(attemptCtx, m)
+	cancel()
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+	// e.g. a reject of the host, the message is not acknowledged
+	if sendErr == nil {
+		expected, _ := ResponseMTI(m.Mti)
+		if resp == nil {
+			sendErr = errors.New("no response to " + m.Mti)
+		} else if resp.Mti != expected {
+			sendErr = errors.New("unexpected response MTI " + resp.Mti + " to " + m.Mti)
+		}
+	}
+	if sendErr == nil {
+		if err := s.Store.Delete(e.ID); err != nil {
+			return 0, err
+		}
+		if s.Acknowledged != nil {
+			s.Acknowledged(m, resp)
+		}
+		return 0, nil
+	}
+
+	if (s.MaxAttempts > 0 && e.Attempts >= s.MaxAttempts) ||
+		(s.MaxAge > 0 && time.Since(e.Created) >= s.MaxAge) {
+		if err := s.Store.Delete(e.ID); err != nil {
+			return 0, err
+		}
+		if s.Expired != nil {
+			s.Expired(m, sendErr)
+		}
+		return 0, nil
+	}
+	if wait := time.Until(e.Next); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
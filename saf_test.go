@@ -0,0 +1,292 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// senderFunc is a function used as Sender
+type senderFunc func(ctx context.Context, m *Message) (*Message, error)
+
+func (f senderFunc) Send(ctx context.Context, m *Message) (*Message, error) {
+	return f(ctx, m)
+}
+
+func safTestReversal(stan string) *Message {
+	m := clientTestRequest(stan)
+	m.Mti = "1420"
+	m.DE24 = NewNumeric("400")
+	return m
+}
+
+func TestRepeatMTI(t *testing.T) {
+	for mti, expected := range map[string]string{"1420": "1421", "1421": "1421", "0420": "0421", "1220": "1221"} {
+		if repeat, err := RepeatMTI(mti); err != nil || repeat != expected {
+			t.Errorf("repeat of %s should be %s, got %s %v", mti, expected, repeat, err)
+		}
+	}
+	if _, err := RepeatMTI("14X0"); err == nil {
+		t.Error("invalid MTI should fail")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Round(0)
+	for _, id := range []string{"0002", "0001", "0003"} {
+		if err := s.Put(&SAFEntry{ID: id, Message: []byte("1420" + id), Created: now, Next: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Put(&SAFEntry{ID: "../x"}); err == nil {
+		t.Error("invalid ID should fail")
+	}
+	if err := s.Put(&SAFEntry{ID: "0002", Message: []byte("14200002"), Attempts: 2, Created: now, Next: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("0003"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("0004"); err != nil {
+		t.Errorf("missing entry should be deleted, got %v", err)
+	}
+
+	// the entries are read again by a new store
+	s, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*SAFEntry{
+		{ID: "0001", Message: []byte("14200001"), Created: now, Next: now},
+		{ID: "0002", Message: []byte("14200002"), Attempts: 2, Created: now, Next: now},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("store should have %d entries, got %d", len(expected), len(entries))
+	}
+	for i := range expected {
+		if !entries[i].Created.Equal(now) || !entries[i].Next.Equal(now) {
+			t.Errorf("entry %s times should be kept", entries[i].ID)
+		}
+		entries[i].Created, entries[i].Next = now, now
+		if !reflect.DeepEqual(entries[i], expected[i]) {
+			t.Errorf("entry %d should be %+v, got %+v", i, expected[i], entries[i])
+		}
+	}
+}
+
+func TestStoreAndForward(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	acked := make(chan *Message, 2)
+	s := &StoreAndForward{
+		// the host is down for the first two attempts
+		Sender: senderFunc(func(ctx context.Context, m *Message) (*Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, m.Mti+" "+m.DE11.String())
+			if len(sent) <= 2 {
+				return nil, ErrTimeout
+			}
+			resp := echoResponse(m)
+			resp.Mti = "1430"
+			return resp, nil
+		}),
+		Store: &MemoryStore{},
+		Retry: func(attempts int) time.Duration { return time.Millisecond },
+		Acknowledged: func(m, resp *Message) {
+			acked <- m
+		},
+	}
+	for _, stan := range []string{"000001", "000002"} {
+		if _, err := s.Enqueue(safTestReversal(stan)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-acked:
+		case <-time.After(2 * time.Second):
+			t.Fatal("messages should be acknowledged")
+		}
+	}
+	mu.Lock()
+	expected := []string{"1420 000001", "1421 000001", "1421 000001", "1420 000002"}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Errorf("sent messages should be %v, got %v", expected, sent)
+	}
+	mu.Unlock()
+	if pending, err := s.Pending(); err != nil || pending != 0 {
+		t.Errorf("store should be empty, got %d %v", pending, err)
+	}
+}
+
+func TestStoreAndForwardExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the messages are stored before the start
+	s := &StoreAndForward{Store: store}
+	if _, err := s.Enqueue(safTestReversal("000001")); err != nil {
+		t.Fatal(err)
+	}
+
+	hostErr := errors.New("host down")
+	expired := make(chan error, 1)
+	attempts := 0
+	s = &StoreAndForward{
+		Sender: senderFunc(func(ctx context.Context, m *Message) (*Message, error) {
+			attempts++
+			return nil, hostErr
+		}),
+		Store:       store,
+		Retry:       func(attempts int) time.Duration { return time.Millisecond },
+		MaxAttempts: 3,
+		Expired: func(m *Message, err error) {
+			if m.DE11.String() != "000001" {
+				t.Errorf("unexpected expired message %v", m)
+			}
+			expired <- err
+		},
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	select {
+	case err := <-expired:
+		if err != hostErr {
+			t.Errorf("error should be the one of the last attempt, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message should expire")
+	}
+	s.Stop()
+	if attempts != 3 {
+		t.Errorf("message should be sent 3 times, got %d", attempts)
+	}
+	if pending, err := s.Pending(); err != nil || pending != 0 {
+		t.Errorf("store should be empty, got %d %v", pending, err)
+	}
+}
+
+func TestStoreAndForwardUnexpectedResponse(t *testing.T) {
+	var sent []string
+	acked := make(chan *Message, 1)
+	s := &StoreAndForward{
+		// the first response is not the one of a reversal
+		Sender: senderFunc(func(ctx context.Context, m *Message) (*Message, error) {
+			sent = append(sent, m.Mti)
+			resp := echoResponse(m)
+			if len(sent) == 1 {
+				resp.Mti = "1410"
+			}
+			return resp, nil
+		}),
+		Store: &MemoryStore{},
+		Retry: func(attempts int) time.Duration { return time.Millisecond },
+		Acknowledged: func(m, resp *Message) {
+			acked <- resp
+		},
+	}
+	if _, err := s.Enqueue(safTestReversal("000001")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	select {
+	case resp := <-acked:
+		if resp.Mti != "1430" {
+			t.Errorf("acknowledgement should be 1430, got %s", resp.Mti)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message should be acknowledged")
+	}
+	s.Stop()
+	if !reflect.DeepEqual(sent, []string{"1420", "1421"}) {
+		t.Errorf("message should be sent again as a repeat, got %v", sent)
+	}
+}
+
+func TestStoreAndForwardWithoutStore(t *testing.T) {
+	s := &StoreAndForward{Sender: senderFunc(func(ctx context.Context, m *Message) (*Message, error) {
+		return echoResponse(m), nil
+	})}
+	if _, err := s.Enqueue(safTestReversal("000001")); err != ErrNoSAFStore {
+		t.Errorf("Enqueue should fail with ErrNoSAFStore, got %v", err)
+	}
+	if err := s.Start(); err != ErrNoSAFStore {
+		t.Errorf("Start should fail with ErrNoSAFStore, got %v", err)
+	}
+	if _, err := s.Pending(); err != ErrNoSAFStore {
+		t.Errorf("Pending should fail with ErrNoSAFStore, got %v", err)
+	}
+}
+
+func TestStoreAndForwardAttemptTimeout(t *testing.T) {
+	expired := make(chan error, 1)
+	attempts := 0
+	s := &StoreAndForward{
+		// the host never answers
+		Sender: senderFunc(func(ctx context.Context, m *Message) (*Message, error) {
+			attempts++
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		Store:          &MemoryStore{},
+		Retry:          func(attempts int) time.Duration { return time.Millisecond },
+		AttemptTimeout: 20 * time.Millisecond,
+		MaxAttempts:    2,
+		Expired: func(m *Message, err error) {
+			expired <- err
+		},
+	}
+	if _, err := s.Enqueue(safTestReversal("000001")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	select {
+	case err := <-expired:
+		if err != context.DeadlineExceeded {
+			t.Errorf("error should be the timeout of the attempt, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message should expire after 2 unanswered attempts")
+	}
+	s.Stop()
+	if attempts != 2 {
+		t.Errorf("message should be sent 2 times, got %d", attempts)
+	}
+}
@@ -0,0 +1,146 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// securityCodesLength is the number of digits of the codes of DE53
+const securityCodesLength = 8
+
+// SecurityControl is the security related control information of DE53,
+// four codes of 2 digits followed by the key serial number (KSN) of DUKPT
+// in hex, e.g. 20010100 and FFFF9876543210E00001 for a format 0 PIN
+// block enciphered with a TDES DUKPT key
+type SecurityControl struct {
+	// FormatCode is the security format code
+	FormatCode string
+	// Algorithm is the PIN encryption algorithm identifier
+	Algorithm string
+	// PINBlockFormat is the PIN block format code, e.g. 01 for ISO 9564 format 0
+	PINBlockFormat string
+	// KeyIndex is the index of the zone key, or of the BDK with DUKPT
+	KeyIndex string
+	// KSN is the key serial number of DUKPT, empty without DUKPT
+	KSN []byte
+}
+
+// ParseSecurityControl parses the value of DE53
+func ParseSecurityControl(value string) (*SecurityControl, error) {
+	s := &SecurityControl{}
+	if err := s.parse([]byte(value)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SecurityControl) parse(val []byte) error {
+	if len(val) < securityCodesLength || !numberRegex.Match(val[:securityCodesLength]) {
+		return errors.New("invalid security control codes")
+	}
+	ksn, err := hex.DecodeString(string(val[securityCodesLength:]))
+	if err != nil {
+		return errors.New("invalid KSN: " + err.Error())
+	}
+	s.FormatCode = string(val[0:2])
+	s.Algorithm = string(val[2:4])
+	s.PINBlockFormat = string(val[4:6])
+	s.KeyIndex = string(val[6:8])
+	s.KSN = nil
+	if len(ksn) > 0 {
+		s.KSN = ksn
+	}
+	return nil
+}
+
+// Bytes returns the value of DE53, the codes and the KSN in upper case hex
+func (s *SecurityControl) Bytes() ([]byte, error) {
+	for _, code := range []string{s.FormatCode, s.Algorithm, s.PINBlockFormat, s.KeyIndex} {
+		if len(code) != 2 || !numberRegex.MatchString(code) {
+			return nil, errors.New("invalid security control code: " + code)
+		}
+	}
+	codes := s.FormatCode + s.Algorithm + s.PINBlockFormat + s.KeyIndex
+	return []byte(codes + strings.ToUpper(hex.EncodeToString(s.KSN))), nil
+}
+
+func (s *SecurityControl) Encode(encoder, length int, format, validator string) ([]byte, error) {
+	val, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBytes(val, validator); err != nil {
+		return []byte{}, err
+	}
+	if format == "" {
+		return []byte{}, errors.New("SecurityControl has variable length")
+	}
+	if len(val) > length {
+		return nil, errors.New("invalid value length")
+	}
+	lInd, err := lengthIndicator(encoder, len(val), format)
+	if err != nil {
+		return nil, err
+	}
+	val = append(lInd, val...)
+
+	switch encoder {
+	case BCDIC:
+		panic("implement me")
+	default: //ASCII encoding
+		return val, nil
+	}
+}
+
+func (s *SecurityControl) Decode(raw []byte, encoder, length int, format, validator string) (int, error) {
+	var nextFieldOffset int
+	switch encoder {
+	case BCDIC:
+	case ASCII:
+		if format == "" {
+			return 0, errors.New("SecurityControl has variable length")
+		}
+		l, lenOfLen, err := getFieldLength(raw, encoder, length, format)
+		if err != nil {
+			return 0, err
+		}
+		val := raw[lenOfLen : l+lenOfLen]
+		nextFieldOffset = lenOfLen + l
+		if err := validateBytes(val, validator); err != nil {
+			return nextFieldOffset, err
+		}
+		if err := s.parse(val); err != nil {
+			return nextFieldOffset, err
+		}
+	}
+	return nextFieldOffset, nil
+}
+
+func (s *SecurityControl) isEmpty() bool {
+	return s.FormatCode == "" && s.Algorithm == "" && s.PINBlockFormat == "" && s.KeyIndex == "" && len(s.KSN) == 0
+}
+
+func (s SecurityControl) String() string {
+	b, _ := s.Bytes()
+	return string(b)
+}
+
+// MarshalJSON encodes the value of DE53 as a string
+func (s *SecurityControl) MarshalJSON() ([]byte, error) {
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON decodes the value of DE53 from a string
+func (s *SecurityControl) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return s.parse([]byte(value))
+}
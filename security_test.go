@@ -0,0 +1,73 @@
+package iso8583
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSecurityControl(t *testing.T) {
+	s, err := ParseSecurityControl("20010100FFFF9876543210E00001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, s.FormatCode+"|"+s.Algorithm+"|"+s.PINBlockFormat+"|"+s.KeyIndex, "20|01|01|00", "codes")
+	if !bytes.Equal(s.KSN, []byte{0xFF, 0xFF, 0x98, 0x76, 0x54, 0x32, 0x10, 0xE0, 0x00, 0x01}) {
+		t.Errorf("KSN should be FFFF9876543210E00001, got %X", s.KSN)
+	}
+	b, err := s.Encode(ASCII, 48, "LLVAR", "BN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "2820010100FFFF9876543210E00001", "")
+
+	decoded := &SecurityControl{}
+	n, err := decoded.Decode([]byte("0820010200rest"), ASCII, 48, "LLVAR", "BN")
+	if err != nil || n != 10 {
+		t.Fatalf("DE53 without KSN should be decoded, got %d %v", n, err)
+	}
+	if decoded.PINBlockFormat != "02" || decoded.KSN != nil {
+		t.Errorf("DE53 should have PIN block format 02 and no KSN, got %#v", decoded)
+	}
+
+	for _, value := range []string{"2001", "2001A100", "20010100FFF"} {
+		if _, err := ParseSecurityControl(value); err == nil {
+			t.Errorf("invalid DE53 %s should fail", value)
+		}
+	}
+	if _, err := (&SecurityControl{FormatCode: "2", Algorithm: "01", PINBlockFormat: "01", KeyIndex: "00"}).Bytes(); err == nil {
+		t.Error("code of 1 digit should fail")
+	}
+	if _, err := s.Encode(ASCII, 20, "LLVAR", "BN"); err == nil {
+		t.Error("DE53 longer than its length should fail")
+	}
+}
+
+func TestSecurityControlInMessage(t *testing.T) {
+	s, err := ParseSecurityControl("20010100FFFF9876543210E00001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Message{Mti: "0200", DE52: NewBinary64Hex("1B9C1845EB993A7A"), DE53: s}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Message{}
+	if err := decoded.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.DE53 == nil || !bytes.Equal(decoded.DE53.KSN, s.KSN) {
+		t.Errorf("DE53 should be decoded, got %v", decoded.DE53)
+	}
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := &Message{}
+	if err := json.Unmarshal(j, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, fromJSON.DE53.String(), "20010100FFFF9876543210E00001", "JSON")
+}
@@ -0,0 +1,358 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown or Close
+var ErrServerClosed = errors.New("server closed")
+
+// Handler answers a request, a nil response is not sent (e.g. for advices
+// answered later), an error is logged without response
+type Handler interface {
+	ServeISO8583(ctx context.Context, req *Message) (*Message, error)
+}
+
+// HandlerFunc is a function used as Handler
+type HandlerFunc func(ctx context.Context, req *Message) (*Message, error)
+
+func (f HandlerFunc) ServeISO8583(ctx context.Context, req *Message) (*Message, error) {
+	return f(ctx, req)
+}
+
+// ServeMux routes the requests to the handlers registered for their MTI.
+// A repeat (e.g. 0101) is routed to the handler of its original MTI (0100)
+// if it has no handler of its own.
+type ServeMux struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	// NotFound answers the requests without handler, nil means an error
+	NotFound Handler
+}
+
+// NewServeMux returns an empty ServeMux
+func NewServeMux() *ServeMux {
+	return &ServeMux{handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler of the MTI
+func (mux *ServeMux) Handle(mti string, h Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.handlers[mti] = h
+}
+
+// HandleFunc registers the handler function of the MTI
+func (mux *ServeMux) HandleFunc(mti string, f func(ctx context.Context, req *Message) (*Message, error)) {
+	mux.Handle(mti, HandlerFunc(f))
+}
+
+// Handler returns the handler of the MTI
+func (mux *ServeMux) Handler(mti string) (Handler, bool) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	if h, ok := mux.handlers[mti]; ok {
+		return h, true
+	}
+	// the origin of a repeat is the previous digit, e.g. 0101 and 0100
+	if len(mti) == 4 && mti[3] != '0' && (mti[3]-'0')%2 == 1 {
+		if h, ok := mux.handlers[mti[:3]+string(mti[3]-1)]; ok {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+func (mux *ServeMux) ServeISO8583(ctx context.Context, req *Message) (*Message, error) {
+	h, ok := mux.Handler(req.Mti)
+	if !ok {
+		if mux.NotFound != nil {
+			return mux.NotFound.ServeISO8583(ctx, req)
+		}
+		return nil, errors.New("no handler of MTI: " + req.Mti)
+	}
+	return h.ServeISO8583(ctx, req)
+}
+
+// SwapTPDUHeader returns the frame header of the response to a request
+// framed by TPDUFramer, the TPDU with the destination and the source swapped
+func SwapTPDUHeader(header []byte) []byte {
+	tpdu, err := ParseTPDU(header)
+	if err != nil {
+		return header
+	}
+	return tpdu.Swap().Bytes()
+}
+
+// Server accepts connections, decodes the requests, passes them to Handler
+// and writes the responses. The requests of a connection are handled
+// concurrently, the responses are written as soon as they are ready.
+type Server struct {
+	// Addr is the TCP address of ListenAndServe
+	Addr string
+	// Handler answers the requests, e.g. a ServeMux
+	Handler Handler
+	// Framer is the frame of the messages, nil means DefaultLengthHeader
+	Framer Framer
+	// MaxLength limits the length of the requests, 0 means DefaultMaxLength
+	MaxLength int
+	// ResponseHeader returns the frame header of the response from the frame
+	// header of the request, e.g. SwapTPDUHeader, nil means the same header
+	ResponseHeader func(header []byte) []byte
+	// ReadTimeout closes the connections idle for longer, 0 means no limit
+	ReadTimeout time.Duration
+	// WriteTimeout limits the writing of each response, 0 means no limit
+	WriteTimeout time.Duration
+	// MaxConns limits the number of open connections, the connections over
+	// the limit are closed right after accepted, 0 means no limit
+	MaxConns int
+	// ErrorLog logs the errors of the connections and the handlers,
+	// nil means the log package
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	shutdown  bool
+	wg        sync.WaitGroup
+}
+
+type serverConn struct {
+	conn net.Conn
+	// handlers are the requests being handled
+	handlers sync.WaitGroup
+	writeMu  sync.Mutex
+	enc      *Encoder
+}
+
+func (s *Server) framer() Framer {
+	if s.Framer == nil {
+		return DefaultLengthHeader
+	}
+	return s.Framer
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+func (s *Server) init() {
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		s.listeners = make(map[net.Listener]struct{})
+		s.conns = make(map[*serverConn]struct{})
+	}
+}
+
+// ListenAndServe listens on Addr and serves the connections
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts the connections of l until Shutdown or Close,
+// then it returns ErrServerClosed
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.init()
+	if s.shutdown {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	var delay time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			shutdown := s.shutdown
+			s.mu.Unlock()
+			if shutdown {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				// back off on temporary errors, e.g. too many open files
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection, e.g. one end of net.Pipe,
+// until it is closed, and closes it
+func (s *Server) ServeConn(conn net.Conn) {
+	c := &serverConn{conn: conn, enc: NewEncoder(conn)}
+	c.enc.SetFramer(s.framer(), nil)
+
+	s.mu.Lock()
+	s.init()
+	if s.shutdown || (s.MaxConns > 0 && len(s.conns) >= s.MaxConns) {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	defer func() {
+		// the pending responses are written before closing
+		c.handlers.Wait()
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	dec := NewDecoder(conn)
+	dec.SetFramer(s.framer())
+	dec.SetMaxLength(s.MaxLength)
+	for {
+		// the deadline is set under the lock, not to override the one of Shutdown
+		s.mu.Lock()
+		shutdown := s.shutdown
+		if !shutdown && s.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+		}
+		s.mu.Unlock()
+		if shutdown {
+			return
+		}
+		header, raw, err := dec.ReadFrame()
+		if err != nil {
+			s.mu.Lock()
+			shutdown := s.shutdown
+			s.mu.Unlock()
+			if !shutdown && err != io.EOF {
+				s.logf("iso8583: read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		req := &Message{}
+		if err := req.Decode(raw); err != nil {
+			// the framing is intact, the next message can be read
+			s.logf("iso8583: decode from %s: %v", conn.RemoteAddr(), err)
+			continue
+		}
+		c.handlers.Add(1)
+		go s.handle(ctx, c, header, req)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, c *serverConn, header []byte, req *Message) {
+	defer c.handlers.Done()
+	if s.Handler == nil {
+		s.logf("iso8583: no handler of MTI %s", req.Mti)
+		return
+	}
+	resp, err := s.Handler.ServeISO8583(ctx, req)
+	if err != nil {
+		s.logf("iso8583: handle %s: %v", req.Mti, err)
+		return
+	}
+	if resp == nil {
+		return
+	}
+	if s.ResponseHeader != nil {
+		header = s.ResponseHeader(header)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if s.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+	if err := c.enc.EncodeHeader(resp, header); err != nil {
+		s.logf("iso8583: write %s to %s: %v", resp.Mti, c.conn.RemoteAddr(), err)
+	}
+}
+
+// Conns returns the number of open connections
+func (s *Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Shutdown stops accepting connections and reading requests, waits until the
+// responses of the requests being handled are written, and closes the
+// connections. If ctx ends first, the connections are closed right away.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.init()
+	s.shutdown = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	// stop reading, the blocked reads return at once
+	for c := range s.conns {
+		c.conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.Close()
+		return ctx.Err()
+	}
+}
+
+// Close closes the listeners and the connections at once, and cancels the
+// context of the handlers
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.init()
+	s.shutdown = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	s.cancel()
+	return nil
+}
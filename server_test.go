@@ -0,0 +1,202 @@
+package iso8583
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+func testServerMux() *ServeMux {
+	mux := NewServeMux()
+	mux.HandleFunc("0800", func(ctx context.Context, req *Message) (*Message, error) {
+		resp := &Message{DE11: req.DE11, DE39: NewAlphanumeric("00")}
+		resp.Mti = "0810"
+		return resp, nil
+	})
+	mux.HandleFunc("0100", func(ctx context.Context, req *Message) (*Message, error) {
+		return echoResponse(req), nil
+	})
+	return mux
+}
+
+func TestServeMux(t *testing.T) {
+	mux := testServerMux()
+	for mti, ok := range map[string]bool{"0800": true, "0100": true, "0101": true, "0102": false, "0200": false} {
+		if _, found := mux.Handler(mti); found != ok {
+			t.Errorf("handler of %s should be found: %v", mti, ok)
+		}
+	}
+	if _, err := mux.ServeISO8583(context.Background(), &Message{Mti: "0200"}); err == nil {
+		t.Error("request without handler should fail")
+	}
+	mux.NotFound = HandlerFunc(func(ctx context.Context, req *Message) (*Message, error) {
+		return &Message{Mti: "0210"}, nil
+	})
+	if resp, err := mux.ServeISO8583(context.Background(), &Message{Mti: "0200"}); err != nil || resp.Mti != "0210" {
+		t.Errorf("request without handler should be answered by NotFound, got %v %v", resp, err)
+	}
+}
+
+func TestServerPipe(t *testing.T) {
+	s := &Server{
+		Handler:        testServerMux(),
+		Framer:         TPDUFramer,
+		ResponseHeader: SwapTPDUHeader,
+		ErrorLog:       log.New(ioutil.Discard, "", 0),
+	}
+	serverEnd, clientEnd := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		s.ServeConn(serverEnd)
+		close(done)
+	}()
+
+	tpdu := NewTPDU(0x60, 0x0001, 0x0002)
+	enc, dec := NewEncoder(clientEnd), NewDecoder(clientEnd)
+	enc.SetFramer(TPDUFramer, tpdu.Bytes())
+	dec.SetFramer(TPDUFramer)
+
+	go func() {
+		req := &Message{DE11: NewNumeric("000001"), DE24: NewNumeric("831")}
+		req.Mti = "0800"
+		_ = enc.Encode(req)
+		// a request without handler is not answered
+		_ = enc.Encode(&Message{Mti: "0200", DE11: NewNumeric("000002")})
+		_ = enc.Encode(clientTestRequest("000003"))
+	}()
+
+	// the requests are handled concurrently, the responses come in any order
+	mtis := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		resp := &Message{}
+		header, err := dec.DecodeHeader(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(header, tpdu.Swap().Bytes()) {
+			t.Errorf("response TPDU should be swapped, got %x", header)
+		}
+		mtis[resp.Mti] = true
+	}
+	if !mtis["0810"] || !mtis["0110"] {
+		t.Errorf("responses should be 0810 and 0110, got %v", mtis)
+	}
+
+	clientEnd.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Error("ServeConn should return when the connection is closed")
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := testServerMux()
+	mux.HandleFunc("0200", func(ctx context.Context, req *Message) (*Message, error) {
+		close(started)
+		<-release
+		return echoResponse(req), nil
+	})
+	s := &Server{Handler: mux, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+
+	c := &Client{Addr: l.Addr().String(), Timeout: 2 * time.Second}
+	defer c.Close()
+	req := clientTestRequest("000001")
+	req.Mti = "0200"
+	responded := make(chan error, 1)
+	go func() {
+		_, err := c.Send(context.Background(), req)
+		responded <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown should wait for the request being handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-responded; err != nil {
+		t.Errorf("request being handled should be answered, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Error(err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve should return ErrServerClosed, got %v", err)
+	}
+	if s.Conns() != 0 {
+		t.Errorf("connections should be closed, got %d", s.Conns())
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	s := &Server{Handler: testServerMux(), MaxConns: 1, ReadTimeout: 100 * time.Millisecond, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	defer s.Close()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	// wait for the first connection to be served
+	for i := 0; s.Conns() == 0 && i < 100; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("connection over the limit should be closed, got %v", err)
+	}
+
+	// the idle first connection is closed after the read timeout
+	first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("idle connection should be closed, got %v", err)
+	}
+}
+
+func TestServerMaxLength(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	s := &Server{Handler: testServerMux(), MaxLength: 16, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	go s.ServeConn(server)
+
+	// the request of 32 bytes is over the limit, the connection is closed
+	raw, err := EncodeFrame(Binary2Framer, nil, framerTestMessage("0800"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := client.Write(raw[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("connection should be closed, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CounterStore persists the counters of the generators, so that a restart
+// does not reuse their numbers
+type CounterStore interface {
+	// Load returns the saved value of the key, 0 if there is none
+	Load(key string) (int64, error)
+	// Save saves the value of the key
+	Save(key string, value int64) error
+}
+
+// FileCounterStore is a CounterStore keeping every counter in a file of
+// a directory, written atomically
+type FileCounterStore struct {
+	dir string
+}
+
+// counterFileExt is the extension of the counter files
+const counterFileExt = ".counter"
+
+// NewFileCounterStore returns the store of the directory, created if missing
+func NewFileCounterStore(dir string) (*FileCounterStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileCounterStore{dir: dir}, nil
+}
+
+func (s *FileCounterStore) path(key string) (string, error) {
+	if key == "" || key[0] == '.' || strings.ContainsAny(key, `/\`) {
+		return "", errors.New("invalid counter key: " + key)
+	}
+	return filepath.Join(s.dir, key+counterFileExt), nil
+}
+
+func (s *FileCounterStore) Load(key string) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid counter " + key + ": " + err.Error())
+	}
+	return value, nil
+}
+
+func (s *FileCounterStore) Save(key string, value int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(strconv.FormatInt(value, 10)+"\n"))
+}
+
+// counter is a persisted counter, saved once per block of values
+type counter struct {
+	store CounterStore
+	key   string
+	block int64
+
+	loaded bool
+	value  int64
+	// saved is the value saved in the store, the values up to it are reserved
+	saved int64
+}
+
+// next returns the value after v, reserving a new block if needed
+func (c *counter) next(v int64) (int64, error) {
+	if c.store == nil || v <= c.saved {
+		return v, nil
+	}
+	block := c.block
+	if block <= 0 {
+		block = 1
+	}
+	if err := c.store.Save(c.key, v+block-1); err != nil {
+		return 0, err
+	}
+	c.saved = v + block - 1
+	return v, nil
+}
+
+func (c *counter) load() error {
+	if c.loaded {
+		return nil
+	}
+	if c.store != nil {
+		value, err := c.store.Load(c.key)
+		if err != nil {
+			return err
+		}
+		// the reserved values may be used before the restart
+		c.value, c.saved = value, value
+	}
+	c.loaded = true
+	return nil
+}
+
+// maxSTAN is the largest STAN, the STAN after it is 000001
+const maxSTAN = 999999
+
+// STANGenerator returns the system trace audit numbers (DE11) of a terminal
+// or a link, from 000001 to 999999 and 000001 again. It is safe for
+// concurrent use.
+type STANGenerator struct {
+	// Store persists the last STAN, nil means no persistence
+	Store CounterStore
+	// Key is the key of the STAN in Store, e.g. the terminal ID
+	Key string
+	// Block is the number of STANs reserved by each save to Store, the
+	// STANs reserved and not used are skipped after a restart, 0 means 1
+	Block int
+
+	mu sync.Mutex
+	c  counter
+}
+
+// Next returns the next STAN
+func (g *STANGenerator) Next() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.c.store, g.c.key, g.c.block = g.Store, g.Key, int64(g.Block)
+	if err := g.c.load(); err != nil {
+		return "", err
+	}
+	// the counter counts all the STANs, it is not reset by the rollover
+	value, err := g.c.next(g.c.value + 1)
+	if err != nil {
+		return "", err
+	}
+	g.c.value = value
+	return fmt.Sprintf("%06d", (value-1)%maxSTAN+1), nil
+}
+
+// Set sets DE11 of m to the next STAN
+func (g *STANGenerator) Set(m *Message) error {
+	stan, err := g.Next()
+	if err != nil {
+		return err
+	}
+	m.DE11 = NewNumeric(stan)
+	return nil
+}
+
+// maxRRNSequence is the largest sequence of an RRN
+const maxRRNSequence = 999999
+
+// RRNGenerator returns the retrieval reference numbers (DE37) of 12 digits,
+// YDDDHHNNNNNN, the last digit of the year, the day of the year, the hour
+// and a sequence, restarted every day. It is safe for concurrent use.
+type RRNGenerator struct {
+	// Store persists the last sequence and its day, nil means no persistence
+	Store CounterStore
+	// Key is the key of the sequence in Store
+	Key string
+	// Block is the number of sequences reserved by each save to Store,
+	// 0 means 1
+	Block int
+	// Now returns the time of the RRN, nil means time.Now
+	Now func() time.Time
+
+	mu sync.Mutex
+	c  counter
+}
+
+// Next returns the next RRN
+func (g *RRNGenerator) Next() (string, error) {
+	now := time.Now()
+	if g.Now != nil {
+		now = g.Now()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.c.store, g.c.key, g.c.block = g.Store, g.Key, int64(g.Block)
+	if err := g.c.load(); err != nil {
+		return "", err
+	}
+	// the counter is the day YYYYDDD followed by the sequence of the day
+	day := int64(now.Year()*1000 + now.YearDay())
+	seq := int64(1)
+	if g.c.value/(maxRRNSequence+1) == day {
+		seq = g.c.value%(maxRRNSequence+1) + 1
+		if seq > maxRRNSequence {
+			seq = 1
+		}
+	}
+	value := day*(maxRRNSequence+1) + seq
+	// a new day or a rollover reserves a new block
+	if value <= g.c.value {
+		g.c.saved = 0
+	}
+	value, err := g.c.next(value)
+	if err != nil {
+		return "", err
+	}
+	g.c.value = value
+	return fmt.Sprintf("%d%03d%02d%06d", now.Year()%10, now.YearDay(), now.Hour(), seq), nil
+}
+
+// Set sets DE37 of m to the next RRN
+func (g *RRNGenerator) Set(m *Message) error {
+	rrn, err := g.Next()
+	if err != nil {
+		return err
+	}
+	m.DE37 = NewANP(rrn)
+	return nil
+}
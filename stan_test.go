@@ -0,0 +1,130 @@
+package iso8583
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCounterStore(t *testing.T) (*FileCounterStore, func()) {
+	dir, err := ioutil.TempDir("", "counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileCounterStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestFileCounterStore(t *testing.T) {
+	s, cleanup := testCounterStore(t)
+	defer cleanup()
+	if value, err := s.Load("TERM0001"); err != nil || value != 0 {
+		t.Errorf("missing counter should be 0, got %d %v", value, err)
+	}
+	if err := s.Save("TERM0001", 1234567); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := s.Load("TERM0001"); err != nil || value != 1234567 {
+		t.Errorf("counter should be 1234567, got %d %v", value, err)
+	}
+	for _, key := range []string{"", "../x", ".hidden"} {
+		if err := s.Save(key, 1); err == nil {
+			t.Errorf("invalid key %q should fail", key)
+		}
+	}
+}
+
+func TestSTANGenerator(t *testing.T) {
+	s, cleanup := testCounterStore(t)
+	defer cleanup()
+	if err := s.Save("TERM0001", maxSTAN-1); err != nil {
+		t.Fatal(err)
+	}
+	g := &STANGenerator{Store: s, Key: "TERM0001", Block: 10}
+	for _, expected := range []string{"999999", "000001", "000002"} {
+		if stan, err := g.Next(); err != nil || stan != expected {
+			t.Errorf("STAN should be %s, got %s %v", expected, stan, err)
+		}
+	}
+	m := &Message{}
+	if err := g.Set(m); err != nil || m.DE11.String() != "000003" {
+		t.Errorf("DE11 should be 000003, got %v %v", m.DE11, err)
+	}
+
+	// after a restart, the reserved STANs are skipped
+	g = &STANGenerator{Store: s, Key: "TERM0001", Block: 10}
+	if stan, err := g.Next(); err != nil || stan != "000010" {
+		t.Errorf("STAN after restart should be 000010, got %s %v", stan, err)
+	}
+	// the STANs of another terminal are independent
+	g = &STANGenerator{Store: s, Key: "TERM0002"}
+	if stan, err := g.Next(); err != nil || stan != "000001" {
+		t.Errorf("STAN of another terminal should be 000001, got %s %v", stan, err)
+	}
+}
+
+func TestSTANGeneratorConcurrent(t *testing.T) {
+	g := &STANGenerator{}
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stan, err := g.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if seen[stan] {
+					t.Errorf("STAN %s is duplicated", stan)
+				}
+				seen[stan] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 1000 {
+		t.Errorf("1000 STANs should be generated, got %d", len(seen))
+	}
+}
+
+func TestRRNGenerator(t *testing.T) {
+	s, cleanup := testCounterStore(t)
+	defer cleanup()
+	now := time.Date(2023, 2, 3, 14, 5, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	g := &RRNGenerator{Store: s, Key: "rrn", Block: 100, Now: clock}
+	for _, expected := range []string{"303414000001", "303414000002"} {
+		if rrn, err := g.Next(); err != nil || rrn != expected {
+			t.Errorf("RRN should be %s, got %s %v", expected, rrn, err)
+		}
+	}
+
+	// after a restart on the same day, the reserved sequences are skipped
+	now = now.Add(time.Hour)
+	g = &RRNGenerator{Store: s, Key: "rrn", Block: 100, Now: clock}
+	m := &Message{}
+	if err := g.Set(m); err != nil || m.DE37.String() != "303415000101" {
+		t.Errorf("RRN after restart should be 303415000101, got %v %v", m.DE37, err)
+	}
+
+	// the sequence restarts on the next day
+	now = now.Add(12 * time.Hour)
+	if rrn, err := g.Next(); err != nil || rrn != "303503000001" {
+		t.Errorf("RRN of the next day should be 303503000001, got %s %v", rrn, err)
+	}
+	g = &RRNGenerator{Store: s, Key: "rrn", Block: 100, Now: clock}
+	if rrn, err := g.Next(); err != nil || rrn != "303503000101" {
+		t.Errorf("RRN after restart should be 303503000101, got %s %v", rrn, err)
+	}
+}
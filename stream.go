@@ -0,0 +1,220 @@
+package iso8583
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// LengthBinary is a big endian binary length
+	LengthBinary = iota
+	// LengthASCII is a length of ASCII decimal digits
+	LengthASCII
+)
+
+// LengthHeader is the length prefix of every message on a stream
+type LengthHeader struct {
+	// Size is the number of bytes of the length, e.g. 2
+	Size int
+	// Encoding is LengthBinary or LengthASCII
+	Encoding int
+	// Inclusive is true if the length counts the header too
+	Inclusive bool
+}
+
+// DefaultLengthHeader is a 2 byte binary length, without the header
+var DefaultLengthHeader = LengthHeader{Size: 2, Encoding: LengthBinary}
+
+// max returns the largest message length of the header
+func (h LengthHeader) max() int {
+	max := 1
+	for i := 0; i < h.Size; i++ {
+		if h.Encoding == LengthASCII {
+			max *= 10
+		} else {
+			max *= 256
+		}
+		// limit the length to int32 on every platform
+		if max > 1<<31-1 {
+			return 1<<31 - 1
+		}
+	}
+	max--
+	if h.Inclusive {
+		max -= h.Size
+	}
+	return max
+}
+
+func (h LengthHeader) check() error {
+	maxSize := 4
+	switch h.Encoding {
+	case LengthBinary:
+	case LengthASCII:
+		maxSize = 9
+	default:
+		return errors.New("invalid length header encoding")
+	}
+	if h.Size < 1 || h.Size > maxSize {
+		return errors.New("invalid length header size: " + strconv.Itoa(h.Size))
+	}
+	return nil
+}
+
+// encode writes the header of a message of length l to b, len(b) is the size
+func (h LengthHeader) encode(b []byte, l int) error {
+	if l > h.max() {
+		return errors.New("message too long: " + strconv.Itoa(l))
+	}
+	if h.Inclusive {
+		l += h.Size
+	}
+	for i := len(b) - 1; i >= 0; i-- {
+		if h.Encoding == LengthASCII {
+			b[i] = byte('0' + l%10)
+			l /= 10
+		} else {
+			b[i] = byte(l)
+			l >>= 8
+		}
+	}
+	return nil
+}
+
+// decode returns the length of the message following the header b
+func (h LengthHeader) decode(b []byte) (int, error) {
+	l := 0
+	for _, c := range b {
+		if h.Encoding == LengthASCII {
+			if c < '0' || c > '9' {
+				return 0, errors.New("invalid length header: " + string(b))
+			}
+			l = l*10 + int(c-'0')
+		} else {
+			l = l<<8 | int(c)
+		}
+	}
+	if h.Inclusive {
+		if l < h.Size {
+			return 0, errors.New("invalid length header: " + strconv.Itoa(l))
+		}
+		l -= h.Size
+	}
+	if l > h.max() {
+		return 0, errors.New("message too long: " + strconv.Itoa(l))
+	}
+	return l, nil
+}
+
+// Encoder writes framed messages to a stream, by default with
+// DefaultLengthHeader. It is not safe for concurrent use.
+type Encoder struct {
+	w      io.Writer
+	framer Framer
+	header []byte
+	buf    []byte
+}
+
+// NewEncoder returns an encoder writing to w with DefaultLengthHeader
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, framer: DefaultLengthHeader}
+}
+
+// SetLengthHeader sets the length prefix of the messages
+func (e *Encoder) SetLengthHeader(h LengthHeader) error {
+	if err := h.check(); err != nil {
+		return err
+	}
+	e.framer = h
+	e.header = nil
+	return nil
+}
+
+// SetFramer sets the frame of the messages, and the header used by Encode,
+// e.g. a TPDU
+func (e *Encoder) SetFramer(f Framer, header []byte) {
+	e.framer = f
+	e.header = header
+}
+
+// Encode writes the encoded message in its frame to the stream,
+// in a single write
+func (e *Encoder) Encode(m *Message) error {
+	return e.EncodeHeader(m, e.header)
+}
+
+// EncodeHeader writes the encoded message in its frame with the header
+// (e.g. the swapped TPDU of a request) to the stream, in a single write
+func (e *Encoder) EncodeHeader(m *Message, header []byte) error {
+	buf, err := appendFrame(e.buf[:0], e.framer, header, m)
+	if err != nil {
+		return err
+	}
+	e.buf = buf
+	_, err = e.w.Write(buf)
+	return err
+}
+
+// Decoder reads framed messages from a stream, by default with
+// DefaultLengthHeader. It is not safe for concurrent use.
+type Decoder struct {
+	r         io.Reader
+	framer    Framer
+	maxLength int
+}
+
+// NewDecoder returns a decoder reading from r with DefaultLengthHeader
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, framer: DefaultLengthHeader}
+}
+
+// SetLengthHeader sets the length prefix of the messages
+func (d *Decoder) SetLengthHeader(h LengthHeader) error {
+	if err := h.check(); err != nil {
+		return err
+	}
+	d.framer = h
+	return nil
+}
+
+// SetFramer sets the frame of the messages
+func (d *Decoder) SetFramer(f Framer) {
+	d.framer = f
+}
+
+// SetMaxLength limits the length of the messages, 0 means DefaultMaxLength
+func (d *Decoder) SetMaxLength(max int) {
+	d.maxLength = max
+}
+
+// ReadMessage reads the next message from the stream, without its frame.
+// It returns io.EOF if the stream ends before the message, and
+// io.ErrUnexpectedEOF if it ends within the message.
+func (d *Decoder) ReadMessage() ([]byte, error) {
+	_, msg, err := d.framer.ReadFrame(d.r, d.maxLength)
+	return msg, err
+}
+
+// ReadFrame reads the next message from the stream, and returns the header
+// of its frame and the message
+func (d *Decoder) ReadFrame() (header, msg []byte, err error) {
+	return d.framer.ReadFrame(d.r, d.maxLength)
+}
+
+// Decode reads the next message from the stream and decodes it into m.
+// The fields of m alias a buffer of their own, which is not reused.
+func (d *Decoder) Decode(m *Message) error {
+	_, err := d.DecodeHeader(m)
+	return err
+}
+
+// DecodeHeader reads the next message from the stream, decodes it into m,
+// and returns the header of its frame, e.g. a TPDU
+func (d *Decoder) DecodeHeader(m *Message) ([]byte, error) {
+	header, msg, err := d.framer.ReadFrame(d.r, d.maxLength)
+	if err != nil {
+		return nil, err
+	}
+	return header, m.Decode(msg)
+}
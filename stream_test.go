@@ -0,0 +1,91 @@
+package iso8583
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestStreamEncodeDecode(t *testing.T) {
+	var scenarios = []struct {
+		header LengthHeader
+		prefix string
+	}{
+		{header: DefaultLengthHeader, prefix: "\x00\x25"},
+		{header: LengthHeader{Size: 4, Encoding: LengthASCII}, prefix: "0037"},
+		{header: LengthHeader{Size: 4, Encoding: LengthASCII, Inclusive: true}, prefix: "0041"},
+		{header: LengthHeader{Size: 2, Encoding: LengthBinary, Inclusive: true}, prefix: "\x00\x27"},
+	}
+	for _, scenario := range scenarios {
+		var stream bytes.Buffer
+		enc := NewEncoder(&stream)
+		if err := enc.SetLengthHeader(scenario.header); err != nil {
+			t.Fatal(err)
+		}
+		for _, stan := range []string{"000001", "000002"} {
+			m := &Message{DE2: NewNumeric("123"), DE3: NewNumeric("11"), DE11: NewNumeric(stan)}
+			m.Mti = "1100"
+			if err := enc.Encode(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.HasPrefix(stream.Bytes(), []byte(scenario.prefix+"1100")) {
+			t.Errorf("stream should start with %q, got %q", scenario.prefix, stream.Bytes())
+		}
+
+		dec := NewDecoder(&stream)
+		if err := dec.SetLengthHeader(scenario.header); err != nil {
+			t.Fatal(err)
+		}
+		for _, stan := range []string{"000001", "000002"} {
+			m := &Message{}
+			if err := dec.Decode(m); err != nil {
+				t.Fatal(err)
+			}
+			if m.DE11.String() != stan {
+				t.Errorf("DE11 should be %s, got %s", stan, m.DE11)
+			}
+		}
+		if err := dec.Decode(&Message{}); err != io.EOF {
+			t.Errorf("end of stream should be io.EOF, got %v", err)
+		}
+	}
+}
+
+func TestStreamDecodeErrors(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte("\x00\x101100")))
+	if _, err := dec.ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated message should be io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte("\x00")))
+	if _, err := dec.ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated header should be io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte("00A4")))
+	_ = dec.SetLengthHeader(LengthHeader{Size: 4, Encoding: LengthASCII})
+	if _, err := dec.ReadMessage(); err == nil {
+		t.Error("invalid ASCII length should fail")
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte("\x10\x00")))
+	dec.SetMaxLength(1024)
+	if _, err := dec.ReadMessage(); err == nil {
+		t.Error("message over the maximum length should fail")
+	}
+
+	if err := dec.SetLengthHeader(LengthHeader{Size: 5, Encoding: LengthBinary}); err == nil {
+		t.Error("5 byte binary length should be invalid")
+	}
+}
+
+func TestStreamEncodeTooLong(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+	_ = enc.SetLengthHeader(LengthHeader{Size: 1, Encoding: LengthASCII})
+	m := &Message{DE2: NewNumeric("123")}
+	m.Mti = "1100"
+	if err := enc.Encode(m); err == nil {
+		t.Error("message longer than 9 should fail with 1 digit length")
+	}
+}
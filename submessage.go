@@ -1,11 +1,23 @@
 package iso8583
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// SubMessage is the bitmapped submessage carried in DE125.
+//
+// Any other struct can be used as a bitmapped submessage in the same way:
+// its exported pointer fields are the subfields, named with a trailing field
+// number (e.g. SE2, SF2, PDS2) and tagged like the fields of Message, in
+// ascending order. A uint64 field with number 1 receives the secondary bitmap.
+// Subfields can be bitmapped submessages themselves, the layout of their
+// bitmap is given by the `bitmap` tag (see BitmapSpec), e.g.
+//
+//	SF3 *Nested `format:"LLLVAR" length:"999" bitmap:"4,binary"`
 type SubMessage struct {
 	encoder       int
 	bitmapPrimary uint64
@@ -30,7 +42,7 @@ type SubMessage struct {
 	SE18 *ANS `format:"" length:"16" validator:"ANS"  json:",omitempty"`
 	SE19 *ANS `format:"" length:"999" validator:"ANS"  json:",omitempty"`
 	SE20 *ANS `format:"" length:"2" validator:"ANS"  json:",omitempty"`
-	SE21 *ANS `format:"" length:"194" validator:"ANS"  json:",omitempty"` // ANS 255 if Tagged field 0008 is included
+	SE21 *TaggedANS `format:"" length:"194" validator:"ANS" tagged:"0008:57" json:",omitempty"` // ANS 255 if Tagged field 0008 is included
 	SE22 *ANS `format:"LLLVAR" length:"255" validator:"ANS"  json:",omitempty"`
 	SE23 *Reserved `json:",omitempty"`
 	SE24 *ANS `format:"LLVAR" length:"99" validator:"ANS"  json:",omitempty"`
@@ -112,149 +124,334 @@ type SubMessage struct {
 }
 
 func (m *SubMessage) Encode() ([]byte, error) {
-	res := make([]byte, 0)
+	return EncodeSubMessage(m, m.encoder, DefaultBitmap)
+}
+
+func (m *SubMessage) Decode(bytes []byte) error {
+	return DecodeSubMessage(bytes, m, m.encoder, DefaultBitmap)
+}
+
+// Validate checks every present field without encoding the submessage,
+// and returns ValidationErrors with all of the invalid fields, or nil
+func (m *SubMessage) Validate() error {
+	if errs := validateBitmapped(structOf(m), m.encoder, DefaultBitmap, ""); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *SubMessage) setPrimaryBitmap(bitmap uint64) {
+	m.bitmapPrimary = bitmap
+}
+
+// bitmapHolder is implemented by the messages keeping their primary bitmap
+// after encoding or decoding
+type bitmapHolder interface {
+	setPrimaryBitmap(bitmap uint64)
+}
 
-	// initialize bitmaps
-	var bitmapPrimary uint64
-	var bitmapSecondary uint64
+// EncodeSubMessage encodes sm, a pointer to a bitmapped submessage struct,
+// with the bitmap layout of spec
+func EncodeSubMessage(sm interface{}, encoder int, spec BitmapSpec) ([]byte, error) {
+	if err := checkSubMessage(sm, spec); err != nil {
+		return nil, err
+	}
+	return encodeBitmapped(structOf(sm), encoder, spec)
+}
+
+// DecodeSubMessage decodes raw into sm, a pointer to a bitmapped submessage
+// struct, with the bitmap layout of spec
+func DecodeSubMessage(raw []byte, sm interface{}, encoder int, spec BitmapSpec) error {
+	if err := checkSubMessage(sm, spec); err != nil {
+		return err
+	}
+	_, err := decodeBitmapped(raw, structOf(sm), encoder, spec, nil, "")
+	return err
+}
 
-	data := make([]byte, 0, 512)
+func checkSubMessage(sm interface{}, spec BitmapSpec) error {
+	v := reflect.ValueOf(sm)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("submessage must be a non-nil pointer to a struct")
+	}
+	return spec.check()
+}
+
+// fieldIndex returns the field number from the name of the struct field,
+// e.g. for DE2 or SE2 index=2
+func fieldIndex(name string) (int, error) {
+	return strconv.Atoi(strings.TrimLeft(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"))
+}
+
+// encodeBitmapped encodes the bitmaps and the fields of the struct s
+func encodeBitmapped(s Generated, encoder int, spec BitmapSpec) ([]byte, error) {
+	return appendBitmapped(nil, s, encoder, spec)
+}
+
+// scratchBuffers are reused for the encoded fields until the bitmaps are known
+var scratchBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// lastBitmap returns the index of the last bitmap which can hold a field of
+// the specs, in ascending order. The first bit of the bitmaps before it
+// indicates the next bitmap, the first bit of the last one is a field,
+// e.g. SE65 after the secondary bitmap.
+func lastBitmap(specs []FieldSpec, spec BitmapSpec) int {
+	if spec.Fixed || len(specs) == 0 {
+		return 0
+	}
+	n, _ := spec.position(specs[len(specs)-1].Index)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// appendBitmapped appends the bitmaps and the fields of the struct s to dst
+func appendBitmapped(dst []byte, s Generated, encoder int, spec BitmapSpec) ([]byte, error) {
+	// initialize bitmaps, bitmaps[0] is the primary
+	bitmaps := make([]uint64, 1, 2)
+
+	scratch := scratchBuffers.Get().(*[]byte)
+	defer func() {
+		*scratch = (*scratch)[:0]
+		scratchBuffers.Put(scratch)
+	}()
+	data := *scratch
 
-	v := reflect.Indirect(reflect.ValueOf(m))
-	t := v.Type()
 	// iterate through iso fields, if field is not empty,
 	// encode and append it to data, and set the proper bit in bitmap
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Kind() != reflect.Ptr || v.Field(i).IsNil() {
+	specs := s.ISO8583Fields()
+	last := lastBitmap(specs, spec)
+	for i := range specs {
+		fs := &specs[i]
+		value := s.ISO8583Field(i)
+		// skip empty fields
+		if value == nil {
 			continue
 		}
-		sf := t.Field(i)
-		// get field index, e.g. for SE2 index=2
-		index, err := strconv.Atoi(strings.Trim(sf.Name, "SE"))
-		if err != nil {
-			return nil, err
-		}
-		// field length
-		length, err := strconv.Atoi(sf.Tag.Get("length"))
-		if err != nil {
+		if err := fs.check(); err != nil {
 			return nil, err
 		}
-		// iso field format, "" means that field has fixed length, but
-		// e.g. LLVAR means that length indicator is encoded in the first 2 byte
-		// in this case length means "maximum length
-		format := sf.Tag.Get("format")
-		validator := sf.Tag.Get("validator")
 
-		f := v.Field(i).Interface().(field)
-
-		if index <= 64 {
-			bitmapPrimary = addField(bitmapPrimary, uint8(index))
-		} else {
-			// if we need secondary bitmap, set first bit in primary bitmap
-			bitmapPrimary |= 1 << 63
-			bitmapSecondary = addField(bitmapSecondary, uint8(index-64))
+		n, bit := spec.position(fs.Index)
+		if n > 0 && spec.Fixed {
+			return nil, errors.New("field out of bitmap range: " + fs.Name)
+		}
+		if bit == 1 && n < last {
+			return nil, errors.New("field reserved for bitmap: " + fs.Name)
 		}
+		for len(bitmaps) <= n {
+			// if we need the next bitmap, set first bit in the previous one
+			bitmaps[len(bitmaps)-1] = spec.set(bitmaps[len(bitmaps)-1], 1)
+			bitmaps = append(bitmaps, 0)
+		}
+		bitmaps[n] = spec.set(bitmaps[n], bit)
 
 		// encode field, append it to data
-		d, err := f.Encode(m.encoder, length, format, validator)
+		// format "" means that field has fixed length, but e.g. LLVAR means
+		// that length indicator is encoded in the first 2 byte,
+		// in this case length means "maximum length"
+		var d []byte
+		var err error
+		if fs.SubMessage {
+			d, err = encodeSubField(nestedStructOf(s, value), encoder, fs)
+		} else {
+			var f field
+			if f, err = fs.asField(value); err != nil {
+				return nil, err
+			}
+			d, err = f.Encode(encoder, fs.Length, fs.Format, fs.Validator)
+		}
 		if err != nil {
 			return nil, err
 		}
 		data = append(data, d...)
 	}
 
-	// append bitmaps to result
-	m.bitmapPrimary = bitmapPrimary
-	res = append(res, []byte(bitmapHex(bitmapPrimary))...)
+	// keep the grown buffer for the next encoding
+	*scratch = data
 
-	if bitmapSecondary != 0 {
-		m.SE1 = bitmapSecondary
-		res = append(res, []byte(bitmapHex(bitmapSecondary))...)
-	}
+	setBitmaps(s, spec, bitmaps)
 
-	// append iso data elements to result
-	res = append(res, data...)
-	return res, nil
+	// append bitmaps and iso data elements to result
+	if dst == nil {
+		dst = make([]byte, 0, len(bitmaps)*spec.width()+len(data))
+	}
+	for _, bitmap := range bitmaps {
+		dst = append(dst, spec.encode(bitmap)...)
+	}
+	return append(dst, data...), nil
 }
 
-func (m *SubMessage) Decode(bytes []byte) error {
-	var err error
-
-	// decode MTI
-
+// decodeBitmapped decodes the bitmaps and the fields from raw into the struct s,
+// and returns the number of bytes read.
+// If errs is not nil, decoding is lenient: fields with invalid values are kept
+// and their errors are appended to errs with the name of the field after prefix,
+// decoding stops only if the next field cannot be found
+func decodeBitmapped(raw []byte, s Generated, encoder int, spec BitmapSpec, errs *ValidationErrors, prefix string) (int, error) {
 	// it is an iterator, watching where we are currently in the iteration,
 	// which byte will be the starting position of the next decode
 	it := 0
 
-	// decode bitmaps
-	//decode primary bitmap
-	m.bitmapPrimary, err = decodeHexString(string(bytes[it : it+16]))
-	if err != nil {
-		return err
-	}
-	it += 16
-
-	// if first bit is 1, it means that we have secondary bitmap, decode secondary bitmap
-	if isBitSet(m.bitmapPrimary, 1) {
-		m.SE1, err = decodeHexString(string(bytes[it : it+16]))
+	// decode bitmaps, while first bit is 1, it means that we have a next bitmap,
+	// up to the last bitmap of the fields
+	specs := s.ISO8583Fields()
+	last := lastBitmap(specs, spec)
+	bitmaps := make([]uint64, 0, 2)
+	for len(bitmaps) == 0 || (len(bitmaps) <= last && spec.isSet(bitmaps[len(bitmaps)-1], 1)) {
+		bitmap, err := spec.decode(raw[it:])
 		if err != nil {
-			return err
+			return 0, err
 		}
-		it += 16
+		bitmaps = append(bitmaps, bitmap)
+		it += spec.width()
 	}
 
-	v := reflect.Indirect(reflect.ValueOf(m))
-	t := v.Type()
 	// iterate through iso fields, if bitmap is not empty at bit position i,
 	// set field with index i with proper value
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Kind() != reflect.Ptr {
-			continue
+	for i := range specs {
+		fs := &specs[i]
+		if fs.Index < 0 {
+			return 0, fs.check()
 		}
-		sf := t.Field(i)
-		// get field index, e.g. for DE2 index=2
-		index, err := strconv.Atoi(strings.Trim(sf.Name, "SE"))
-		if err != nil {
-			return err
-		}
-		// if index < 64, search in primary bitmap if it is set
-		if index <= 64 && !isBitSet(m.bitmapPrimary, uint8(index)) {
+		// search in the bitmap of the field if it is set
+		n, bit := spec.position(fs.Index)
+		if n >= len(bitmaps) || !spec.isSet(bitmaps[n], bit) {
 			continue
 		}
-
-		// if index > 64, search in secondary bitmap if it is set
-		if index > 64 && !isBitSet(m.SE1, uint8(index-64)) {
-			continue
+		if err := fs.check(); err != nil {
+			return 0, err
 		}
 
-		// field (maximum) length
-		length, err := strconv.Atoi(sf.Tag.Get("length"))
+		// initialize field with empty struct, and decode it
+		value := s.ISO8583NewField(i)
+
+		var nextFieldOffset int
+		var err error
+		if fs.SubMessage {
+			// the names of the subfields are needed by the lenient errors only
+			var subPrefix string
+			if errs != nil {
+				subPrefix = prefix + fs.Name + "."
+			}
+			nextFieldOffset, err = decodeSubField(raw[it:], nestedStructOf(s, value), encoder, fs, errs, subPrefix)
+		} else {
+			var f field
+			if f, err = fs.asField(value); err != nil {
+				return 0, err
+			}
+			nextFieldOffset, err = f.Decode(raw[it:], encoder, fs.Length, fs.Format, fs.Validator)
+		}
 		if err != nil {
-			return err
+			if errs == nil {
+				return 0, err
+			}
+			fe := &FieldError{Field: prefix + fs.Name, Err: err}
+			// without the offset of the next field, decoding cannot go on
+			if nextFieldOffset == 0 {
+				return 0, fe
+			}
+			fe.Raw = raw[it : it+nextFieldOffset]
+			*errs = append(*errs, fe)
 		}
-		// iso field format, "" means that field has fixed length, but
-		// e.g. LLVAR means that length indicator is encoded in the first 2 byte
-		// in this case length means "maximum length
-		format := sf.Tag.Get("format")
-		validator := sf.Tag.Get("validator")
+		it += nextFieldOffset
+	}
 
-		// Decode field
-		structField := v.Field(i)
+	setBitmaps(s, spec, bitmaps)
+	return it, nil
+}
 
-		// skip if unexported
-		if !structField.CanSet() {
-			continue
+// setBitmaps stores the secondary bitmap in the uint64 field with index 1,
+// and passes the primary bitmap to the struct if it keeps it
+func setBitmaps(s Generated, spec BitmapSpec, bitmaps []uint64) {
+	if !spec.Fixed {
+		var secondary uint64
+		if len(bitmaps) > 1 {
+			secondary = bitmaps[1]
 		}
-		// initialize field with empty struct
-		fieldTyp := reflect.New(structField.Type().Elem())
-		structField.Set(fieldTyp)
+		s.ISO8583SetSecondaryBitmap(secondary)
+	}
+	if h, ok := s.(bitmapHolder); ok {
+		h.setPrimaryBitmap(bitmaps[0])
+	}
+}
 
-		f := v.Field(i).Interface().(field)
-		nextFieldOffset, err := f.Decode(bytes[it:], m.encoder, length, format, validator)
-		if err != nil {
-			return err
+// encodeSubField encodes a submessage subfield, and adds the length prefix
+// in specific format
+func encodeSubField(sub Generated, encoder int, fs *FieldSpec) ([]byte, error) {
+	spec, err := parseBitmapSpec(fs.Tag.Get("bitmap"))
+	if err != nil {
+		return nil, err
+	}
+	val, err := encodeBitmapped(sub, encoder, spec)
+	if err != nil {
+		return nil, err
+	}
+	// binary bitmaps cannot be validated as characters,
+	// their subfields are validated on their own
+	if spec.Encoding != BitmapBinary {
+		if err := validateBytes(val, fs.Validator); err != nil {
+			return nil, err
 		}
-		it += nextFieldOffset
 	}
-	return nil
+	if fs.Format == "" {
+		if len(val) != fs.Length {
+			return nil, errors.New("invalid value length")
+		}
+		return val, nil
+	}
+	if len(val) > fs.Length {
+		return nil, errors.New("invalid value length")
+	}
+	lInd, err := lengthIndicator(encoder, len(val), fs.Format)
+	if err != nil {
+		return nil, err
+	}
+	return append(lInd, val...), nil
+}
+
+// decodeSubField decodes a submessage subfield, and returns the offset of the next field.
+// In lenient decoding, the errors of the submessage are appended to errs
+// as long as its length is known
+func decodeSubField(raw []byte, sub Generated, encoder int, fs *FieldSpec, errs *ValidationErrors, prefix string) (int, error) {
+	spec, err := parseBitmapSpec(fs.Tag.Get("bitmap"))
+	if err != nil {
+		return 0, err
+	}
+	l, lenOfLen, err := getFieldLength(raw, encoder, fs.Length, fs.Format)
+	if err != nil {
+		return 0, err
+	}
+	if fs.Format == "" {
+		l = fs.Length
+	}
+	if len(raw) < lenOfLen+l {
+		return 0, errors.New("submessage too short")
+	}
+	val := raw[lenOfLen : lenOfLen+l]
+	if spec.Encoding != BitmapBinary {
+		if err := validateBytes(val, fs.Validator); err != nil {
+			if errs == nil {
+				return 0, err
+			}
+			*errs = append(*errs, &FieldError{Field: strings.TrimSuffix(prefix, "."), Err: err, Raw: raw[:lenOfLen+l]})
+		}
+	}
+	if _, err := decodeBitmapped(val, sub, encoder, spec, errs, prefix); err != nil {
+		if errs == nil {
+			return 0, err
+		}
+		fe, ok := err.(*FieldError)
+		if !ok {
+			fe = &FieldError{Field: strings.TrimSuffix(prefix, "."), Err: err}
+		}
+		fe.Raw = raw[:lenOfLen+l]
+		*errs = append(*errs, fe)
+	}
+	return lenOfLen + l, nil
 }
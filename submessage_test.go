@@ -3,6 +3,7 @@ package iso8583
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -22,12 +23,12 @@ func TestMessageWithSubMessage(t *testing.T) {
 		DE2:   NewNumeric("4846811212"),        // Primary Account Number
 		DE3:   NewNumeric("201234"),            // Processing Code
 		DE4:   NewNumeric("10000000"),          // Amount, Transaction
-		DE7:   NewNumeric("1107221800"),        // Date And Time, Transmission
+		DE7:   NewDateTime("1107221800"),       // Date And Time, Transmission
 		DE11:  NewNumeric("000001"),            // Systems Trace Audit Number
-		DE12:  NewNumeric("161204171926"),      // Date And Time, Local Transaction
+		DE12:  NewDateTime("161204171926"),     // Date And Time, Local Transaction
 		DE22:  NewAlphanumeric("FABCDE123ABD"), // Point Of Service Data Code
 		DE32:  NewNumeric("414243"),            // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),               // Action Code
+		DE39:  NewAlphanumeric("000"),          // Action Code
 		DE41:  NewANS("termid12"),              // Card Acceptor Terminal Identification
 		DE43:  NewANS("Community1"),            // Card Acceptor Name/Location
 		DE102: NewANS("12341234234"),           // Account Identification 1
@@ -90,12 +91,12 @@ func TestMessageWithSubMessageDecode(t *testing.T) {
 		DE2:   NewNumeric("4846811212"),        // Primary Account Number
 		DE3:   NewNumeric("201234"),            // Processing Code
 		DE4:   NewNumeric("10000000"),          // Amount, Transaction
-		DE7:   NewNumeric("1107221800"),        // Date And Time, Transmission
+		DE7:   NewDateTime("1107221800"),       // Date And Time, Transmission
 		DE11:  NewNumeric("000001"),            // Systems Trace Audit Number
-		DE12:  NewNumeric("161204171926"),      // Date And Time, Local Transaction
+		DE12:  NewDateTime("161204171926"),     // Date And Time, Local Transaction
 		DE22:  NewAlphanumeric("FABCDE123ABD"), // Point Of Service Data Code
 		DE32:  NewNumeric("414243"),            // Acquiring Institution Identification Code
-		DE39:  NewNumeric("000"),               // Action Code
+		DE39:  NewAlphanumeric("000"),          // Action Code
 		DE41:  NewANS("termid12"),              // Card Acceptor Terminal Identification
 		DE43:  NewANS("Community1"),            // Card Acceptor Name/Location
 		DE102: NewANS("12341234234"),           // Account Identification 1
@@ -206,6 +207,38 @@ func TestMessageWithSubMessageDecodeWithExtendedBitmap(t *testing.T) {
 	}
 }
 
+func TestSubMessageSE65(t *testing.T) {
+	// the first bit of the secondary bitmap is SE65, not a third bitmap
+	sm := &SubMessage{
+		SE2:  NewANS("Test Address"),
+		SE65: NewAlphanumeric("Y"),
+		SE98: NewAlphanumeric("1"),
+	}
+	b, err := sm.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "C0000000000000008000000040000000Test Address                 Y1"
+	if string(b) != expected {
+		t.Errorf("Encoded should be %s, instead of %s", expected, b)
+	}
+
+	decoded := &SubMessage{}
+	if err := decoded.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, decoded.SE65.String(), "Y", "SE65")
+	equals(t, decoded.SE98.String(), "1", "SE98")
+
+	// the bit 65 of a message without DE65 is ignored
+	m := &Message{}
+	m.encoder = ASCII
+	if err := m.Decode([]byte("1200C0000000000000008000000000000000104846811212")); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, m.DE2.String(), "4846811212", "DE2")
+}
+
 func TestSubMessageEncodeBinaries(t *testing.T) {
 	m := &Message{
 		DE52: NewBinary64Uint64(1 << 5), // Personal Identification Number Data
@@ -273,3 +306,150 @@ func TestMessageDecodeBinaries(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+type testPrivateData struct {
+	SF1  uint64
+	SF2  *N              `format:"" length:"4" validator:"N" json:",omitempty"`
+	SF3  *testNestedData `format:"LLVAR" length:"99" bitmap:"4,binary" json:",omitempty"`
+	SF5  *ANS            `format:"LLVAR" length:"20" validator:"ANS" json:",omitempty"`
+	SF70 *AN             `format:"" length:"2" validator:"AN" json:",omitempty"`
+}
+
+type testNestedData struct {
+	TG2 *AN `format:"" length:"3" validator:"AN" json:",omitempty"`
+	TG9 *N  `format:"LLVAR" length:"10" validator:"N" json:",omitempty"`
+}
+
+func TestGenericSubMessage(t *testing.T) {
+	sm := &testPrivateData{
+		SF2: NewNumeric("0012"),
+		SF3: &testNestedData{
+			TG2: NewAlphanumeric("ABC"),
+			TG9: NewNumeric("42"),
+		},
+		SF5:  NewANS("hello"),
+		SF70: NewAlphanumeric("XY"),
+	}
+	b, err := EncodeSubMessage(sm, ASCII, DefaultBitmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "E80000000000000004000000000000000012" + "11\x40\x80\x00\x00ABC0242" + "05hello" + "XY"
+	if string(b) != expected {
+		t.Errorf("Encoded should be %q, instead of %q", expected, b)
+	}
+	if bitmapHex(sm.SF1) != "0400000000000000" {
+		t.Errorf("secondary bitmap should be 0400000000000000, instead of %s", bitmapHex(sm.SF1))
+	}
+
+	decoded := &testPrivateData{}
+	if err := DecodeSubMessage(b, decoded, ASCII, DefaultBitmap); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sm, decoded) {
+		t.Errorf("decoded should be %#v, instead of %#v", sm, decoded)
+	}
+}
+
+func TestFixedBitmapSubMessage(t *testing.T) {
+	spec := BitmapSpec{Size: 4, Encoding: BitmapHex, Fixed: true}
+	sm := &testNestedData{
+		TG2: NewAlphanumeric("ABC"),
+		TG9: NewNumeric("42"),
+	}
+	b, err := EncodeSubMessage(sm, ASCII, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "40800000ABC0242" {
+		t.Errorf("Encoded should be 40800000ABC0242, instead of %s", b)
+	}
+
+	decoded := &testNestedData{}
+	if err := DecodeSubMessage(b, decoded, ASCII, spec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sm, decoded) {
+		t.Error("not equal")
+	}
+
+	// SF70 does not fit in a single 4 byte bitmap
+	_, err = EncodeSubMessage(&testPrivateData{SF70: NewAlphanumeric("XY")}, ASCII, spec)
+	if err == nil {
+		t.Error("expecting error, field out of bitmap range")
+	}
+
+	if err := DecodeSubMessage([]byte("8080"), &testNestedData{}, ASCII, spec); err == nil {
+		t.Error("expecting error, bitmap too short")
+	}
+	if _, err := EncodeSubMessage(testNestedData{}, ASCII, spec); err == nil {
+		t.Error("expecting error, submessage is not a pointer")
+	}
+}
+
+func TestMessageWithSubMessageFollowedByField(t *testing.T) {
+	m := &Message{
+		DE125: &SubMessage{
+			SE2: NewANS("Test Address"),
+		},
+		DE126: NewANS("next"),
+	}
+	m.Mti = "1200"
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Message{}
+	if err := decoded.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.DE126 == nil || decoded.DE126.String() != "next" {
+		t.Errorf("DE126 should be next, instead of %v", decoded.DE126)
+	}
+	if !reflect.DeepEqual(m, decoded) {
+		t.Error("not equal")
+	}
+}
+
+func TestSubMessageTaggedField(t *testing.T) {
+	base := strings.Repeat("A", 194)
+	tagged := strings.Repeat("T", 57)
+	var scenarios = []struct {
+		se21    *TaggedANS
+		encoded string
+	}{
+		{
+			se21:    NewTaggedANS(base),
+			encoded: "00000C0000000000" + base + "001A",
+		},
+		{
+			se21:    &TaggedANS{Value: []byte(base), Tags: []TLVElement{{Tag: "0008", Value: []byte(tagged)}}},
+			encoded: "00000C0000000000" + base + "0008" + tagged + "001A",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		sm := &SubMessage{
+			SE21: scenario.se21,
+			SE22: NewANS("A"),
+		}
+		b, err := sm.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != scenario.encoded {
+			t.Errorf("Encoded should be %s, instead of %s", scenario.encoded, b)
+		}
+
+		decoded := &SubMessage{}
+		if err := decoded.Decode(b); err != nil {
+			t.Fatal(err)
+		}
+		equals(t, decoded.SE21.String(), base, "SE21 base")
+		v, _ := decoded.SE21.Get("0008")
+		expected, _ := scenario.se21.Get("0008")
+		equals(t, string(v), string(expected), "SE21 tag 0008")
+		equals(t, decoded.SE22.String(), "A", "SE22")
+	}
+}
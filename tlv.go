@@ -0,0 +1,351 @@
+package iso8583
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TLVSpec describes the layout of the subelements of a TLV data element
+type TLVSpec struct {
+	// TagLength is the number of characters of a tag, e.g. 2 or 3
+	TagLength int
+	// LenLength is the number of digits of a length, e.g. 2 or 3
+	LenLength int
+	// Sorted encodes the subelements in ascending tag order,
+	// otherwise they are encoded in the order they were set or decoded
+	Sorted bool
+	// Strict rejects the tags missing from Tags,
+	// otherwise unknown tags are passed through as they are
+	Strict bool
+	// Tags are the known tags with the rules of their value
+	Tags map[string]TLVTag
+}
+
+// TLVTag is the definition of a known tag
+type TLVTag struct {
+	// MaxLength is the maximum length of the value, 0 means no limit
+	// besides the width of the length
+	MaxLength int
+	// Validator is applied to the value, like the validator struct tag of fields
+	Validator string
+}
+
+// TLVElement is a single tag/length/value subelement
+type TLVElement struct {
+	Tag   string
+	Value []byte
+}
+
+// TLV is a data element carrying tag/length/value subelements with ASCII tags
+// and lengths, e.g. Mastercard DE48 subelements or Visa field 62 datasets.
+//
+// In a message struct, the layout can be given by the tlv tag,
+// `tlv:"<tag length>,<length length>[,sorted][,strict=<tag>|<tag>...]"`,
+// strict listing the known tags, e.g.
+//
+//	PD48 *TLV `format:"LLLVAR" length:"999" validator:"ANS" tlv:"2,2"`
+//	PD62 *TLV `format:"LLLVAR" length:"255" validator:"ANS" tlv:"2,2,strict=01|02"`
+type TLV struct {
+	Spec     TLVSpec
+	Elements []TLVElement
+}
+
+func NewTLV(spec TLVSpec) *TLV {
+	return &TLV{Spec: spec}
+}
+
+// ParseTLV decodes subelements from raw, e.g. the value of an ANS data element
+func ParseTLV(raw []byte, spec TLVSpec) (*TLV, error) {
+	t := NewTLV(spec)
+	return t, t.parse(raw)
+}
+
+// Bytes returns the encoded subelements, without the length prefix of the field
+func (t *TLV) Bytes() ([]byte, error) {
+	if err := t.Spec.check(); err != nil {
+		return nil, err
+	}
+	elements := t.Elements
+	if t.Spec.Sorted {
+		elements = append([]TLVElement{}, elements...)
+		sort.SliceStable(elements, func(i, j int) bool {
+			return elements[i].Tag < elements[j].Tag
+		})
+	}
+
+	res := make([]byte, 0, 64)
+	for _, e := range elements {
+		if len(e.Tag) != t.Spec.TagLength {
+			return nil, errors.New("invalid tag length: " + e.Tag)
+		}
+		if err := t.Spec.validate(e); err != nil {
+			return nil, err
+		}
+		l := fmt.Sprintf("%0*d", t.Spec.LenLength, len(e.Value))
+		if len(l) != t.Spec.LenLength {
+			return nil, errors.New("invalid value length of tag " + e.Tag)
+		}
+		res = append(res, e.Tag...)
+		res = append(res, l...)
+		res = append(res, e.Value...)
+	}
+	return res, nil
+}
+
+func (t *TLV) parse(raw []byte) error {
+	if err := t.Spec.check(); err != nil {
+		return err
+	}
+	t.Elements = nil
+	for it := 0; it < len(raw); {
+		if len(raw)-it < t.Spec.TagLength+t.Spec.LenLength {
+			return errors.New("truncated TLV subelement")
+		}
+		tag := string(raw[it : it+t.Spec.TagLength])
+		it += t.Spec.TagLength
+		l, err := strconv.Atoi(string(raw[it : it+t.Spec.LenLength]))
+		if err != nil || l < 0 {
+			return errors.New("invalid length of tag " + tag)
+		}
+		it += t.Spec.LenLength
+		if len(raw)-it < l {
+			return errors.New("truncated value of tag " + tag)
+		}
+		e := TLVElement{Tag: tag, Value: raw[it : it+l]}
+		if err := t.Spec.validate(e); err != nil {
+			return err
+		}
+		t.Elements = append(t.Elements, e)
+		it += l
+	}
+	return nil
+}
+
+func (t *TLV) Encode(encoder, length int, format, validator string) ([]byte, error) {
+	val, err := t.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBytes(val, validator); err != nil {
+		return []byte{}, err
+	}
+	if format == "" {
+		return []byte{}, errors.New("TLV has variable length")
+	}
+	if len(val) > length {
+		return nil, errors.New("invalid value length")
+	}
+	lInd, err := lengthIndicator(encoder, len(val), format)
+	if err != nil {
+		return nil, err
+	}
+	val = append(lInd, val...)
+
+	switch encoder {
+	case BCDIC:
+		panic("implement me")
+	default: //ASCII encoding
+		return val, nil
+	}
+}
+
+func (t *TLV) Decode(raw []byte, encoder, length int, format, validator string) (int, error) {
+	var nextFieldOffset int
+	switch encoder {
+	case BCDIC:
+	case ASCII:
+		if format == "" {
+			return 0, errors.New("TLV has variable length")
+		}
+		l, lenOfLen, err := getFieldLength(raw, encoder, length, format)
+		if err != nil {
+			return 0, err
+		}
+		val := raw[lenOfLen : l+lenOfLen]
+		nextFieldOffset = lenOfLen + l
+		if err := validateBytes(val, validator); err != nil {
+			return nextFieldOffset, err
+		}
+		if err := t.parse(val); err != nil {
+			return nextFieldOffset, err
+		}
+	}
+	return nextFieldOffset, nil
+}
+
+func (t *TLV) isEmpty() bool {
+	return len(t.Elements) == 0
+}
+
+// configure reads the layout from the tlv struct tag, unless it is already set
+func (t *TLV) configure(tag reflect.StructTag) error {
+	if t.Spec.TagLength != 0 {
+		return nil
+	}
+	opts := strings.Split(tag.Get("tlv"), ",")
+	if len(opts) < 2 {
+		return errors.New("missing tlv tag")
+	}
+	var err error
+	if t.Spec.TagLength, err = strconv.Atoi(opts[0]); err != nil {
+		return errors.New("invalid tlv tag length: " + opts[0])
+	}
+	if t.Spec.LenLength, err = strconv.Atoi(opts[1]); err != nil {
+		return errors.New("invalid tlv length length: " + opts[1])
+	}
+	for _, opt := range opts[2:] {
+		switch {
+		case opt == "sorted":
+			t.Spec.Sorted = true
+		case strings.HasPrefix(opt, "strict="):
+			t.Spec.Strict = true
+			if t.Spec.Tags == nil {
+				t.Spec.Tags = map[string]TLVTag{}
+			}
+			for _, known := range strings.Split(strings.TrimPrefix(opt, "strict="), "|") {
+				if len(known) != t.Spec.TagLength {
+					return errors.New("invalid tlv strict tag: " + known)
+				}
+				t.Spec.Tags[known] = TLVTag{}
+			}
+		case opt == "strict":
+			// without the known tags, every tag would be rejected
+			return errors.New("tlv option strict needs the known tags, e.g. strict=01|02")
+		default:
+			return errors.New("invalid tlv option: " + opt)
+		}
+	}
+	return t.Spec.check()
+}
+
+// Tags returns the tags in the order they are kept
+func (t *TLV) Tags() []string {
+	tags := make([]string, 0, len(t.Elements))
+	for _, e := range t.Elements {
+		tags = append(tags, e.Tag)
+	}
+	return tags
+}
+
+// Get returns the value of the first subelement with the tag
+func (t *TLV) Get(tag string) ([]byte, bool) {
+	for _, e := range t.Elements {
+		if e.Tag == tag {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// GetString returns the value of the tag, or "" if it is missing
+func (t *TLV) GetString(tag string) string {
+	v, _ := t.Get(tag)
+	return string(v)
+}
+
+// GetInt returns the value of the tag as a number
+func (t *TLV) GetInt(tag string) (int, error) {
+	v, ok := t.Get(tag)
+	if !ok {
+		return 0, errors.New("missing tag " + tag)
+	}
+	return strconv.Atoi(string(v))
+}
+
+// Set replaces the value of the tag in place, or appends it if it is missing
+func (t *TLV) Set(tag string, value []byte) {
+	for i := range t.Elements {
+		if t.Elements[i].Tag == tag {
+			t.Elements[i].Value = value
+			return
+		}
+	}
+	t.Elements = append(t.Elements, TLVElement{Tag: tag, Value: value})
+}
+
+func (t *TLV) SetString(tag, value string) {
+	t.Set(tag, []byte(value))
+}
+
+// SetInt sets the number as the value of the tag, left padded with '0' to width
+func (t *TLV) SetInt(tag string, value, width int) {
+	t.Set(tag, []byte(fmt.Sprintf("%0*d", width, value)))
+}
+
+// Delete removes every subelement with the tag
+func (t *TLV) Delete(tag string) {
+	elements := t.Elements[:0]
+	for _, e := range t.Elements {
+		if e.Tag != tag {
+			elements = append(elements, e)
+		}
+	}
+	t.Elements = elements
+}
+
+func (t TLV) String() string {
+	b, _ := t.Bytes()
+	return string(b)
+}
+
+// MarshalJSON encodes the subelements as an object, keeping their order
+func (t *TLV) MarshalJSON() ([]byte, error) {
+	res := bytes.NewBufferString("{")
+	for i, e := range t.Elements {
+		if i > 0 {
+			res.WriteByte(',')
+		}
+		tag, _ := json.Marshal(e.Tag)
+		value, _ := json.Marshal(string(e.Value))
+		res.Write(tag)
+		res.WriteByte(':')
+		res.Write(value)
+	}
+	res.WriteByte('}')
+	return res.Bytes(), nil
+}
+
+// UnmarshalJSON decodes the subelements from an object, in ascending tag order
+func (t *TLV) UnmarshalJSON(data []byte) error {
+	values := map[string]string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	tags := make([]string, 0, len(values))
+	for tag := range values {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	t.Elements = nil
+	for _, tag := range tags {
+		t.SetString(tag, values[tag])
+	}
+	return nil
+}
+
+func (s TLVSpec) check() error {
+	if s.TagLength < 1 || s.LenLength < 1 {
+		return errors.New("invalid TLV layout")
+	}
+	return nil
+}
+
+func (s TLVSpec) validate(e TLVElement) error {
+	def, ok := s.Tags[e.Tag]
+	if !ok {
+		if s.Strict {
+			return errors.New("unknown tag " + e.Tag)
+		}
+		return nil
+	}
+	if def.MaxLength > 0 && len(e.Value) > def.MaxLength {
+		return errors.New("invalid value length of tag " + e.Tag)
+	}
+	return validateBytes(e.Value, def.Validator)
+}
@@ -0,0 +1,160 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTLVEncode(t *testing.T) {
+	tlv := NewTLV(TLVSpec{TagLength: 2, LenLength: 2})
+	tlv.SetString("42", "210")
+	tlv.SetInt("10", 7, 3)
+	tlv.SetString("61", "00000")
+	b, err := tlv.Encode(ASCII, 999, "LLLVAR", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "02342032101003007610500000", "unsorted")
+
+	tlv.Spec.Sorted = true
+	b, err = tlv.Encode(ASCII, 999, "LLLVAR", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "02310030074203210610500000", "sorted")
+	if !reflect.DeepEqual(tlv.Tags(), []string{"42", "10", "61"}) {
+		t.Error("sorting on encode should keep the order of elements")
+	}
+
+	tlv.SetString("42", "212")
+	tlv.Delete("61")
+	equals(t, tlv.String(), "10030074203212", "after set and delete")
+
+	if _, err := tlv.Encode(ASCII, 5, "LLLVAR", "ANS"); err == nil {
+		t.Error("expecting error, length 5 < len(tlv)")
+	}
+	tlv.SetString("123", "x")
+	if _, err := tlv.Encode(ASCII, 999, "LLLVAR", "ANS"); err == nil {
+		t.Error("expecting error, tag 123 is too long")
+	}
+}
+
+func TestTLVDecode(t *testing.T) {
+	tlv := &TLV{}
+	tlv.Spec = TLVSpec{
+		TagLength: 3,
+		LenLength: 3,
+		Tags: map[string]TLVTag{
+			"001": {MaxLength: 4, Validator: "N"},
+		},
+	}
+	n, err := tlv.Decode([]byte("019001004123499Z003ABCrest"), ASCII, 999, "LLLVAR", "ANS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 22 {
+		t.Errorf("next field offset should be 22, instead of %d", n)
+	}
+	if v, err := tlv.GetInt("001"); err != nil || v != 1234 {
+		t.Errorf("tag 001 should be 1234, instead of %d (%v)", v, err)
+	}
+	// unknown tags are passed through
+	equals(t, tlv.GetString("99Z"), "ABC", "unknown tag")
+	if _, ok := tlv.Get("002"); ok {
+		t.Error("tag 002 should be missing")
+	}
+
+	var scenarios = []struct {
+		raw  string
+		spec TLVSpec
+	}{
+		// value of a known tag is invalid
+		{raw: "0080010021-", spec: TLVSpec{TagLength: 3, LenLength: 3, Tags: map[string]TLVTag{"001": {Validator: "AN"}}}},
+		// unknown tag in strict mode
+		{raw: "01000100212", spec: TLVSpec{TagLength: 3, LenLength: 3, Strict: true}},
+		// value is truncated
+		{raw: "0090010101", spec: TLVSpec{TagLength: 3, LenLength: 3}},
+		// missing layout
+		{raw: "00800100212"},
+	}
+	for _, scenario := range scenarios {
+		tlv := NewTLV(scenario.spec)
+		if _, err := tlv.Decode([]byte(scenario.raw), ASCII, 999, "LLLVAR", "ANS"); err == nil {
+			t.Errorf("expecting error for %s", scenario.raw)
+		}
+	}
+}
+
+func TestTLVInSubMessage(t *testing.T) {
+	type privateData struct {
+		PD2 *TLV `format:"LLLVAR" length:"999" validator:"ANS" tlv:"2,2,sorted" json:",omitempty"`
+		PD3 *N   `format:"" length:"2" validator:"N" json:",omitempty"`
+	}
+	pd := &privateData{
+		PD2: &TLV{},
+		PD3: NewNumeric("12"),
+	}
+	pd.PD2.SetString("B2", "x")
+	pd.PD2.SetString("A1", "yz")
+	b, err := EncodeSubMessage(pd, ASCII, DefaultBitmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), "6000000000000000011A102yzB201x12", "")
+
+	decoded := &privateData{}
+	if err := DecodeSubMessage(b, decoded, ASCII, DefaultBitmap); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.PD2.Spec.Sorted || decoded.PD2.Spec.TagLength != 2 {
+		t.Errorf("layout should be read from the tlv tag, instead of %#v", decoded.PD2.Spec)
+	}
+	equals(t, decoded.PD2.GetString("B2"), "x", "")
+	equals(t, decoded.PD3.String(), "12", "")
+}
+
+func TestTLVStrictTag(t *testing.T) {
+	type privateData struct {
+		PD2 *TLV `format:"LLLVAR" length:"999" validator:"ANS" tlv:"2,2,strict=A1|B2" json:",omitempty"`
+	}
+	decoded := &privateData{}
+	if err := DecodeSubMessage([]byte("4000000000000000011A102yzB201x"), decoded, ASCII, DefaultBitmap); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.PD2.Spec.Strict || len(decoded.PD2.Spec.Tags) != 2 {
+		t.Errorf("known tags should be read from the tlv tag, instead of %#v", decoded.PD2.Spec)
+	}
+	equals(t, decoded.PD2.GetString("B2"), "x", "")
+
+	decoded = &privateData{}
+	if err := DecodeSubMessage([]byte("4000000000000000011A102yzC301x"), decoded, ASCII, DefaultBitmap); err == nil {
+		t.Error("expecting error for unknown tag C3")
+	}
+
+	for _, tag := range []string{`tlv:"2,2,strict"`, `tlv:"2,2,strict=A1|B"`} {
+		if err := (&TLV{}).configure(reflect.StructTag(tag)); err == nil {
+			t.Errorf("expecting error for %s", tag)
+		}
+	}
+}
+
+func TestTLVJSON(t *testing.T) {
+	tlv, err := ParseTLV([]byte("42032101003007610500000"), TLVSpec{TagLength: 2, LenLength: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(tlv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(b), `{"42":"210","10":"007","61":"00000"}`, "")
+
+	decoded := &TLV{}
+	if err := json.Unmarshal(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Tags(), []string{"10", "42", "61"}) {
+		t.Errorf("tags should be sorted, instead of %v", decoded.Tags())
+	}
+}
@@ -0,0 +1,415 @@
+// Package tr31 parses and builds the key blocks of ANSI X9.143 (TR-31),
+// versions A, B, C and D, e.g. the working keys of the key exchange
+// messages. The keys are wrapped and unwrapped in software, with the key
+// block protection key (KBPK) in the clear.
+package tr31
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fluidpay/iso8583"
+	"github.com/fluidpay/iso8583/mac"
+)
+
+const (
+	// VersionA is the TDES key block with the variant binding method,
+	// deprecated by X9.143
+	VersionA byte = 'A'
+	// VersionB is the TDES key block with the key derivation binding method
+	VersionB byte = 'B'
+	// VersionC is the TDES key block with the variant binding method of
+	// version A, and another version ID
+	VersionC byte = 'C'
+	// VersionD is the AES key block with the key derivation binding method
+	VersionD byte = 'D'
+)
+
+// headerLength is the length of the header without its optional blocks
+const headerLength = 16
+
+// random is the source of the random padding, replaced in tests
+var random io.Reader = rand.Reader
+
+// OptionalBlock is an optional block of the header, e.g. KS, the KSN of
+// a DUKPT initial key
+type OptionalBlock struct {
+	// ID is the identifier of 2 characters
+	ID string
+	// Data is the data of printable characters
+	Data string
+}
+
+// Header is the header of a key block, in the clear and authenticated
+type Header struct {
+	// Version is the key block version ID, e.g. VersionB
+	Version byte
+	// KeyUsage is the usage of the key, e.g. P0 for a PIN encryption key,
+	// M3 for an X9.19 MAC key, K0 for a key encryption key or B0 for a BDK
+	KeyUsage string
+	// Algorithm is the algorithm of the key, e.g. T for TDES or A for AES
+	Algorithm byte
+	// ModeOfUse is the operations allowed to the key, e.g. E for encryption
+	// only, B for both encryption and decryption, C for MAC generation and
+	// verification, N without restrictions
+	ModeOfUse byte
+	// KeyVersion is the key version number of 2 characters, 00 if unused
+	KeyVersion string
+	// Exportability is E if the key can be exported, N if it cannot, S if
+	// it is sensitive
+	Exportability byte
+	// OptionalBlocks are the optional blocks, without the padding block PB
+	OptionalBlocks []OptionalBlock
+}
+
+// Optional returns the data of the optional block of the ID
+func (h *Header) Optional(id string) (string, bool) {
+	for _, b := range h.OptionalBlocks {
+		if b.ID == id {
+			return b.Data, true
+		}
+	}
+	return "", false
+}
+
+// blockSize is the size of the cipher blocks of the version
+func blockSize(version byte) int {
+	if version == VersionD {
+		return aes.BlockSize
+	}
+	return des.BlockSize
+}
+
+// macLength is the number of bytes of the MAC of the version
+func macLength(version byte) int {
+	switch version {
+	case VersionA, VersionC:
+		return 4
+	case VersionB:
+		return 8
+	}
+	return 16
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// encode returns the header of the key block of the length, with the
+// padding block PB if the optional blocks are not a multiple of the
+// cipher block size
+func (h *Header) encode(payloadLength int) (string, error) {
+	switch h.Version {
+	case VersionA, VersionB, VersionC, VersionD:
+	default:
+		return "", errors.New("invalid key block version: " + string(h.Version))
+	}
+	fixed := []string{h.KeyUsage, string(h.Algorithm), string(h.ModeOfUse), h.KeyVersion, string(h.Exportability)}
+	for i, l := range []int{2, 1, 1, 2, 1} {
+		if len(fixed[i]) != l || !printable(fixed[i]) {
+			return "", errors.New("invalid key block header field: " + fixed[i])
+		}
+	}
+
+	var opt strings.Builder
+	n := len(h.OptionalBlocks)
+	for _, b := range h.OptionalBlocks {
+		if len(b.ID) != 2 || !printable(b.ID) || !printable(b.Data) || b.ID == "PB" {
+			return "", errors.New("invalid optional block: " + b.ID)
+		}
+		opt.WriteString(b.ID)
+		if l := 4 + len(b.Data); l <= 0xFF {
+			fmt.Fprintf(&opt, "%02X", l)
+		} else {
+			// the extended length, 00 and the length of the length
+			fmt.Fprintf(&opt, "0004%04X", 10+len(b.Data))
+		}
+		opt.WriteString(b.Data)
+	}
+	size := blockSize(h.Version)
+	if rest := opt.Len() % size; rest != 0 {
+		pad := size - rest
+		if pad < 4 {
+			pad += size
+		}
+		fmt.Fprintf(&opt, "PB%02X%s", pad, strings.Repeat("0", pad-4))
+		n++
+	}
+	if n > 99 {
+		return "", errors.New("too many optional blocks")
+	}
+
+	length := headerLength + opt.Len() + 2*payloadLength + 2*macLength(h.Version)
+	if length > 9999 {
+		return "", errors.New("key block too long")
+	}
+	return fmt.Sprintf("%c%04d%s%02d00%s", h.Version, length, strings.Join(fixed, ""), n, opt.String()), nil
+}
+
+// ParseHeader parses the header of a key block, without its key, and returns
+// the length of the header
+func ParseHeader(block string) (*Header, int, error) {
+	if len(block) < headerLength {
+		return nil, 0, errors.New("key block too short")
+	}
+	h := &Header{
+		Version:       block[0],
+		KeyUsage:      block[5:7],
+		Algorithm:     block[7],
+		ModeOfUse:     block[8],
+		KeyVersion:    block[9:11],
+		Exportability: block[11],
+	}
+	switch h.Version {
+	case VersionA, VersionB, VersionC, VersionD:
+	default:
+		return nil, 0, errors.New("invalid key block version: " + string(h.Version))
+	}
+	if !printable(block[:headerLength]) {
+		return nil, 0, errors.New("invalid key block header")
+	}
+	length, err := strconv.Atoi(block[1:5])
+	if err != nil || length != len(block) {
+		return nil, 0, errors.New("invalid key block length: " + block[1:5])
+	}
+	n, err := strconv.Atoi(block[12:14])
+	if err != nil {
+		return nil, 0, errors.New("invalid number of optional blocks: " + block[12:14])
+	}
+
+	offset := headerLength
+	for i := 0; i < n; i++ {
+		if len(block) < offset+4 {
+			return nil, 0, errors.New("optional block too short")
+		}
+		id := block[offset : offset+2]
+		l, err := strconv.ParseUint(block[offset+2:offset+4], 16, 8)
+		if err != nil {
+			return nil, 0, errors.New("invalid length of optional block " + id)
+		}
+		start := offset + 4
+		if l == 0 {
+			// the extended length, the length of the length and the length
+			if len(block) < start+2 {
+				return nil, 0, errors.New("optional block too short")
+			}
+			ll, err := strconv.ParseUint(block[start:start+2], 16, 8)
+			if err != nil || len(block) < start+2+int(ll) {
+				return nil, 0, errors.New("invalid length of optional block " + id)
+			}
+			if l, err = strconv.ParseUint(block[start+2:start+2+int(ll)], 16, 32); err != nil {
+				return nil, 0, errors.New("invalid length of optional block " + id)
+			}
+			start += 2 + int(ll)
+		}
+		end := offset + int(l)
+		if end < start || end > len(block) {
+			return nil, 0, errors.New("invalid length of optional block " + id)
+		}
+		if id != "PB" {
+			h.OptionalBlocks = append(h.OptionalBlocks, OptionalBlock{ID: id, Data: block[start:end]})
+		}
+		offset = end
+	}
+	if !printable(block[headerLength:offset]) {
+		return nil, 0, errors.New("invalid optional blocks")
+	}
+	if (offset-headerLength)%blockSize(h.Version) != 0 {
+		return nil, 0, errors.New("optional blocks not padded to the cipher block size")
+	}
+	return h, offset, nil
+}
+
+// keys returns the cipher of the encryption key and the MAC key of the key
+// block, derived from the KBPK
+func keys(version byte, kbpk []byte) (cipher.Block, cipher.Block, error) {
+	switch version {
+	case VersionA, VersionC:
+		if len(kbpk) != 16 && len(kbpk) != 24 {
+			return nil, nil, errors.New("invalid TDES KBPK length: " + strconv.Itoa(len(kbpk)))
+		}
+		// the variants of the KBPK
+		kbek, kbmk := make([]byte, len(kbpk)), make([]byte, len(kbpk))
+		for i := range kbpk {
+			kbek[i], kbmk[i] = kbpk[i]^0x45, kbpk[i]^0x4D
+		}
+		enc, err := tdesCipher(kbek)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := tdesCipher(kbmk)
+		return enc, m, err
+	case VersionB:
+		if len(kbpk) != 16 && len(kbpk) != 24 {
+			return nil, nil, errors.New("invalid TDES KBPK length: " + strconv.Itoa(len(kbpk)))
+		}
+		c, err := tdesCipher(kbpk)
+		if err != nil {
+			return nil, nil, err
+		}
+		algorithm := 0
+		if len(kbpk) == 24 {
+			algorithm = 1
+		}
+		kbek, kbmk := derive(c, algorithm, len(kbpk))
+		enc, err := tdesCipher(kbek)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := tdesCipher(kbmk)
+		return enc, m, err
+	case VersionD:
+		c, err := aes.NewCipher(kbpk)
+		if err != nil {
+			return nil, nil, errors.New("invalid AES KBPK length: " + strconv.Itoa(len(kbpk)))
+		}
+		kbek, kbmk := derive(c, len(kbpk)/8, len(kbpk))
+		enc, err := aes.NewCipher(kbek)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := aes.NewCipher(kbmk)
+		return enc, m, err
+	}
+	return nil, nil, errors.New("invalid key block version: " + string(version))
+}
+
+func tdesCipher(key []byte) (cipher.Block, error) {
+	if len(key) == 16 {
+		key = append(append([]byte(nil), key...), key[:8]...)
+	}
+	return des.NewTripleDESCipher(key)
+}
+
+// derive returns the encryption key and the MAC key derived from the KBPK
+// with CMAC, the algorithm is 0 and 1 for TDES of 2 and 3 keys, 2, 3 and 4
+// for AES of 128, 192 and 256 bits
+func derive(kbpk cipher.Block, algorithm, length int) ([]byte, []byte) {
+	data := []byte{0, 0, 0, 0, 0, byte(algorithm), byte(length * 8 >> 8), byte(length * 8)}
+	key := func(usage byte) []byte {
+		data[2] = usage
+		var k []byte
+		for i := 1; len(k) < length; i++ {
+			data[0] = byte(i)
+			k = append(k, mac.CMAC(kbpk, data)...)
+		}
+		return k[:length]
+	}
+	return key(0), key(1)
+}
+
+// cbc enciphers or deciphers the data in CBC mode
+func cbc(c cipher.Block, iv, data []byte, encrypt bool) []byte {
+	res := make([]byte, len(data))
+	if encrypt {
+		cipher.NewCBCEncrypter(c, iv).CryptBlocks(res, data)
+	} else {
+		cipher.NewCBCDecrypter(c, iv).CryptBlocks(res, data)
+	}
+	return res
+}
+
+// Wrap returns the key block of the key with the header, protected by the
+// KBPK, TDES for versions A, B and C, AES for version D
+func Wrap(kbpk []byte, h *Header, key []byte) (string, error) {
+	if len(key) == 0 || len(key) > 0xFFFF/8 {
+		return "", errors.New("invalid key length")
+	}
+	enc, mk, err := keys(h.Version, kbpk)
+	if err != nil {
+		return "", err
+	}
+	// the key length in bits, the key and the random padding
+	size := blockSize(h.Version)
+	payload := make([]byte, 2+len(key), (2+len(key)+size-1)/size*size)
+	payload[0], payload[1] = byte(len(key)*8>>8), byte(len(key)*8)
+	copy(payload[2:], key)
+	pad := make([]byte, cap(payload)-len(payload))
+	if _, err := io.ReadFull(random, pad); err != nil {
+		return "", err
+	}
+	payload = append(payload, pad...)
+
+	header, err := h.encode(len(payload))
+	if err != nil {
+		return "", err
+	}
+	var encrypted, tag []byte
+	switch h.Version {
+	case VersionA, VersionC:
+		encrypted = cbc(enc, []byte(header[:des.BlockSize]), payload, true)
+		tag = chainMAC(mk, append([]byte(header), encrypted...))
+	default:
+		// the MAC of the clear key is the IV of the encryption
+		tag = mac.CMAC(mk, append([]byte(header), payload...))[:macLength(h.Version)]
+		encrypted = cbc(enc, tag[:size], payload, true)
+	}
+	return header + strings.ToUpper(hex.EncodeToString(encrypted)+hex.EncodeToString(tag)), nil
+}
+
+// chainMAC returns the TDES CBC-MAC of versions A and C over the header and
+// the binary encrypted key, the data is padded with zeros
+func chainMAC(c cipher.Block, data []byte) []byte {
+	for len(data)%des.BlockSize != 0 {
+		data = append(data, 0)
+	}
+	iv := make([]byte, des.BlockSize)
+	encrypted := cbc(c, iv, data, true)
+	return encrypted[len(encrypted)-des.BlockSize:][:4]
+}
+
+// Unwrap verifies the MAC of the key block with the KBPK, and returns its
+// header and its key. A wrong MAC returns iso8583.ErrInvalidMAC.
+func Unwrap(kbpk []byte, block string) (*Header, []byte, error) {
+	h, offset, err := ParseHeader(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, mk, err := keys(h.Version, kbpk)
+	if err != nil {
+		return nil, nil, err
+	}
+	size, macLen := blockSize(h.Version), macLength(h.Version)
+	body, err := hex.DecodeString(block[offset:])
+	if err != nil {
+		return nil, nil, errors.New("invalid key block encoding")
+	}
+	if len(body) < macLen+size || (len(body)-macLen)%size != 0 {
+		return nil, nil, errors.New("invalid key block length")
+	}
+	encrypted, tag := body[:len(body)-macLen], body[len(body)-macLen:]
+	header := block[:offset]
+
+	var payload, expected []byte
+	switch h.Version {
+	case VersionA, VersionC:
+		expected = chainMAC(mk, append([]byte(header), encrypted...))
+		payload = cbc(enc, []byte(header[:des.BlockSize]), encrypted, false)
+	default:
+		payload = cbc(enc, tag[:size], encrypted, false)
+		expected = mac.CMAC(mk, append([]byte(header), payload...))[:macLen]
+	}
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return nil, nil, iso8583.ErrInvalidMAC
+	}
+
+	bits := int(payload[0])<<8 | int(payload[1])
+	if bits == 0 || bits%8 != 0 || 2+bits/8 > len(payload) {
+		return nil, nil, errors.New("invalid key length in key block")
+	}
+	return h, payload[2 : 2+bits/8], nil
+}
@@ -0,0 +1,220 @@
+package tr31
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fluidpay/iso8583"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// fixedRandom makes the random padding repeatable
+func fixedRandom() func() {
+	saved := random
+	random = bytes.NewReader(bytes.Repeat([]byte{0x5A}, 64))
+	return func() { random = saved }
+}
+
+func TestUnwrapVersionD(t *testing.T) {
+	// the AES key block of the X9.143 examples
+	kbpk := mustHex(t, "88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6")
+	block := "D0112P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34"
+	h, key, err := Unwrap(kbpk, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, mustHex(t, "3F419E1CB7079442AA37474C2EFBF8B8")) {
+		t.Errorf("key should be 3F419E1CB7079442AA37474C2EFBF8B8, got %X", key)
+	}
+	expected := &Header{Version: VersionD, KeyUsage: "P0", Algorithm: 'A', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("header should be %+v, got %+v", expected, h)
+	}
+
+	// a changed header or key is not authenticated
+	for _, changed := range []string{
+		strings.Replace(block, "P0AE", "P0AB", 1),
+		block[:20] + "0" + block[21:],
+	} {
+		if _, _, err := Unwrap(kbpk, changed); err != iso8583.ErrInvalidMAC {
+			t.Errorf("changed key block should be ErrInvalidMAC, got %v", err)
+		}
+	}
+}
+
+func TestUnwrapTDES(t *testing.T) {
+	// the TDES key blocks of the X9.143 examples
+	for _, test := range []struct {
+		kbpk  string
+		block string
+		key   string
+	}{
+		{
+			kbpk:  "89E88CF7931444F334BD7547FC3F380C",
+			block: "A0072P0TE00E0000F5161ED902807AF26F1D62263644BD24192FDB3193C730301CEE8701",
+			key:   "F039121BEC83D26B169BDCD5B22AAF8F",
+		},
+		{
+			kbpk:  "DD7515F2BFC17F85CE48F3CA25CB21F6",
+			block: "B0080P0TE00E000094B420079CC80BA3461F86FE26EFC4A3B8E4FA4C5F5341176EED7B727B8A248E",
+			key:   "3F419E1CB7079442AA37474C2EFBF8B8",
+		},
+	} {
+		h, key, err := Unwrap(mustHex(t, test.kbpk), test.block)
+		if err != nil {
+			t.Fatalf("key block of version %c: %v", test.block[0], err)
+		}
+		if !bytes.Equal(key, mustHex(t, test.key)) {
+			t.Errorf("key of version %c should be %s, got %X", test.block[0], test.key, key)
+		}
+		expected := &Header{Version: test.block[0], KeyUsage: "P0", Algorithm: 'T', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}
+		if !reflect.DeepEqual(h, expected) {
+			t.Errorf("header should be %+v, got %+v", expected, h)
+		}
+		changed := strings.Replace(test.block, "P0TE", "P0TB", 1)
+		if _, _, err := Unwrap(mustHex(t, test.kbpk), changed); err != iso8583.ErrInvalidMAC {
+			t.Errorf("changed key block of version %c should be ErrInvalidMAC, got %v", test.block[0], err)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	defer fixedRandom()()
+	tdes := mustHex(t, "89E88CF7931444F334BD7547FC3F380C")
+	aes := mustHex(t, "88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6")
+	key := mustHex(t, "F039121BEC83D26B169BDCD5B22AAF8F")
+	for _, test := range []struct {
+		version byte
+		kbpk    []byte
+		length  int
+	}{
+		// the header, the length, the key, the padding and the MAC
+		{VersionA, tdes, 16 + 48 + 8},
+		{VersionB, tdes, 16 + 48 + 16},
+		{VersionC, tdes, 16 + 48 + 8},
+		{VersionD, aes, 16 + 64 + 32},
+	} {
+		h := &Header{Version: test.version, KeyUsage: "P0", Algorithm: 'T', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}
+		block, err := Wrap(test.kbpk, h, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(block) != test.length || block[:5] != fmt.Sprintf("%c%04d", test.version, test.length) {
+			t.Errorf("key block of version %c should have %d characters, got %s", test.version, test.length, block)
+		}
+		unwrapped, k, err := Unwrap(test.kbpk, block)
+		if err != nil {
+			t.Fatalf("key block of version %c should be unwrapped: %v", test.version, err)
+		}
+		if !bytes.Equal(k, key) || !reflect.DeepEqual(unwrapped, h) {
+			t.Errorf("key block of version %c should be %+v %X, got %+v %X", test.version, h, key, unwrapped, k)
+		}
+		other := append([]byte(nil), test.kbpk...)
+		// not the parity bit of a DES key
+		other[0] ^= 0x02
+		if _, _, err := Unwrap(other, block); err != iso8583.ErrInvalidMAC {
+			t.Errorf("key block of version %c with another KBPK should be ErrInvalidMAC, got %v", test.version, err)
+		}
+	}
+
+	if _, err := Wrap(tdes, &Header{Version: 'E', KeyUsage: "P0", Algorithm: 'T', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}, key); err == nil {
+		t.Error("version E should fail")
+	}
+	if _, err := Wrap(tdes, &Header{Version: VersionB, KeyUsage: "P", Algorithm: 'T', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}, key); err == nil {
+		t.Error("key usage of 1 character should fail")
+	}
+	if _, err := Wrap(tdes[:8], &Header{Version: VersionB, KeyUsage: "P0", Algorithm: 'T', ModeOfUse: 'E', KeyVersion: "00", Exportability: 'E'}, key); err == nil {
+		t.Error("KBPK of 8 bytes should fail")
+	}
+}
+
+func TestOptionalBlocks(t *testing.T) {
+	defer fixedRandom()()
+	kbpk := mustHex(t, "88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6")
+	h := &Header{
+		Version: VersionD, KeyUsage: "B1", Algorithm: 'A', ModeOfUse: 'X', KeyVersion: "00", Exportability: 'N',
+		OptionalBlocks: []OptionalBlock{
+			{ID: "KS", Data: "FFFF9876543210E00000"},
+			{ID: "TS", Data: strings.Repeat("X", 300)},
+		},
+	}
+	block, err := Wrap(kbpk, h, mustHex(t, "1273671EA26AC29AFA4D1084127652A1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// KS of 24 characters, TS of extended length, and PB to a multiple of 16
+	if !strings.HasPrefix(block[5:], "B1AX00N0300KS18FFFF9876543210E00000TS00040136"+strings.Repeat("X", 300)+"PB12") {
+		t.Errorf("header should have the optional blocks, got %s", block[:60])
+	}
+	parsed, offset, err := ParseHeader(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 16+24+310+18 || !reflect.DeepEqual(parsed, h) {
+		t.Errorf("header should be %+v of %d characters, got %+v %d", h, 16+24+310+18, parsed, offset)
+	}
+	if ks, ok := parsed.Optional("KS"); !ok || ks != "FFFF9876543210E00000" {
+		t.Errorf("KS should be FFFF9876543210E00000, got %s", ks)
+	}
+	if _, ok := parsed.Optional("PB"); ok {
+		t.Error("padding block should not be returned")
+	}
+
+	h.OptionalBlocks = []OptionalBlock{{ID: "PB", Data: "00"}}
+	if _, err := Wrap(kbpk, h, make([]byte, 16)); err == nil {
+		t.Error("padding block should not be set")
+	}
+}
+
+func TestParseHeaderInvalid(t *testing.T) {
+	for _, block := range []string{
+		"B0016P0TE00E00",           // short
+		"E0016P0TE00E0000",         // version
+		"B0017P0TE00E0000",         // length
+		"B0016P0TE00E0100",         // missing optional block
+		"B0024P0TE00E0100KS0CFFFF", // optional block length
+		"B0024P0TE00E0100KS06FFFF", // not padded
+	} {
+		if _, _, err := ParseHeader(block); err == nil {
+			t.Errorf("invalid header %s should fail", block)
+		}
+	}
+}
+
+func TestKeyExchange(t *testing.T) {
+	defer fixedRandom()()
+	kek := mustHex(t, "88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6")
+	pinKey := mustHex(t, "C1D0F8FB4958670DBA40AB1F3752EF0D")
+	block, err := Wrap(kek, &Header{Version: VersionD, KeyUsage: "P0", Algorithm: 'A', ModeOfUse: 'B', KeyVersion: "01", Exportability: 'E'}, pinKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key exchange request of the host carries the key block in DE96
+	m := &iso8583.Message{Mti: "1804", DE24: iso8583.NewNumeric("811"), DE96: iso8583.NewANS(block)}
+	b, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := &iso8583.Message{}
+	if err := received.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	h, key, err := Unwrap(kek, received.DE96.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.KeyUsage != "P0" || h.KeyVersion != "01" || !bytes.Equal(key, pinKey) {
+		t.Errorf("PIN key of version 01 should be received, got %+v %X", h, key)
+	}
+}
@@ -0,0 +1,115 @@
+package iso8583
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError is the error of a single field, Field is the name of the field
+// with the names of its parents, e.g. DE125.SE2
+type FieldError struct {
+	Field string
+	Err   error
+	// Raw holds the bytes of the field as received, including its length
+	// prefix, if the field was kept by a lenient decode
+	Raw []byte
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors are the errors of every invalid field, in field order
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Fields returns the names of the invalid fields
+func (e ValidationErrors) Fields() []string {
+	fields := make([]string, 0, len(e))
+	for _, fe := range e {
+		fields = append(fields, fe.Field)
+	}
+	return fields
+}
+
+func (e ValidationErrors) has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBitmapped checks every present field of the struct s,
+// the same way as encodeBitmapped, but without stopping at the first error
+func validateBitmapped(s Generated, encoder int, spec BitmapSpec, prefix string) ValidationErrors {
+	var errs ValidationErrors
+	addErr := func(name string, err error) {
+		errs = append(errs, &FieldError{Field: prefix + name, Err: err})
+	}
+
+	specs := s.ISO8583Fields()
+	for i := range specs {
+		fs := &specs[i]
+		value := s.ISO8583Field(i)
+		// skip empty fields
+		if value == nil {
+			continue
+		}
+		if err := fs.check(); err != nil {
+			addErr(fs.Name, err)
+			continue
+		}
+
+		n, bit := spec.position(fs.Index)
+		if n > 0 && spec.Fixed {
+			addErr(fs.Name, errors.New("field out of bitmap range"))
+			continue
+		}
+		if bit == 1 && !spec.Fixed {
+			addErr(fs.Name, errors.New("field reserved for bitmap"))
+			continue
+		}
+
+		if fs.SubMessage {
+			bitmapSpec, err := parseBitmapSpec(fs.Tag.Get("bitmap"))
+			if err != nil {
+				addErr(fs.Name, err)
+				continue
+			}
+			sub := nestedStructOf(s, value)
+			subErrs := validateBitmapped(sub, encoder, bitmapSpec, prefix+fs.Name+".")
+			if len(subErrs) > 0 {
+				errs = append(errs, subErrs...)
+				continue
+			}
+			// subfields are valid, check the submessage as a whole
+			if _, err := encodeSubField(sub, encoder, fs); err != nil {
+				addErr(fs.Name, err)
+			}
+			continue
+		}
+
+		f, err := fs.asField(value)
+		if err != nil {
+			addErr(fs.Name, err)
+			continue
+		}
+		if _, err := f.Encode(encoder, fs.Length, fs.Format, fs.Validator); err != nil {
+			addErr(fs.Name, err)
+		}
+	}
+	return errs
+}
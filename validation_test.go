@@ -0,0 +1,65 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMessageValidate(t *testing.T) {
+	m := &Message{
+		DE2:  NewNumeric("48468112AB"),    // invalid number
+		DE3:  NewNumeric("201234"),        // valid
+		DE4:  NewNumeric("1234567890123"), // too long
+		DE7:  NewDateTime("1307221800"),   // invalid month
+		DE41: NewANS("termid12"),          // valid
+		DE125: &SubMessage{
+			SE2: NewANS("Test Address"), // valid
+			SE5: &Reserved{},            // reserved
+			SE9: NewAlphanumeric("1-3"), // invalid alphanumeric
+		},
+	}
+
+	err := m.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error should be ValidationErrors, instead of %#v", err)
+	}
+	expected := []string{"MTI", "DE2", "DE4", "DE7", "DE125.SE5", "DE125.SE9"}
+	if !reflect.DeepEqual(errs.Fields(), expected) {
+		t.Errorf("invalid fields should be %v, instead of %v", expected, errs.Fields())
+	}
+	if errs[1].Error() != "DE2: invalid number value format: 48468112AB" {
+		t.Errorf("unexpected error message %q", errs[1].Error())
+	}
+
+	m = &Message{
+		DE3:   NewNumeric("201234"),
+		DE125: &SubMessage{SE2: NewANS("Test Address")},
+	}
+	m.Mti = "1200"
+	if err := m.Validate(); err != nil {
+		t.Errorf("message should be valid, instead of %v", err)
+	}
+}
+
+func TestSubMessageValidate(t *testing.T) {
+	sm := &SubMessage{
+		SE2:  NewANS("Test Address"),
+		SE4:  NewNumeric("98765432101"), // too long
+		SE98: NewAlphanumeric("1"),
+	}
+	err := sm.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error should be ValidationErrors, instead of %#v", err)
+	}
+	if !reflect.DeepEqual(errs.Fields(), []string{"SE4"}) {
+		t.Errorf("invalid fields should be [SE4], instead of %v", errs.Fields())
+	}
+
+	sm.SE4 = NewNumeric("9876543210")
+	if err := sm.Validate(); err != nil {
+		t.Errorf("submessage should be valid, instead of %v", err)
+	}
+}